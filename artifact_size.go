@@ -0,0 +1,138 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"io"
+	"net/http"
+	"os"
+)
+
+const bytesPerMB = 1024 * 1024
+
+// sizeLimitTransport reject http responses bigger than maxSize, from Content-Length or while reading body
+type sizeLimitTransport struct {
+	base    http.RoundTripper
+	maxSize int64
+}
+
+func (t sizeLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.ContentLength > t.maxSize {
+		resp.Body.Close()
+		return nil, artifactTooLargeError(resp.ContentLength, t.maxSize)
+	}
+	resp.Body = &sizeLimitBody{ReadCloser: resp.Body, maxSize: t.maxSize}
+	return resp, nil
+}
+
+type sizeLimitBody struct {
+	io.ReadCloser
+	read    int64
+	maxSize int64
+}
+
+func (b *sizeLimitBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.maxSize {
+		return n, fmt.Errorf("Artifact is bigger than max artifact size of %s", humanBytes(uint64(b.maxSize)))
+	}
+	return n, err
+}
+
+func artifactTooLargeError(size, maxSize int64) error {
+	return fmt.Errorf(
+		"Artifact size of %s exceeds max artifact size of %s",
+		humanBytes(uint64(size)), humanBytes(uint64(maxSize)),
+	)
+}
+
+// limitedZipperSess give a zipper session which fails when downloading artifacts bigger than maxSize
+func limitedZipperSess(uri, fileType string, maxSize int64) (*zipper.Session, error) {
+	m, err := zipper.NewManager(zipper.NewGitHandler(), &zipper.HttpHandler{}, &zipper.LocalHandler{}, &AzblobHandler{}, NewArtifactoryHandler(), NewNexusHandler(), &MavenHandler{}, &SftpHandler{})
+	if err != nil {
+		return nil, err
+	}
+	m.SetHttpClient(&http.Client{
+		Transport: sizeLimitTransport{base: http.DefaultTransport, maxSize: maxSize},
+	})
+	if fileType != "" {
+		return m.CreateSession(uri, fileType)
+	}
+	return m.CreateSession(uri)
+}
+
+// maxArtifactSize give max size in bytes of sidecar artifact, 0 means no limit
+func (l Launcher) maxArtifactSize(sidecar *config.Sidecar) int64 {
+	if sidecar.MaxArtifactSize > 0 {
+		return int64(sidecar.MaxArtifactSize) * bytesPerMB
+	}
+	return int64(l.sConfig.MaxArtifactSize) * bytesPerMB
+}
+
+// artifactSize give size of artifact before downloading it when it can be known (-1 otherwise)
+// from Content-Length for http artifacts or from file size for local ones
+func (l Launcher) artifactSize(sidecar *config.Sidecar) int64 {
+	if localPath, ok := l.localArtifactDir(sidecar); ok {
+		stat, err := os.Stat(localPath)
+		if err != nil || stat.IsDir() {
+			return -1
+		}
+		return stat.Size()
+	}
+	if !isHttpArtifact(sidecar) {
+		return -1
+	}
+	resp, err := httpHead(sidecar.ArtifactURI, HttpValidators{})
+	if err != nil {
+		return -1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// checkArtifactSize check artifact size against max artifact size and free disk space in dir before downloading,
+// downloaded archive and extracted files are both expected to be around artifact size
+func (l Launcher) checkArtifactSize(sidecar *config.Sidecar, dir string) error {
+	size := l.artifactSize(sidecar)
+	if size < 0 {
+		return nil
+	}
+	maxSize := l.maxArtifactSize(sidecar)
+	if maxSize > 0 && size > maxSize {
+		return artifactTooLargeError(size, maxSize)
+	}
+	free, ok := freeDiskSpace(dir)
+	if !ok {
+		return nil
+	}
+	required := uint64(size) * 2
+	if free < required {
+		return fmt.Errorf(
+			"Not enough free disk space in %s: %s available but around %s required to download and extract artifact of %s",
+			dir, humanBytes(free), humanBytes(required), humanBytes(uint64(size)),
+		)
+	}
+	return nil
+}
+
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
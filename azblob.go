@@ -0,0 +1,307 @@
+package sidecars
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	azblobScheme  = "azblob://"
+	azblobVersion = "2020-04-08"
+
+	AzureConnectionStringEnvKey = "AZURE_STORAGE_CONNECTION_STRING"
+	AzureSasTokenEnvKey         = "AZURE_STORAGE_SAS_TOKEN"
+	// AzureClientIdEnvKey select user assigned managed identity
+	AzureClientIdEnvKey = "AZURE_CLIENT_ID"
+
+	azureImdsTokenUrl    = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureStorageScope    = "https://storage.azure.com/"
+	azureTokenMargin     = 5 * time.Minute
+	azureImdsTimeout     = 10 * time.Second
+	azureDefaultSuffix   = "core.windows.net"
+	azureDefaultProtocol = "https"
+)
+
+func init() {
+	zipper.AddHandler(&AzblobHandler{})
+}
+
+// AzblobHandler is a zipper handler downloading artifacts from azure blob storage with uri azblob://<account>/<container>/<path>,
+// it is authenticated with AZURE_STORAGE_CONNECTION_STRING, AZURE_STORAGE_SAS_TOKEN or else with managed identity
+type AzblobHandler struct{}
+
+func (h AzblobHandler) Zip(src *zipper.Source) (zipper.ZipReadCloser, error) {
+	httpSrc, err := h.httpSource(src)
+	if err != nil {
+		return nil, err
+	}
+	return zipper.HttpHandler{}.Zip(httpSrc)
+}
+
+func (h AzblobHandler) Sha1(src *zipper.Source) (string, error) {
+	httpSrc, err := h.httpSource(src)
+	if err != nil {
+		return "", err
+	}
+	return zipper.HttpHandler{}.Sha1(httpSrc)
+}
+
+func (h AzblobHandler) Detect(src *zipper.Source) bool {
+	return strings.HasPrefix(src.Path, azblobScheme)
+}
+
+func (h AzblobHandler) Name() string {
+	return "azblob"
+}
+
+// httpSource give source of blob url downloaded by http handler with a client authenticating requests
+func (h AzblobHandler) httpSource(src *zipper.Source) (*zipper.Source, error) {
+	account, blobPath, err := parseAzblobUri(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := azblobAuthFromEnv(account)
+	if err != nil {
+		return nil, err
+	}
+	// sas is added by transport to not have it in path used to name downloaded file
+	httpSrc := zipper.NewSource(auth.endpoint + "/" + blobPath)
+	transport := http.DefaultTransport
+	if client := zipper.CtxHttpClient(src); client != nil && client.Transport != nil {
+		transport = client.Transport
+	}
+	zipper.SetCtxHttpClient(httpSrc, &http.Client{
+		Transport: &azblobTransport{base: transport, auth: auth},
+	})
+	return httpSrc, nil
+}
+
+// parseAzblobUri give account and escaped path of blob in the form <container>/<blob>
+func parseAzblobUri(uri string) (account, blobPath string, err error) {
+	parts := strings.SplitN(strings.TrimPrefix(uri, azblobScheme), "/", 3)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("Invalid azure blob uri '%s', it must be in the form azblob://<account>/<container>/<path>", uri)
+	}
+	u := url.URL{Path: parts[1] + "/" + parts[2]}
+	return parts[0], u.EscapedPath(), nil
+}
+
+type azblobAuth struct {
+	account    string
+	endpoint   string
+	accountKey []byte
+	sas        url.Values
+	token      *azureToken
+}
+
+// azblobAuthFromEnv give authentication to use from env, connection string takes precedence over sas token
+// and managed identity is used when none of them is set
+func azblobAuthFromEnv(account string) (azblobAuth, error) {
+	auth := azblobAuth{
+		account:  account,
+		endpoint: fmt.Sprintf("%s://%s.blob.%s", azureDefaultProtocol, account, azureDefaultSuffix),
+	}
+	if connStr := os.Getenv(AzureConnectionStringEnvKey); connStr != "" {
+		return auth, auth.loadConnectionString(connStr)
+	}
+	if sas := os.Getenv(AzureSasTokenEnvKey); sas != "" {
+		values, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return auth, fmt.Errorf("Invalid azure sas token: %s", err.Error())
+		}
+		auth.sas = values
+		return auth, nil
+	}
+	auth.token = &azureToken{clientId: os.Getenv(AzureClientIdEnvKey)}
+	return auth, nil
+}
+
+func (a *azblobAuth) loadConnectionString(connStr string) error {
+	settings := make(map[string]string)
+	for _, part := range strings.Split(connStr, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			settings[strings.ToLower(kv[0])] = kv[1]
+		}
+	}
+	if name := settings["accountname"]; name != "" && name != a.account {
+		return fmt.Errorf("Azure connection string is for account %s and not for account %s", name, a.account)
+	}
+	protocol := azureDefaultProtocol
+	if settings["defaultendpointsprotocol"] != "" {
+		protocol = settings["defaultendpointsprotocol"]
+	}
+	suffix := azureDefaultSuffix
+	if settings["endpointsuffix"] != "" {
+		suffix = settings["endpointsuffix"]
+	}
+	a.endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, a.account, suffix)
+	if settings["blobendpoint"] != "" {
+		a.endpoint = strings.TrimSuffix(settings["blobendpoint"], "/")
+	}
+	if sas := settings["sharedaccesssignature"]; sas != "" {
+		values, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return fmt.Errorf("Invalid shared access signature in azure connection string: %s", err.Error())
+		}
+		a.sas = values
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(settings["accountkey"])
+	if err != nil || len(key) == 0 {
+		return fmt.Errorf("Azure connection string must have a valid account key or a shared access signature")
+	}
+	a.accountKey = key
+	return nil
+}
+
+// azblobTransport authenticate requests to azure blob storage
+type azblobTransport struct {
+	base http.RoundTripper
+	auth azblobAuth
+}
+
+func (t *azblobTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	u := *req.URL
+	r.URL = &u
+	r.Header.Set("x-ms-version", azblobVersion)
+	r.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	switch {
+	case t.auth.sas != nil:
+		query := r.URL.Query()
+		for k, v := range t.auth.sas {
+			query[k] = v
+		}
+		r.URL.RawQuery = query.Encode()
+	case t.auth.accountKey != nil:
+		r.Header.Set("Authorization", t.auth.sharedKeyAuthorization(r))
+	default:
+		token, err := t.auth.token.get()
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return t.base.RoundTrip(r)
+}
+
+// sharedKeyAuthorization give Authorization header of request signed with account key as described in
+// https://docs.microsoft.com/rest/api/storageservices/authorize-with-shared-key
+func (a azblobAuth) sharedKeyAuthorization(req *http.Request) string {
+	mac := hmac.New(sha256.New, a.accountKey)
+	mac.Write([]byte(a.sharedKeyStringToSign(req)))
+	return fmt.Sprintf("SharedKey %s:%s", a.account, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// sharedKeyStringToSign give canonicalized headers and resource of request signed with account key
+func (a azblobAuth) sharedKeyStringToSign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	h := req.Header
+	lines := []string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		contentLength,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // date is given in x-ms-date
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	}
+	msHeaders := make([]string, 0)
+	for k, v := range h {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k+":"+strings.TrimSpace(strings.Join(v, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+	lines = append(lines, msHeaders...)
+
+	resource := "/" + a.account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for k := range query {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		values := query[k]
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(values, ",")
+	}
+	lines = append(lines, resource)
+	return strings.Join(lines, "\n")
+}
+
+// azureToken is a managed identity token retrieved from instance metadata service and kept until it expires
+type azureToken struct {
+	clientId  string
+	mutex     sync.Mutex
+	value     string
+	expiresAt time.Time
+}
+
+func (t *azureToken) get() (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.value != "" && time.Now().Add(azureTokenMargin).Before(t.expiresAt) {
+		return t.value, nil
+	}
+	params := url.Values{}
+	params.Set("api-version", "2018-02-01")
+	params.Set("resource", azureStorageScope)
+	if t.clientId != "" {
+		params.Set("client_id", t.clientId)
+	}
+	req, err := http.NewRequest(http.MethodGet, azureImdsTokenUrl+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+	client := &http.Client{Timeout: azureImdsTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("No azure credentials found in env and managed identity is not available: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Could not get managed identity token for azure storage: %s", resp.Status)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("Invalid managed identity token: %s", err.Error())
+	}
+	expiresOn, _ := strconv.ParseInt(token.ExpiresOn, 10, 64)
+	t.value = token.AccessToken
+	t.expiresAt = time.Unix(expiresOn, 0)
+	return t.value, nil
+}
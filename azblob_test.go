@@ -0,0 +1,61 @@
+package sidecars
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+// azureDevAccountKey is the well known key of azure storage emulator account devstoreaccount1
+const azureDevAccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+func TestSharedKeyAuthorization(t *testing.T) {
+	key, err := base64.StdEncoding.DecodeString(azureDevAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := azblobAuth{account: "devstoreaccount1", accountKey: key}
+	req, err := http.NewRequest(http.MethodGet, "https://devstoreaccount1.blob.core.windows.net/artifacts/sidecars/my%20sidecar.tgz?timeout=20", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	req.Header.Set("User-Agent", "azsdk-go-azblob/v1.8.1 (go1.27.1; linux)")
+	req.Header.Set("x-ms-date", "Fri, 16 Oct 2026 20:17:00 GMT")
+	req.Header.Set("x-ms-range", "bytes=0-1023")
+	req.Header.Set("x-ms-version", "2026-12-06")
+
+	expectedStringToSign := "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Fri, 16 Oct 2026 20:17:00 GMT\n" +
+		"x-ms-range:bytes=0-1023\n" +
+		"x-ms-version:2026-12-06\n" +
+		"/devstoreaccount1/artifacts/sidecars/my%20sidecar.tgz\n" +
+		"timeout:20"
+	if stringToSign := auth.sharedKeyStringToSign(req); stringToSign != expectedStringToSign {
+		t.Fatalf("Expected string to sign:\n%q\ngot:\n%q", expectedStringToSign, stringToSign)
+	}
+	// header sent by azure sdk for go for the same request
+	expectedAuthorization := "SharedKey devstoreaccount1:kLabIbLtd6+89CrCgMDXdxEcV+Tads0knQzwk2l6uoQ="
+	if authorization := auth.sharedKeyAuthorization(req); authorization != expectedAuthorization {
+		t.Fatalf("Expected authorization %s, got %s", expectedAuthorization, authorization)
+	}
+}
+
+func TestSharedKeyCanonicalizedResource(t *testing.T) {
+	auth := azblobAuth{account: "myaccount"}
+	// example of https://docs.microsoft.com/rest/api/storageservices/authorize-with-shared-key
+	req, err := http.NewRequest(http.MethodGet, "https://myaccount.blob.core.windows.net/mycontainer?restype=container&comp=metadata&timeout=20", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-ms-date", "Fri, 26 Jun 2015 23:39:12 GMT")
+	req.Header.Set("x-ms-version", "2015-02-21")
+
+	expected := "GET\n\n\n\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Fri, 26 Jun 2015 23:39:12 GMT\n" +
+		"x-ms-version:2015-02-21\n" +
+		"/myaccount/mycontainer\ncomp:metadata\nrestype:container\ntimeout:20"
+	if stringToSign := auth.sharedKeyStringToSign(req); stringToSign != expected {
+		t.Fatalf("Expected string to sign:\n%q\ngot:\n%q", expected, stringToSign)
+	}
+}
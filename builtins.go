@@ -0,0 +1,48 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/forward"
+	"github.com/orange-cloudfoundry/cloud-sidecars/rproxy"
+	"github.com/orange-cloudfoundry/cloud-sidecars/static"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"os"
+	"os/exec"
+)
+
+// builtinCmd give command running builtin sidecar with current binary
+func builtinCmd(sidecar *config.Sidecar) (*exec.Cmd, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	command, _, _ := builtinConfig(sidecar)
+	return exec.Command(exePath, command), nil
+}
+
+// builtinEnv give sidecar env with config of builtin sidecar
+func builtinEnv(sidecar *config.Sidecar, env map[string]string) (map[string]string, error) {
+	_, envKey, conf := builtinConfig(sidecar)
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MergeEnv(env, map[string]string{
+		envKey: string(b),
+	}), nil
+}
+
+// builtinConfig give command and env var name running builtin sidecar and config given to it
+func builtinConfig(sidecar *config.Sidecar) (command string, envKey string, conf interface{}) {
+	switch sidecar.Type {
+	case config.SidecarTypeBuiltinStatic:
+		return static.Command, static.ConfigEnvKey, sidecar.BuiltinStatic
+	case config.SidecarTypeBuiltinForward:
+		return forward.Command, forward.ConfigEnvKey, sidecar.BuiltinForward
+	}
+	if sidecar.BuiltinRproxy == nil {
+		return rproxy.Command, rproxy.ConfigEnvKey, config.BuiltinRproxy{}
+	}
+	return rproxy.Command, rproxy.ConfigEnvKey, sidecar.BuiltinRproxy
+}
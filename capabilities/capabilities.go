@@ -0,0 +1,44 @@
+// Package capabilities drops linux capabilities of a sidecar process before executing it.
+//
+// Like package rproxy, launcher executes its own binary with Command as first argument followed by executable
+// and args of sidecar, config is given in ConfigEnvKey env var. Programs embedding launcher must call ExecFromEnv
+// with remaining args when they are executed with Command as first argument to use capabilities of sidecars.
+package capabilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+const (
+	// Command is first argument given to launcher binary to execute a sidecar with dropped capabilities
+	Command = "exec-capabilities"
+	// ConfigEnvKey is env var giving capabilities to drop as json
+	ConfigEnvKey = "SIDECARS_CAPABILITIES"
+)
+
+// ExecFromEnv drop capabilities given by ConfigEnvKey env var and replace current process by executable args[0]
+// with args[1:] as arguments, it only returns on error
+func ExecFromEnv(args []string) error {
+	// capabilities are dropped for calling thread only, exec must happen on the same thread,
+	// thread is never unlocked as current process is replaced or exits on error
+	runtime.LockOSThread()
+	if len(args) == 0 {
+		return fmt.Errorf("Executable to run with dropped capabilities must be given")
+	}
+	conf := config.Capabilities{}
+	err := json.Unmarshal([]byte(os.Getenv(ConfigEnvKey)), &conf)
+	if err != nil {
+		return fmt.Errorf("Invalid capabilities config: %s", err.Error())
+	}
+	os.Unsetenv(ConfigEnvKey)
+	execPath, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return execWithCapabilities(conf, execPath, args)
+}
@@ -0,0 +1,93 @@
+//go:build linux
+// +build linux
+
+package capabilities
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// execWithCapabilities drop capabilities and set no new privs flag before executing sidecar
+func execWithCapabilities(conf config.Capabilities, execPath string, args []string) error {
+	err := dropCapabilities(conf)
+	if err != nil {
+		return fmt.Errorf("Error when dropping capabilities: %s", err.Error())
+	}
+	if conf.NoNewPrivs {
+		err = unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0)
+		if err != nil {
+			return fmt.Errorf("Error when setting no new privs: %s", err.Error())
+		}
+	}
+	return unix.Exec(execPath, args, os.Environ())
+}
+
+// dropCapabilities remove capabilities from bounding set, when allowed, and from effective, permitted and inheritable sets,
+// ambient capabilities are removed with inheritable ones by kernel
+func dropCapabilities(conf config.Capabilities) error {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	err := unix.Capget(&header, &data[0])
+	if err != nil {
+		return err
+	}
+	// dropping from bounding set requires cap_setpcap, capabilities unknown by kernel can't be dropped
+	canDropBounding := hasCapability(data[0].Effective, unix.CAP_SETPCAP)
+	lastCap := lastCapability()
+	unbounded := make([]string, 0)
+	for _, number := range conf.Dropped() {
+		if number <= lastCap {
+			inBounding, _ := unix.PrctlRetInt(unix.PR_CAPBSET_READ, uintptr(number), 0, 0, 0)
+			switch {
+			case inBounding == 1 && canDropBounding:
+				err = unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(number), 0, 0, 0)
+				if err != nil {
+					return fmt.Errorf("Capability %s can't be dropped from bounding set: %s", config.CapabilityNames[number], err.Error())
+				}
+			case inBounding == 1:
+				unbounded = append(unbounded, config.CapabilityNames[number])
+			}
+		}
+		bit := uint32(1) << uint(number%32)
+		set := &data[number/32]
+		set.Effective &^= bit
+		set.Permitted &^= bit
+		set.Inheritable &^= bit
+	}
+	err = unix.Capset(&header, &data[0])
+	if err != nil {
+		return err
+	}
+	if len(unbounded) > 0 && !conf.NoNewPrivs {
+		log.WithField("component", "Capabilities").Warnf(
+			"Capabilities %s could be regained by setuid or file capabilities executables, "+
+				"cap_setpcap is needed to drop them from bounding set, set no_new_privs to prevent it",
+			strings.Join(unbounded, ", "),
+		)
+	}
+	return nil
+}
+
+func hasCapability(set uint32, number int) bool {
+	return set&(uint32(1)<<uint(number)) != 0
+}
+
+// lastCapability give number of last capability known by kernel
+func lastCapability() int {
+	b, err := ioutil.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return len(config.CapabilityNames) - 1
+	}
+	last, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return len(config.CapabilityNames) - 1
+	}
+	return last
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package capabilities
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"runtime"
+)
+
+// execWithCapabilities is not supported on this platform
+func execWithCapabilities(conf config.Capabilities, execPath string, args []string) error {
+	return fmt.Errorf("Capabilities are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,61 @@
+package sidecars
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DirChecksum compute a sha256 checksum of a directory content.
+// Files path, permissions and content are taken in account, symlinks are hashed by their target.
+func DirChecksum(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "l %s %s\n", relPath, target)
+		case info.IsDir():
+			fmt.Fprintf(h, "d %s\n", relPath)
+		case info.Mode().IsRegular():
+			fileSum, err := fileChecksum(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "f %s %o %s\n", relPath, info.Mode().Perm(), fileSum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
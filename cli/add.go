@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func addRun(c *cli.Context) error {
+	initApp(c)
+	in := bufio.NewReader(os.Stdin)
+
+	name := c.Args().First()
+	if name == "" {
+		name = prompt(in, os.Stderr, "Sidecar name")
+	}
+	executable := c.String("command")
+	if executable == "" {
+		executable = prompt(in, os.Stderr, "Command to run sidecar")
+	}
+
+	env, err := keyValuesToMap(c.StringSlice("env"))
+	if err != nil {
+		return err
+	}
+	appEnv, err := keyValuesToMap(c.StringSlice("app-env"))
+	if err != nil {
+		return err
+	}
+
+	sidecar := &config.Sidecar{
+		Name:         name,
+		Executable:   executable,
+		Args:         c.StringSlice("arg"),
+		ArtifactURI:  c.String("artifact"),
+		ArtifactType: c.String("artifact-type"),
+		ArtifactSha1: c.String("artifact-sha1"),
+		AfterInstall: c.String("after-install"),
+		Env:          env,
+		AppEnv:       appEnv,
+		IsRproxy:     c.Bool("rproxy"),
+	}
+
+	confPath, dir := findConfPathAndDir(c)
+	if _, err := os.Stat(confPath); os.IsNotExist(err) {
+		confPath = filepath.Join(dir, c.GlobalString("config-path"))
+	}
+	err = config.AppendSidecarToFile(confPath, sidecar)
+	if err != nil {
+		return err
+	}
+	log.WithField("component", "cli").Infof("Sidecar '%s' has been added to %s", sidecar.Name, confPath)
+	return nil
+}
+
+func prompt(in *bufio.Reader, out io.Writer, label string) string {
+	fmt.Fprintf(out, "%s: ", label)
+	text, _ := in.ReadString('\n')
+	return strings.TrimSpace(text)
+}
+
+func keyValuesToMap(kvs []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, kv := range kvs {
+		splitKv := strings.SplitN(kv, "=", 2)
+		if len(splitKv) != 2 || splitKv[0] == "" {
+			return nil, fmt.Errorf("Invalid value '%s', it must be in the form KEY=VALUE", kv)
+		}
+		m[splitKv[0]] = splitKv[1]
+	}
+	return m, nil
+}
@@ -9,8 +9,14 @@ import (
 	"github.com/cloudfoundry-community/gautocloud/interceptor/configfile"
 	"github.com/cloudfoundry-community/gautocloud/loader"
 	"github.com/orange-cloudfoundry/cloud-sidecars"
+	"github.com/orange-cloudfoundry/cloud-sidecars/capabilities"
 	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/forward"
+	"github.com/orange-cloudfoundry/cloud-sidecars/isolation"
+	"github.com/orange-cloudfoundry/cloud-sidecars/rproxy"
 	"github.com/orange-cloudfoundry/cloud-sidecars/starter"
+	"github.com/orange-cloudfoundry/cloud-sidecars/static"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 	"gopkg.in/yaml.v2"
@@ -23,7 +29,10 @@ import (
 var cliInterceptor *urfave.CliInterceptor
 var confFileIntercept *configfile.ConfigFileInterceptor
 
-const configFileName = "sidecars-config.yml"
+const (
+	configFileName        = "sidecars-config.yml"
+	disableSidecarsEnvKey = "SIDECARS_DISABLE"
+)
 
 func init() {
 	log.SetOutput(os.Stdout)
@@ -47,6 +56,9 @@ func NewApp(version string) *CloudSidecarApp {
 	app.Version = version
 	app.Usage = "Cloud sidecar cli"
 	app.ErrWriter = os.Stderr
+	// exit codes are handled by main to log error before exiting
+	app.ExitErrHandler = func(c *cli.Context, err error) {}
+	app.EnableBashCompletion = true
 	app.Flags = []cli.Flag{
 		cli.StringFlag{
 			Name:   "config-path, c",
@@ -67,6 +79,10 @@ func NewApp(version string) *CloudSidecarApp {
 			Name:  "cloud-env",
 			Usage: "Force cloud env detection",
 		},
+		cli.BoolFlag{
+			Name:  "quiet, q",
+			Usage: "Only show errors in launcher logs, output of sidecars and app is still shown",
+		},
 		cli.BoolFlag{
 			Name:  "log-json, j",
 			Usage: "Write log in json",
@@ -96,6 +112,43 @@ func NewApp(version string) *CloudSidecarApp {
 					Name:  "no-starter",
 					Usage: "Main process will not be started",
 				},
+				cli.BoolFlag{
+					Name:  "interactive, i",
+					Usage: "Forward stdin to main process",
+				},
+				cli.BoolFlag{
+					Name:  "tty, t",
+					Usage: "Attach main process to a pseudo-terminal, implies --interactive",
+				},
+				cli.StringFlag{
+					Name:  "app-command",
+					Usage: "Command to start app, override app_command from config and start command detected by starter",
+				},
+				cli.BoolFlag{
+					Name:  "no-control-api",
+					Usage: "Do not serve control api",
+				},
+				cli.StringSliceFlag{
+					Name:  "only",
+					Usage: "Name of sidecar to launch, others are not launched, can be comma separated list or set multiple times",
+				},
+				cli.StringSliceFlag{
+					Name:  "except",
+					Usage: "Name of sidecar to not launch, can be comma separated list or set multiple times (alias of --skip)",
+				},
+				cli.StringSliceFlag{
+					Name:  "skip",
+					Usage: "Name of sidecar to not launch, can be comma separated list or set multiple times (names in env var " + disableSidecarsEnvKey + " are also skipped)",
+				},
+				cli.BoolFlag{
+					Name:  "watch",
+					Usage: "Reload config when config file changes and restart sidecars when one of their watch_files changes (same as watch_config in config)",
+				},
+				cli.StringFlag{
+					Name:   "pprof-addr",
+					Usage:  "Serve pprof profiles of launcher itself on this address to profile it (e.g.: 127.0.0.1:6060), should only listen on localhost",
+					EnvVar: sidecars.PprofAddrEnvKey,
+				},
 			},
 		},
 		{
@@ -107,16 +160,376 @@ func NewApp(version string) *CloudSidecarApp {
 			Name:   "setup",
 			Usage:  "Download sidecars if needed and create profiled files, this should be run by a staging lifecycle (e.g.: cloud foundry buildpack lifecycle)",
 			Action: setupRun,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "allow-unsafe-extract",
+					Usage: "Allow artifacts to contain entries or symlinks escaping sidecar directory (e.g.: absolute symlinks)",
+				},
+				cli.BoolFlag{
+					Name:  "refresh",
+					Usage: "Only download and install again sidecars which have changed since last setup",
+				},
+				cli.StringFlag{
+					Name:  "timings",
+					Value: sidecars.SetupTimingsTable,
+					Usage: "Show time spent in each setup phase by sidecar at end of setup as table, json or none",
+				},
+			},
+		},
+		{
+			Name:         "sha1",
+			Usage:        "See sha1 corresponding to your artifacts",
+			ArgsUsage:    "[sidecar names...]",
+			Action:       sha1Run,
+			BashComplete: completeSidecarNames,
+		},
+		{
+			Name:         "pin",
+			Usage:        "Write sha1 of your artifacts as artifact_sha1 in config file to enforce them",
+			ArgsUsage:    "[sidecar names...]",
+			Action:       pinRun,
+			BashComplete: completeSidecarNames,
+		},
+		{
+			Name:   "list",
+			Usage:  "List sidecars with their details and download state",
+			Action: listRun,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "json",
+					Usage: "Show details as json",
+				},
+			},
+		},
+		{
+			Name:   "plan",
+			Usage:  "Show what would be run on launch: sidecars with resolved artifacts, ports, env and start order",
+			Action: planRun,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "output, o",
+					Value: "table",
+					Usage: "Output format, table or json (json is a stable schema meant to be reviewed by tools)",
+				},
+				cli.BoolFlag{
+					Name:  "show-env",
+					Usage: "Show env values in json output, they are masked by default as they can be secrets",
+				},
+			},
+		},
+		{
+			Name:         "verify",
+			Usage:        "Verify that installed artifacts have not been modified since setup",
+			ArgsUsage:    "[sidecar names...]",
+			Action:       verifyRun,
+			BashComplete: completeSidecarNames,
+		},
+		{
+			Name:      "add",
+			Usage:     "Add a sidecar in config file, missing name or command will be asked",
+			ArgsUsage: "<sidecar name>",
+			Action:    addRun,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "command, e",
+					Usage: "Executable path to run sidecar",
+				},
+				cli.StringSliceFlag{
+					Name:  "arg",
+					Usage: "Argument to pass to executable (can be set multiple times)",
+				},
+				cli.StringFlag{
+					Name:  "artifact, a",
+					Usage: "Artifact uri to download sidecar from",
+				},
+				cli.StringFlag{
+					Name:  "artifact-type",
+					Usage: "Force artifact type detection",
+				},
+				cli.StringFlag{
+					Name:  "artifact-sha1",
+					Usage: "Sha1 to ensure to have correct downloaded artifact",
+				},
+				cli.StringFlag{
+					Name:  "after-install",
+					Usage: "Script to run after setup artifact",
+				},
+				cli.StringSliceFlag{
+					Name:  "env",
+					Usage: "Env var for sidecar in the form KEY=VALUE (can be set multiple times)",
+				},
+				cli.StringSliceFlag{
+					Name:  "app-env",
+					Usage: "Env var for app in the form KEY=VALUE (can be set multiple times)",
+				},
+				cli.BoolFlag{
+					Name:  "rproxy",
+					Usage: "Sidecar is a reverse proxy in front of app",
+				},
+			},
+		},
+		{
+			Name:   "status",
+			Usage:  "Show status of processes from a running launcher",
+			Action: statusRun,
+		},
+		{
+			Name:         "logs",
+			Usage:        "Show last output of a process from a running launcher (app process is named launcher)",
+			ArgsUsage:    "<process name>",
+			Action:       logsRun,
+			BashComplete: completeSidecarNames,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "follow, f",
+					Usage: "Follow output of process until launcher stops",
+				},
+			},
 		},
 		{
-			Name:   "sha1",
-			Usage:  "See sha1 corresponding to your artifacts",
-			Action: sha1Run,
+			Name:         "restart",
+			Usage:        "Restart sidecars of a running launcher",
+			ArgsUsage:    "[sidecar names...]",
+			Action:       restartRun,
+			BashComplete: completeSidecarNames,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "group, g",
+					Usage: "Restart all sidecars of this group, can be comma separated list or set multiple times",
+				},
+			},
+		},
+		{
+			Name:   "cf-register",
+			Usage:  "Register sidecars as platform sidecars of app with cloud foundry v3 api instead of launching them",
+			Action: cfRegisterRun,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "api",
+					Usage:  "Url of cloud foundry api, by default api of current app or cf cli target",
+					EnvVar: "CF_API",
+				},
+				cli.StringFlag{
+					Name:   "token",
+					Usage:  "Oauth token for cloud foundry api, by default token of cf cli",
+					EnvVar: "CF_TOKEN",
+				},
+				cli.StringFlag{
+					Name:  "app-guid",
+					Usage: "Guid of app where sidecars are registered, by default current app",
+				},
+				cli.StringSliceFlag{
+					Name:  "process-type",
+					Usage: "Process type of app where sidecars run (default: web), can be set multiple times",
+				},
+				cli.StringFlag{
+					Name:  "launcher-path",
+					Value: "cloud-sidecars",
+					Usage: "Path of cloud-sidecars in app container used in commands of platform sidecars",
+				},
+				cli.BoolFlag{
+					Name:  "prune",
+					Usage: "Delete sidecars of app which are not in config",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only show what would be done",
+				},
+			},
+		},
+		{
+			Name:  "generate",
+			Usage: "Generate files to run sidecars with other platforms",
+			Subcommands: []cli.Command{
+				{
+					Name:   "cnb",
+					Usage:  "Setup sidecars in a launch layer and write launch.toml with process types for app and each sidecar, this should be run by build of a cloud native buildpack",
+					Action: generateCnbRun,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:   "layers-dir",
+							Usage:  "Layers dir of buildpack given to its build",
+							EnvVar: "CNB_LAYERS_DIR",
+						},
+						cli.StringFlag{
+							Name:  "layer-name",
+							Value: "sidecars",
+							Usage: "Name of layer containing launcher and artifacts of sidecars",
+						},
+						cli.StringFlag{
+							Name:  "app-command",
+							Usage: "Command to start app in web process, by default command is detected at launch",
+						},
+						cli.BoolFlag{
+							Name:  "allow-unsafe-extract",
+							Usage: "Allow artifacts to contain entries or symlinks escaping sidecar directory (e.g.: absolute symlinks)",
+						},
+						cli.BoolFlag{
+							Name:  "refresh",
+							Usage: "Only download and install again sidecars which have changed since layer was cached",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "buildpack",
+			Usage: "Stage sidecars as phases of a cloud foundry buildpack, this is meant to be called by bin/supply or bin/finalize of a buildpack",
+			Subcommands: []cli.Command{
+				{
+					Name:      "supply",
+					Usage:     "Setup sidecars in deps dir of buildpack with launcher in its bin and profile.d files in its profile.d",
+					ArgsUsage: "<build-dir> <cache-dir> <deps-dir> <deps-idx>",
+					Action:    buildpackSupplyRun,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "allow-unsafe-extract",
+							Usage: "Allow artifacts to contain entries or symlinks escaping sidecar directory (e.g.: absolute symlinks)",
+						},
+					},
+				},
+				{
+					Name:      "finalize",
+					Usage:     "Setup sidecars in deps dir of buildpack with launcher in its bin and profile.d files in profile dir",
+					ArgsUsage: "<build-dir> <cache-dir> <deps-dir> <deps-idx> [<profile-dir>]",
+					Action:    buildpackFinalizeRun,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "allow-unsafe-extract",
+							Usage: "Allow artifacts to contain entries or symlinks escaping sidecar directory (e.g.: absolute symlinks)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:   "self-update",
+			Usage:  "Replace this binary by the one of latest release (or of given version) after verifying its checksum (integrity only, not authenticity)",
+			Action: selfUpdateRun,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "version",
+					Usage: "Version to install instead of latest release",
+				},
+				cli.StringFlag{
+					Name:  "channel",
+					Value: ReleaseChannelStable,
+					Usage: "Release channel where latest release is searched: stable or prerelease",
+				},
+				cli.BoolFlag{
+					Name:  "check",
+					Usage: "Only check if a new version is available",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Install release even if it is the current version",
+				},
+				cli.StringFlag{
+					Name:  "repo",
+					Value: defaultReleasesRepo,
+					Usage: "Github repository where releases are published",
+				},
+				cli.StringFlag{
+					Name:  "github-api",
+					Value: defaultGithubApi,
+					Usage: "Url of github api (e.g.: for github enterprise)",
+				},
+			},
+		},
+		{
+			Name:   "version",
+			Usage:  "Show version and check if a newer release is available",
+			Action: versionRun,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "check",
+					Usage: "Check if a newer release is available on channel",
+				},
+				cli.StringFlag{
+					Name:  "channel",
+					Value: ReleaseChannelStable,
+					Usage: "Release channel to check: stable or prerelease",
+				},
+				cli.StringFlag{
+					Name:  "repo",
+					Value: defaultReleasesRepo,
+					Usage: "Github repository where releases are published",
+				},
+				cli.StringFlag{
+					Name:  "github-api",
+					Value: defaultGithubApi,
+					Usage: "Url of github api (e.g.: for github enterprise)",
+				},
+			},
+		},
+		{
+			Name:   rproxy.Command,
+			Usage:  "Run builtin reverse proxy, it is started by launcher for sidecars of type builtin-rproxy",
+			Hidden: true,
+			Action: builtinRproxyRun,
+		},
+		{
+			Name:   static.Command,
+			Usage:  "Run builtin file server, it is started by launcher for sidecars of type builtin-static",
+			Hidden: true,
+			Action: builtinStaticRun,
+		},
+		{
+			Name:   forward.Command,
+			Usage:  "Run builtin tcp forwarder, it is started by launcher for sidecars of type builtin-forward",
+			Hidden: true,
+			Action: builtinForwardRun,
+		},
+		{
+			Name:            capabilities.Command,
+			Usage:           "Execute a sidecar with dropped linux capabilities, it is used by launcher for sidecars with capabilities",
+			Hidden:          true,
+			SkipFlagParsing: true,
+			Action:          execCapabilitiesRun,
+		},
+		{
+			Name:            isolation.Command,
+			Usage:           "Execute a sidecar in new linux namespaces, it is used by launcher for isolated sidecars",
+			Hidden:          true,
+			SkipFlagParsing: true,
+			Action:          execIsolatedRun,
+		},
+		{
+			Name:      "completion",
+			Usage:     "Generate shell completion script (bash, zsh or fish)",
+			ArgsUsage: "<bash|zsh|fish>",
+			Action:    completionRun,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "prog-name",
+					Usage: "Program name to complete, by default it is the current binary name",
+				},
+			},
 		},
 	}
 	return app
 }
 
+func builtinRproxyRun(c *cli.Context) error {
+	return rproxy.RunFromEnv()
+}
+
+func builtinStaticRun(c *cli.Context) error {
+	return static.RunFromEnv()
+}
+
+func builtinForwardRun(c *cli.Context) error {
+	return forward.RunFromEnv()
+}
+
+func execCapabilitiesRun(c *cli.Context) error {
+	return capabilities.ExecFromEnv(c.Args())
+}
+
+func execIsolatedRun(c *cli.Context) error {
+	return isolation.ExecFromEnv(c.Args())
+}
+
 func sha1Run(c *cli.Context) error {
 	log.SetOutput(os.Stderr)
 	loadLogConfig(&config.Sidecars{
@@ -129,15 +542,134 @@ func sha1Run(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	return l.ShowSidecarsSha1()
+	return l.ShowSidecarsSha1(c.Args()...)
+}
+
+func pinRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	fmt.Fprint(os.Stderr, "Retrieving sha1 for all of your sidecars ...\n")
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	confPath, _ := findConfPathAndDir(c)
+	return l.PinSidecarsSha1(confPath, c.Args()...)
+}
+
+func listRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.ShowSidecarsInfo(c.Bool("json"))
+}
+
+func planRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	output := c.String("output")
+	if output != "table" && output != "json" {
+		return fmt.Errorf("Output format %s is not supported, use table or json", output)
+	}
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.ShowPlan(output == "json", c.Bool("show-env"))
+}
+
+func verifyRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.ShowVerifySidecars(c.Args()...)
+}
+
+func statusRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.ShowProcessesStatus()
+}
+
+func logsRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	if c.NArg() != 1 {
+		return fmt.Errorf("You must provide a process name")
+	}
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.ShowProcessLogs(c.Args().First(), c.Bool("follow"))
+}
+
+func restartRun(c *cli.Context) error {
+	log.SetOutput(os.Stderr)
+	loadLogConfig(&config.Sidecars{
+		LogJson:  c.GlobalBool("log-json"),
+		LogLevel: "ERROR",
+		NoColor:  c.GlobalBool("no-color"),
+	})
+	groups := splitNames(c.StringSlice("group")...)
+	if c.NArg() == 0 && len(groups) == 0 {
+		return fmt.Errorf("You must provide sidecar names or groups to restart")
+	}
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	return l.RestartProcesses(c.Args(), groups)
 }
 
 func setupRun(c *cli.Context) error {
 	initApp(c)
+	switch c.String("timings") {
+	case sidecars.SetupTimingsTable, sidecars.SetupTimingsJson, sidecars.SetupTimingsNone:
+	default:
+		return fmt.Errorf("Timings format %s is not supported, use table, json or none", c.String("timings"))
+	}
 	l, err := createLauncher(c, false)
 	if err != nil {
 		return err
 	}
+	l.AllowUnsafeExtract(c.Bool("allow-unsafe-extract"))
+	l.SetRefresh(c.Bool("refresh"))
+	l.SetSetupTimings(c.String("timings"))
 	return l.Setup()
 }
 
@@ -147,9 +679,41 @@ func launchRun(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	only := splitNames(c.StringSlice("only")...)
+	if len(only) > 0 {
+		l.OnlySidecars(only...)
+	}
+	disabled := append(c.StringSlice("skip"), c.StringSlice("except")...)
+	l.DisableSidecars(splitNames(append(disabled, os.Getenv(disableSidecarsEnvKey))...)...)
+	l.SetPprofAddr(c.String("pprof-addr"))
+	if c.Bool("interactive") || c.Bool("tty") {
+		l.SetInteractive(os.Stdin, c.Bool("tty"))
+	}
+	l.SetConfigLoader(func() (*config.Sidecars, error) {
+		return launchConfig(c)
+	})
+	if l.Config().WatchConfig {
+		confPath, _ := findConfPathAndDir(c)
+		l.WatchConfig(confPath)
+	}
+	noticeNewerVersion(l.Config(), c.App.Version)
 	return l.Launch()
 }
 
+// splitNames give names from a list of comma separated names
+func splitNames(values ...string) []string {
+	names := make([]string, 0)
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
 func vendorRun(c *cli.Context) error {
 	initApp(c)
 	l, err := createLauncher(c, false)
@@ -164,13 +728,26 @@ func initApp(c *cli.Context) {
 		LogJson:  c.GlobalBool("log-json"),
 		LogLevel: c.GlobalString("log-level"),
 		NoColor:  c.GlobalBool("no-color"),
+		Quiet:    c.GlobalBool("quiet"),
 	})
 }
 
 func createLauncher(c *cli.Context, failWhenNoStarter bool) (*sidecars.Launcher, error) {
 	entry := log.WithField("component", "cli")
 	entry.Debug("Creating launcher ...")
-	conf, err := retrieveConfig(c)
+	cStarter, err := retrieveStarter(c, failWhenNoStarter)
+	if err != nil {
+		return nil, err
+	}
+	if local, ok := cStarter.(starter.Local); ok {
+		// .env is loaded before config to be used in config expansion and env overrides
+		_, dir := findConfPathAndDir(c)
+		err := local.LoadDotEnv(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conf, err := launchConfig(c)
 	if err != nil {
 		return nil, err
 	}
@@ -182,38 +759,66 @@ func createLauncher(c *cli.Context, failWhenNoStarter bool) (*sidecars.Launcher,
 	if profileDir == "" {
 		profileDir = filepath.Join(baseDir, "profile.d")
 	}
-	var cStarter starter.Starter
-
-	if !c.Bool("no-starter") {
-		entry.Debug("Loading starter ...")
-		sidecarEnv := c.GlobalString("cloud-env")
-		for _, s := range starter.Retrieve() {
-			if s.Name() == sidecarEnv {
-				log.Infof("Starter for %s is loading", s.Name())
-				cStarter = s
-				break
-			}
-			if s.Detect() && sidecarEnv == "" {
-				log.Infof("Starter for %s is loading", s.Name())
-				cStarter = s
-				break
-			}
-		}
-		if cStarter == nil && failWhenNoStarter {
-			details := ""
-			if sidecarEnv != "" {
-				details = fmt.Sprintf("for cloud-env %s", sidecarEnv)
-			}
-			return nil, fmt.Errorf("Could not found starter %s", details)
-		}
-		entry.Debug("Finished loading starter.")
-	}
 	defaultPort := c.GlobalInt("app-port")
 	l := sidecars.NewLauncher(*conf, cStarter, profileDir, os.Stdout, os.Stderr, defaultPort)
+	l.SetVersion(c.App.Version)
 	entry.Debug("Finished creating launcher.")
 	return l, nil
 }
 
+// retrieveStarter give starter set by cloud-env flag or detected, nil when starter is disabled or not found
+func retrieveStarter(c *cli.Context, failWhenNoStarter bool) (starter.Starter, error) {
+	if c.Bool("no-starter") {
+		return nil, nil
+	}
+	entry := log.WithField("component", "cli")
+	entry.Debug("Loading starter ...")
+	var cStarter starter.Starter
+	sidecarEnv := c.GlobalString("cloud-env")
+	for _, s := range starter.Retrieve() {
+		if s.Name() == sidecarEnv {
+			log.Infof("Starter for %s is loading", s.Name())
+			cStarter = s
+			break
+		}
+		if s.Detect() && sidecarEnv == "" {
+			log.Infof("Starter for %s is loading", s.Name())
+			cStarter = s
+			break
+		}
+	}
+	if cStarter == nil && failWhenNoStarter {
+		details := ""
+		if sidecarEnv != "" {
+			details = fmt.Sprintf("for cloud-env %s", sidecarEnv)
+		}
+		return nil, fmt.Errorf("Could not found starter %s", details)
+	}
+	entry.Debug("Finished loading starter.")
+	return cStarter, nil
+}
+
+// launchConfig give config with overrides from command flags
+func launchConfig(c *cli.Context) (*config.Sidecars, error) {
+	conf, err := retrieveConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if c.String("app-command") != "" {
+		conf.AppCommand = c.String("app-command")
+	}
+	if c.Bool("watch") {
+		conf.WatchConfig = true
+	}
+	if c.GlobalBool("quiet") {
+		conf.Quiet = true
+	}
+	if c.Bool("no-control-api") {
+		conf.NoControlApi = true
+	}
+	return conf, nil
+}
+
 func retrieveConfig(c *cli.Context) (*config.Sidecars, error) {
 	// Has been modified in init, reset it after loading config for possible env var usage in sidecars
 	defer os.Unsetenv(cloudenv.LOCAL_CONFIG_ENV_KEY)
@@ -224,7 +829,12 @@ func retrieveConfig(c *cli.Context) (*config.Sidecars, error) {
 	confFileIntercept.SetConfigPath(confPath)
 
 	conf := &config.Sidecars{}
-	err := gautocloud.Inject(conf)
+	var err error
+	if config.IsSopsFile(confPath) {
+		err = loadSopsConfig(confPath, conf)
+	} else {
+		err = gautocloud.Inject(conf)
+	}
 	if _, ok := err.(loader.ErrGiveService); ok {
 		log.Warnf("Cannot found configuration from gautocloud, fallback to %s file", confPath)
 		var b []byte
@@ -237,9 +847,36 @@ func retrieveConfig(c *cli.Context) (*config.Sidecars, error) {
 			return nil, fmt.Errorf("configuration loading from %s error: %s", confPath, err.Error())
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	err = conf.ApplyOverrides(os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	conf.ExpandEnv()
 	conf.Dir = baseDir
+	// sidecars are checked when unmarshalled, config as a whole can only be checked once overridden and expanded
+	err = conf.Check()
+	if err != nil {
+		return nil, err
+	}
 	log.WithField("component", "cli").Debug("Finished loading configuration.")
-	return conf, err
+	return conf, nil
+}
+
+// loadSopsConfig load config file encrypted with sops, it is decrypted in memory and never written on disk
+func loadSopsConfig(confPath string, conf *config.Sidecars) error {
+	log.WithField("component", "cli").Debugf("Decrypting configuration %s with sops ...", confPath)
+	b, err := config.DecryptSopsFile(confPath)
+	if err != nil {
+		return err
+	}
+	err = yaml.Unmarshal(b, conf)
+	if err != nil {
+		return fmt.Errorf("configuration loading from %s error: %s", confPath, err.Error())
+	}
+	return nil
 }
 
 func findConfPathAndDir(c *cli.Context) (confPath string, dir string) {
@@ -282,32 +919,63 @@ func findConfPathAndDir(c *cli.Context) (confPath string, dir string) {
 }
 
 func loadLogConfig(c *config.Sidecars) {
+	// colors are already disabled by logrus when output is not a terminal
+	var formatter log.Formatter = &log.TextFormatter{
+		DisableColors:             c.NoColor || utils.NoColor(),
+		EnvironmentOverrideColors: true,
+	}
 	if c.LogJson {
-		log.SetFormatter(&log.JSONFormatter{})
-	} else {
-		log.SetFormatter(&log.TextFormatter{
-			DisableColors: c.NoColor,
-		})
+		formatter = &log.JSONFormatter{}
 	}
 
-	if c.LogLevel == "" {
+	level := log.GetLevel()
+	if lvl, ok := parseLogLevel(c.LogLevel); ok {
+		level = lvl
+	}
+	if c.Quiet {
+		level = log.ErrorLevel
+	}
+	if len(c.LogLevels) == 0 {
+		log.SetFormatter(formatter)
+		log.SetLevel(level)
 		return
 	}
-	switch strings.ToUpper(c.LogLevel) {
+	// logger must let pass entries of the most verbose component, others are filtered by formatter
+	maxLevel := level
+	levels := make(map[string]log.Level)
+	for component, componentLevel := range c.LogLevels {
+		lvl, ok := parseLogLevel(componentLevel)
+		if !ok {
+			log.Warnf("Log level '%s' of component %s is not valid, it is ignored", componentLevel, component)
+			continue
+		}
+		levels[strings.ToLower(component)] = lvl
+		if lvl > maxLevel {
+			maxLevel = lvl
+		}
+	}
+	log.SetFormatter(&componentLevelFormatter{
+		formatter:    formatter,
+		defaultLevel: level,
+		levels:       levels,
+	})
+	log.SetLevel(maxLevel)
+}
+
+func parseLogLevel(level string) (log.Level, bool) {
+	switch strings.ToUpper(level) {
 	case "ERROR":
-		log.SetLevel(log.ErrorLevel)
-		return
+		return log.ErrorLevel, true
 	case "WARN":
-		log.SetLevel(log.WarnLevel)
-		return
+		return log.WarnLevel, true
+	case "INFO":
+		return log.InfoLevel, true
 	case "DEBUG":
-		log.SetLevel(log.DebugLevel)
-		return
+		return log.DebugLevel, true
 	case "PANIC":
-		log.SetLevel(log.PanicLevel)
-		return
+		return log.PanicLevel, true
 	case "FATAL":
-		log.SetLevel(log.FatalLevel)
-		return
+		return log.FatalLevel, true
 	}
+	return log.InfoLevel, false
 }
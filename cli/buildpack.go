@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const (
+	buildpackName        = "cloud-sidecars"
+	buildpackProfileName = "000_cloud-sidecars.sh"
+)
+
+// buildpackSupplyRun setup sidecars as supply phase of a cloud foundry buildpack,
+// launcher, artifacts and profile.d files are written in deps dir of buildpack
+func buildpackSupplyRun(c *cli.Context) error {
+	if c.NArg() < 4 {
+		return fmt.Errorf("Build dir, cache dir, deps dir and deps index must be given as arguments as buildpack lifecycle does")
+	}
+	depDir := filepath.Join(c.Args().Get(2), c.Args().Get(3))
+	return buildpackStage(c, c.Args().Get(0), depDir, c.Args().Get(3), filepath.Join(depDir, "profile.d"))
+}
+
+// buildpackFinalizeRun setup sidecars as finalize phase of a cloud foundry buildpack,
+// launcher and artifacts are written in deps dir of buildpack and profile.d files in profile dir given by lifecycle
+func buildpackFinalizeRun(c *cli.Context) error {
+	if c.NArg() < 4 {
+		return fmt.Errorf("Build dir, cache dir, deps dir and deps index must be given as arguments as buildpack lifecycle does")
+	}
+	buildDir := c.Args().Get(0)
+	profileDir := c.Args().Get(4)
+	if profileDir == "" {
+		profileDir = filepath.Join(buildDir, ".profile.d")
+	}
+	return buildpackStage(c, buildDir, filepath.Join(c.Args().Get(2), c.Args().Get(3)), c.Args().Get(3), profileDir)
+}
+
+func buildpackStage(c *cli.Context, buildDir, depDir, depIdx, profileDir string) error {
+	initApp(c)
+	entry := log.WithField("component", "buildpack")
+	err := c.GlobalSet("dir", buildDir)
+	if err != nil {
+		return err
+	}
+	if c.GlobalString("profile-dir") == "" {
+		err = c.GlobalSet("profile-dir", profileDir)
+		if err != nil {
+			return err
+		}
+	}
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	conf := l.Config()
+
+	entry.Infof("Linking %s to deps dir %s ...", sidecars.PathSidecarsWd, depDir)
+	err = linkSidecarsDir(filepath.Join(conf.Dir, sidecars.PathSidecarsWd), filepath.Join(depDir, "sidecars"))
+	if err != nil {
+		return err
+	}
+	l.AllowUnsafeExtract(c.Bool("allow-unsafe-extract"))
+	err = l.Setup()
+	if err != nil {
+		return err
+	}
+
+	launcherPath := filepath.Join(depDir, "bin", buildpackName)
+	entry.Infof("Copying launcher to %s ...", launcherPath)
+	err = copyExecutable(launcherPath)
+	if err != nil {
+		return err
+	}
+	// bin of deps dir is not in PATH at runtime, it is added by a profile.d file sourced before the app starts
+	err = ioutil.WriteFile(
+		filepath.Join(c.GlobalString("profile-dir"), buildpackProfileName),
+		[]byte(fmt.Sprintf("export PATH=\"$DEPS_DIR/%s/bin:$PATH\"\n", depIdx)),
+		0755,
+	)
+	if err != nil {
+		return err
+	}
+	configYml := fmt.Sprintf("name: %s\nconfig: {}\nversion: %q\n", buildpackName, c.App.Version)
+	err = ioutil.WriteFile(filepath.Join(depDir, "config.yml"), []byte(configYml), 0644)
+	if err != nil {
+		return err
+	}
+	entry.Info("Finished staging sidecars, start app with 'cloud-sidecars launch'.")
+	return nil
+}
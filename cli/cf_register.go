@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"gopkg.in/alessio/shellescape.v1"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const cfApiTimeout = 30 * time.Second
+
+// cfSidecar is a sidecar of cloud foundry v3 api
+type cfSidecar struct {
+	Guid         string   `json:"guid,omitempty"`
+	Name         string   `json:"name"`
+	Command      string   `json:"command"`
+	ProcessTypes []string `json:"process_types"`
+	Origin       string   `json:"origin,omitempty"`
+}
+
+type cfClient struct {
+	client *http.Client
+	api    string
+	token  string
+}
+
+// cfRegisterRun register sidecars of config as platform sidecars on app with cloud foundry v3 api,
+// each platform sidecar runs launcher with only its sidecar
+func cfRegisterRun(c *cli.Context) error {
+	initApp(c)
+	entry := log.WithField("component", "cf-register")
+	conf, err := retrieveConfig(c)
+	if err != nil {
+		return err
+	}
+	cf, err := newCfClient(c)
+	if err != nil {
+		return err
+	}
+	appGuid := c.String("app-guid")
+	if appGuid == "" {
+		appGuid = vcapApplication().ApplicationId
+	}
+	if appGuid == "" {
+		return fmt.Errorf("App guid must be given with --app-guid when not running on cloud foundry")
+	}
+	existing, err := cf.appSidecars(appGuid)
+	if err != nil {
+		return err
+	}
+	processTypes := c.StringSlice("process-type")
+	if len(processTypes) == 0 {
+		processTypes = []string{"web"}
+	}
+	dryRun := c.Bool("dry-run")
+	registered := make([]string, 0)
+	for _, sidecar := range conf.Sidecars {
+		if sidecar.IsRproxy {
+			entry.Warnf("Sidecar %s is a reverse proxy and cannot be registered as platform sidecar, it is skipped.", sidecar.Name)
+			continue
+		}
+		wanted := cfSidecar{
+			Name:         sidecar.Name,
+			Command:      platformSidecarCommand(c.String("launcher-path"), sidecar),
+			ProcessTypes: processTypes,
+		}
+		registered = append(registered, sidecar.Name)
+		current, ok := existing[sidecar.Name]
+		switch {
+		case ok && current.Command == wanted.Command && strings.Join(current.ProcessTypes, ",") == strings.Join(wanted.ProcessTypes, ","):
+			entry.Infof("Sidecar %s is already registered.", sidecar.Name)
+		case dryRun:
+			entry.Infof("Sidecar %s would be registered with command: %s", sidecar.Name, wanted.Command)
+		case ok:
+			entry.Infof("Updating sidecar %s ...", sidecar.Name)
+			err = cf.do(http.MethodPatch, "/v3/sidecars/"+current.Guid, wanted, nil)
+		default:
+			entry.Infof("Registering sidecar %s ...", sidecar.Name)
+			err = cf.do(http.MethodPost, "/v3/apps/"+appGuid+"/sidecars", wanted, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("Could not register sidecar %s: %s", sidecar.Name, err.Error())
+		}
+	}
+	if c.Bool("prune") {
+		for name, current := range existing {
+			if current.Origin != "user" || conf.SidecarByName(name) != nil {
+				continue
+			}
+			entry.Infof("Deleting sidecar %s which is not in config ...", name)
+			if dryRun {
+				continue
+			}
+			err = cf.do(http.MethodDelete, "/v3/sidecars/"+current.Guid, nil, nil)
+			if err != nil {
+				return fmt.Errorf("Could not delete sidecar %s: %s", name, err.Error())
+			}
+		}
+	}
+	if len(registered) > 0 && !dryRun {
+		entry.Infof(
+			"Sidecars are run by platform after restarting app, launch app with --skip %s to not run them twice.",
+			strings.Join(registered, ","),
+		)
+	}
+	return nil
+}
+
+// platformSidecarCommand give command run by platform for sidecar
+func platformSidecarCommand(launcherPath string, sidecar *config.Sidecar) string {
+	return fmt.Sprintf(
+		"%s launch --no-starter --no-control-api --only %s",
+		shellescape.Quote(launcherPath), shellescape.Quote(sidecar.Name),
+	)
+}
+
+type cfVcapApplication struct {
+	ApplicationId string `json:"application_id"`
+	CfApi         string `json:"cf_api"`
+}
+
+func vcapApplication() cfVcapApplication {
+	var vcap cfVcapApplication
+	json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &vcap)
+	return vcap
+}
+
+// newCfClient give client of api given by flags, VCAP_APPLICATION or else by cf cli config (target and token of cf login)
+func newCfClient(c *cli.Context) (*cfClient, error) {
+	api := c.String("api")
+	if api == "" {
+		api = vcapApplication().CfApi
+	}
+	token := c.String("token")
+	if api == "" || token == "" {
+		cfConfig, err := cfCliConfig()
+		if err == nil {
+			if api == "" {
+				api = cfConfig.Target
+			}
+			if token == "" {
+				token = cfConfig.AccessToken
+			}
+		}
+	}
+	if api == "" || token == "" {
+		return nil, fmt.Errorf("Cloud foundry api and token must be given with --api and --token or by logging in with cf cli")
+	}
+	if !strings.HasPrefix(strings.ToLower(token), "bearer ") {
+		token = "bearer " + token
+	}
+	return &cfClient{
+		client: &http.Client{Timeout: cfApiTimeout},
+		api:    strings.TrimSuffix(api, "/"),
+		token:  token,
+	}, nil
+}
+
+type cfCliConfigFile struct {
+	Target      string `json:"Target"`
+	AccessToken string `json:"AccessToken"`
+}
+
+func cfCliConfig() (cfCliConfigFile, error) {
+	var conf cfCliConfigFile
+	home := os.Getenv("CF_HOME")
+	if home == "" {
+		var err error
+		home, err = os.UserHomeDir()
+		if err != nil {
+			return conf, err
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(home, ".cf", "config.json"))
+	if err != nil {
+		return conf, err
+	}
+	err = json.Unmarshal(b, &conf)
+	return conf, err
+}
+
+// appSidecars give sidecars of app by name
+func (cf *cfClient) appSidecars(appGuid string) (map[string]cfSidecar, error) {
+	sidecars := make(map[string]cfSidecar)
+	path := "/v3/apps/" + appGuid + "/sidecars?per_page=5000"
+	for path != "" {
+		var page struct {
+			Pagination struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"pagination"`
+			Resources []cfSidecar `json:"resources"`
+		}
+		err := cf.do(http.MethodGet, path, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, sidecar := range page.Resources {
+			sidecars[sidecar.Name] = sidecar
+		}
+		path = ""
+		if page.Pagination.Next != nil {
+			path = strings.TrimPrefix(page.Pagination.Next.Href, cf.api)
+		}
+	}
+	return sidecars, nil
+}
+
+func (cf *cfClient) do(method, path string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, cf.api+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", cf.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cf.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("Cloud foundry api refused token, log in again with cf cli or give a fresh token (cf oauth-token)")
+	}
+	if resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Cloud foundry api answered %s on %s %s: %s", resp.Status, method, path, strings.TrimSpace(string(b)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
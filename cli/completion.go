@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const bashCompletionTpl = `_{{ .FuncName }}_bash_autocomplete() {
+  if [[ "${COMP_WORDS[0]}" != "source" ]]; then
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    if [[ "$cur" == "-"* ]]; then
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} ${cur} --generate-bash-completion 2>/dev/null )
+    else
+      opts=$( ${COMP_WORDS[@]:0:$COMP_CWORD} --generate-bash-completion 2>/dev/null )
+    fi
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+  fi
+}
+
+complete -o bashdefault -o default -o nospace -F _{{ .FuncName }}_bash_autocomplete {{ .Prog }}
+`
+
+const zshCompletionTpl = `#compdef {{ .Prog }}
+
+_{{ .FuncName }}_zsh_autocomplete() {
+  local -a opts
+  local cur
+  cur=${words[-1]}
+  if [[ "$cur" == "-"* ]]; then
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} ${cur} --generate-bash-completion 2>/dev/null)}")
+  else
+    opts=("${(@f)$(_CLI_ZSH_AUTOCOMPLETE_HACK=1 ${words[@]:0:#words[@]-1} --generate-bash-completion 2>/dev/null)}")
+  fi
+
+  if [[ "${opts[1]}" != "" ]]; then
+    _describe 'values' opts
+  else
+    _files
+  fi
+}
+
+compdef _{{ .FuncName }}_zsh_autocomplete {{ .Prog }}
+`
+
+const fishCompletionTpl = `function __fish_{{ .FuncName }}_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l cur (commandline -ct)
+    if string match -q -- '-*' $cur
+        {{ .Prog }} $args $cur --generate-bash-completion 2>/dev/null
+    else
+        {{ .Prog }} $args --generate-bash-completion 2>/dev/null
+    end
+end
+
+complete -c {{ .Prog }} -f -a '(__fish_{{ .FuncName }}_complete)'
+`
+
+var completionTpls = map[string]string{
+	"bash": bashCompletionTpl,
+	"zsh":  zshCompletionTpl,
+	"fish": fishCompletionTpl,
+}
+
+var funcNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func completionRun(c *cli.Context) error {
+	shell := c.Args().First()
+	tpl, ok := completionTpls[shell]
+	if !ok {
+		return fmt.Errorf("Shell '%s' is not supported, you must choose one of: bash, zsh, fish", shell)
+	}
+	prog := c.String("prog-name")
+	if prog == "" {
+		prog = filepath.Base(os.Args[0])
+	}
+	t, err := template.New(shell).Parse(tpl)
+	if err != nil {
+		return err
+	}
+	return t.Execute(c.App.Writer, struct {
+		Prog     string
+		FuncName string
+	}{
+		Prog:     prog,
+		FuncName: funcNameSanitizer.ReplaceAllString(prog, "_"),
+	})
+}
+
+// completeSidecarNames complete command args with sidecar names found in config
+// and fallback on flags completion when user is typing a flag
+func completeSidecarNames(c *cli.Context) {
+	if len(os.Args) > 2 && strings.HasPrefix(os.Args[len(os.Args)-2], "-") {
+		cli.DefaultCompleteWithFlags(&c.Command)(c)
+		return
+	}
+	// logs must not be mixed with completion results
+	log.SetOutput(ioutil.Discard)
+	conf, err := retrieveConfig(c)
+	if err != nil || conf == nil {
+		return
+	}
+	alreadySet := make(map[string]bool)
+	for _, arg := range c.Args() {
+		alreadySet[arg] = true
+	}
+	for _, sidecar := range conf.Sidecars {
+		if alreadySet[sidecar.Name] {
+			continue
+		}
+		fmt.Fprintln(c.App.Writer, sidecar.Name)
+	}
+}
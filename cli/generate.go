@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	launchTomlName    = "launch.toml"
+	cnbLauncherName   = "cloud-sidecars"
+	cnbSidecarTypePfx = "sidecar-"
+)
+
+// cnbProcess is a process type of launch.toml as defined by buildpack api 0.9
+type cnbProcess struct {
+	Type    string
+	Command []string
+	Default bool
+}
+
+// generateCnbRun write a launch layer containing launcher and artifacts of sidecars and a launch.toml
+// with a web process launching app with sidecars and a process type for each sidecar,
+// this is meant to be run by build phase of a cloud native buildpack
+func generateCnbRun(c *cli.Context) error {
+	initApp(c)
+	entry := log.WithField("component", "generate").WithField("output", "cnb")
+	if c.String("layers-dir") == "" {
+		return fmt.Errorf("Layers dir must be given with --layers-dir (e.g.: layers dir given as first argument of bin/build)")
+	}
+	layersDir, err := filepath.Abs(c.String("layers-dir"))
+	if err != nil {
+		return err
+	}
+	layerDir := filepath.Join(layersDir, c.String("layer-name"))
+	if c.GlobalString("profile-dir") == "" {
+		err = c.GlobalSet("profile-dir", filepath.Join(layerDir, "profile.d"))
+		if err != nil {
+			return err
+		}
+	}
+	l, err := createLauncher(c, false)
+	if err != nil {
+		return err
+	}
+	conf := l.Config()
+
+	entry.Infof("Linking %s to layer %s ...", sidecars.PathSidecarsWd, layerDir)
+	err = linkSidecarsDir(filepath.Join(conf.Dir, sidecars.PathSidecarsWd), filepath.Join(layerDir, "sidecars"))
+	if err != nil {
+		return err
+	}
+	l.AllowUnsafeExtract(c.Bool("allow-unsafe-extract"))
+	l.SetRefresh(c.Bool("refresh"))
+	err = l.Setup()
+	if err != nil {
+		return err
+	}
+	for _, sidecar := range conf.Sidecars {
+		if sidecar.InstallDir != "" {
+			entry.Warnf("Sidecar %s has an install_dir, its artifact is kept in app dir instead of layer.", sidecar.Name)
+		}
+	}
+
+	launcherPath := filepath.Join(layerDir, "bin", cnbLauncherName)
+	entry.Infof("Copying launcher to %s ...", launcherPath)
+	err = copyExecutable(launcherPath)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(layerDir+".toml", []byte("[types]\nlaunch = true\ncache = true\n"), 0644)
+	if err != nil {
+		return err
+	}
+
+	webCommand := []string{launcherPath, "launch"}
+	if c.String("app-command") != "" {
+		webCommand = append(webCommand, "--app-command", c.String("app-command"))
+	}
+	processes := []cnbProcess{{Type: "web", Command: webCommand, Default: true}}
+	for _, sidecar := range conf.Sidecars {
+		if sidecar.IsRproxy {
+			entry.Warnf("Sidecar %s is a reverse proxy and has no process type, it is only run by web process.", sidecar.Name)
+			continue
+		}
+		processes = append(processes, cnbProcess{
+			Type:    cnbSidecarTypePfx + sidecar.Name,
+			Command: []string{launcherPath, "launch", "--no-starter", "--no-control-api", "--only", sidecar.Name},
+		})
+	}
+	entry.Infof("Writing %s ...", launchTomlName)
+	err = ioutil.WriteFile(filepath.Join(layersDir, launchTomlName), launchToml(processes), 0644)
+	if err != nil {
+		return err
+	}
+	entry.Infof("Finished generating cnb layer %s.", c.String("layer-name"))
+	return nil
+}
+
+// launchToml give content of launch.toml for processes
+func launchToml(processes []cnbProcess) []byte {
+	buf := &bytes.Buffer{}
+	for i, process := range processes {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("[[processes]]\n")
+		fmt.Fprintf(buf, "type = %s\n", tomlValue(process.Type))
+		fmt.Fprintf(buf, "command = %s\n", tomlValue(process.Command))
+		buf.WriteString("args = []\n")
+		fmt.Fprintf(buf, "default = %t\n", process.Default)
+	}
+	return buf.Bytes()
+}
+
+// tomlValue give a string or an array of strings in toml, json escapes are all valid toml escapes
+func tomlValue(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// linkSidecarsDir make sidecars dir of app a relative symlink to target dir (e.g.: dir in a layer),
+// content already in sidecars dir (e.g.: config file) is moved to target dir
+func linkSidecarsDir(sidecarsDir, targetDir string) error {
+	err := os.MkdirAll(targetDir, 0755)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Lstat(sidecarsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		err = os.Remove(sidecarsDir)
+		if err != nil {
+			return err
+		}
+	}
+	if err == nil && fi.IsDir() {
+		files, err := ioutil.ReadDir(sidecarsDir)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			err = moveFile(filepath.Join(sidecarsDir, file.Name()), filepath.Join(targetDir, file.Name()))
+			if err != nil {
+				return err
+			}
+		}
+		err = os.Remove(sidecarsDir)
+		if err != nil {
+			return err
+		}
+	}
+	// relative link stays valid when app and target are moved together (e.g.: droplet extracted in another dir)
+	link, err := filepath.Rel(filepath.Dir(sidecarsDir), targetDir)
+	if err != nil {
+		link = targetDir
+	}
+	return os.Symlink(link, sidecarsDir)
+}
+
+// moveFile move file or dir to dst, replacing dst, by copying it when it cannot be renamed (e.g.: layers on another volume)
+func moveFile(src, dst string) error {
+	err := os.RemoveAll(dst)
+	if err != nil {
+		return err
+	}
+	if os.Rename(src, dst) == nil {
+		return nil
+	}
+	err = filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case fi.IsDir():
+			return os.MkdirAll(target, fi.Mode().Perm())
+		default:
+			return copyFile(path, target, fi.Mode().Perm())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyExecutable copy current binary to dst
+func copyExecutable(dst string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	if exe == dst {
+		return nil
+	}
+	// removing first allow to replace a binary restored from cache even if it is running
+	os.Remove(dst)
+	return copyFile(exe, dst, 0755)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// componentLevelFormatter drop log entries which are under level set for their component,
+// component is given by component field or is process for entries about a sidecar or app process
+type componentLevelFormatter struct {
+	formatter    log.Formatter
+	defaultLevel log.Level
+	levels       map[string]log.Level
+}
+
+func (f *componentLevelFormatter) Format(entry *log.Entry) ([]byte, error) {
+	if entry.Level > f.level(entry) {
+		return []byte{}, nil
+	}
+	return f.formatter.Format(entry)
+}
+
+func (f *componentLevelFormatter) level(entry *log.Entry) log.Level {
+	component := ""
+	if c, ok := entry.Data["component"].(string); ok {
+		component = strings.ToLower(c)
+	} else if _, ok := entry.Data["sidecar"]; ok {
+		component = "process"
+	} else if _, ok := entry.Data["cloud"]; ok {
+		component = "process"
+	}
+	if level, ok := f.levels[component]; ok {
+		return level
+	}
+	return f.defaultLevel
+}
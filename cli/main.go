@@ -7,11 +7,18 @@ import (
 
 var Version string
 
+type exitCoder interface {
+	ExitCode() int
+}
+
 func main() {
 	app := NewApp(Version)
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Error(err)
+		if exitErr, ok := err.(exitCoder); ok {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
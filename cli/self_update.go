@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReleasesRepo = "gmllt/cloud-sidecars"
+	defaultGithubApi    = "https://api.github.com"
+	releaseChecksumFile = "sha256.txt"
+	selfUpdateTimeout   = 5 * time.Minute
+)
+
+const (
+	ReleaseChannelStable     = "stable"
+	ReleaseChannelPrerelease = "prerelease"
+)
+
+type githubRelease struct {
+	TagName string               `json:"tag_name"`
+	Draft   bool                 `json:"draft"`
+	Assets  []githubReleaseAsset `json:"assets"`
+}
+
+type githubReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadUrl string `json:"browser_download_url"`
+}
+
+func (r githubRelease) asset(name string) (githubReleaseAsset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return githubReleaseAsset{}, false
+}
+
+func selfUpdateRun(c *cli.Context) error {
+	initApp(c)
+	entry := log.WithField("component", "self-update")
+	client := newGithubClient(selfUpdateTimeout)
+	var release githubRelease
+	var err error
+	if c.String("version") != "" {
+		release, err = fetchRelease(client, c.String("github-api"), c.String("repo"), c.String("version"))
+	} else {
+		release, err = fetchChannelRelease(client, c.String("github-api"), c.String("repo"), c.String("channel"))
+	}
+	if err != nil {
+		return err
+	}
+	current := strings.TrimPrefix(c.App.Version, "v")
+	latest := strings.TrimPrefix(release.TagName, "v")
+	if latest == current && !c.Bool("force") {
+		entry.Infof("Version %s is already installed.", current)
+		return nil
+	}
+	if c.Bool("check") {
+		entry.Infof("Version %s is available, current version is %s.", latest, current)
+		return nil
+	}
+
+	assetName := fmt.Sprintf("cloud-sidecars_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		assetName += ".exe"
+	}
+	binAsset, ok := release.asset(assetName)
+	if !ok {
+		return fmt.Errorf("No binary %s found in release %s", assetName, release.TagName)
+	}
+	checksumAsset, ok := release.asset(releaseChecksumFile)
+	if !ok {
+		return fmt.Errorf("No %s found in release %s, binary can't be verified", releaseChecksumFile, release.TagName)
+	}
+	expected, err := fetchChecksum(client, checksumAsset.BrowserDownloadUrl, assetName)
+	if err != nil {
+		return err
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exePath, err = filepath.EvalSymlinks(exePath)
+	if err != nil {
+		return err
+	}
+	entry.Infof("Downloading %s from release %s ...", assetName, release.TagName)
+	// new binary is written beside current one to be renamed atomically on the same filesystem
+	tmpFile, err := ioutil.TempFile(filepath.Dir(exePath), ".cloud-sidecars-update-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	checksum, err := downloadTo(client, binAsset.BrowserDownloadUrl, tmpFile)
+	tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(checksum, expected) {
+		return fmt.Errorf("Checksum of downloaded binary %s mismatch with expected checksum %s", checksum, expected)
+	}
+	entry.Infof("Finished downloading, checksum %s verified.", checksum)
+
+	err = os.Chmod(tmpFile.Name(), 0755)
+	if err != nil {
+		return err
+	}
+	err = replaceExecutable(exePath, tmpFile.Name())
+	if err != nil {
+		return err
+	}
+	entry.Infof("cloud-sidecars has been updated from version %s to %s.", current, latest)
+	return nil
+}
+
+// fetchRelease give release with given version from github
+func fetchRelease(client githubClient, apiUrl, repo, version string) (githubRelease, error) {
+	var release githubRelease
+	url := fmt.Sprintf("%s/repos/%s/releases/tags/v%s", strings.TrimSuffix(apiUrl, "/"), repo, strings.TrimPrefix(version, "v"))
+	err := githubGetJson(client, url, &release)
+	return release, err
+}
+
+// fetchChannelRelease give latest release of channel, stable channel only gives releases which are not prereleases
+func fetchChannelRelease(client githubClient, apiUrl, repo, channel string) (githubRelease, error) {
+	var release githubRelease
+	switch channel {
+	case "", ReleaseChannelStable:
+		url := fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimSuffix(apiUrl, "/"), repo)
+		err := githubGetJson(client, url, &release)
+		return release, err
+	case ReleaseChannelPrerelease:
+	default:
+		return release, fmt.Errorf("Unknown release channel '%s', it must be %s or %s", channel, ReleaseChannelStable, ReleaseChannelPrerelease)
+	}
+	// releases are listed from the most recent one
+	var releases []githubRelease
+	url := fmt.Sprintf("%s/repos/%s/releases", strings.TrimSuffix(apiUrl, "/"), repo)
+	err := githubGetJson(client, url, &releases)
+	if err != nil {
+		return release, err
+	}
+	for _, release := range releases {
+		if !release.Draft {
+			return release, nil
+		}
+	}
+	return release, fmt.Errorf("No release found in %s", repo)
+}
+
+// fetchChecksum give checksum of asset from checksum file of release,
+// lines can be in the form '<checksum>  <name>' or '<name> - <checksum>'
+func fetchChecksum(client githubClient, url, assetName string) (string, error) {
+	resp, err := githubGet(client, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 2 && fields[1] == assetName:
+			return fields[0], nil
+		case len(fields) == 3 && fields[0] == assetName && fields[1] == "-":
+			return fields[2], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("No checksum found for %s in %s", assetName, releaseChecksumFile)
+}
+
+// downloadTo write content of url in file and give its sha256 checksum
+func downloadTo(client githubClient, url string, f *os.File) (string, error) {
+	resp, err := githubGet(client, url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func githubGetJson(client githubClient, url string, v interface{}) error {
+	resp, err := githubGet(client, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("Could not read release from %s: %s", url, err.Error())
+	}
+	return nil
+}
+
+// githubClient request github, token is sent when set to avoid rate limit of github api
+type githubClient struct {
+	*http.Client
+	token string
+}
+
+// newGithubClient give a client using GITHUB_TOKEN env var of user running command
+func newGithubClient(timeout time.Duration) githubClient {
+	return githubClient{
+		Client: &http.Client{Timeout: timeout},
+		token:  os.Getenv("GITHUB_TOKEN"),
+	}
+}
+
+func githubGet(client githubClient, url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if client.token != "" {
+		req.Header.Set("Authorization", "token "+client.token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Request to %s failed with status %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// replaceExecutable rename new binary over current one,
+// running binary can't be overwritten on windows, it is moved aside first
+func replaceExecutable(exePath, newPath string) error {
+	if runtime.GOOS != "windows" {
+		return os.Rename(newPath, exePath)
+	}
+	oldPath := exePath + ".old"
+	os.Remove(oldPath)
+	err := os.Rename(exePath, oldPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(newPath, exePath)
+	if err != nil {
+		os.Rename(oldPath, exePath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const versionCheckTimeout = 5 * time.Second
+
+func versionRun(c *cli.Context) error {
+	fmt.Fprintln(c.App.Writer, c.App.Version)
+	if !c.Bool("check") {
+		return nil
+	}
+	client := newGithubClient(versionCheckTimeout)
+	release, err := fetchChannelRelease(client, c.String("github-api"), c.String("repo"), c.String("channel"))
+	if err != nil {
+		return err
+	}
+	latest := strings.TrimPrefix(release.TagName, "v")
+	if !utils.IsNewerVersion(latest, c.App.Version) {
+		fmt.Fprintf(c.App.Writer, "Version is up to date on channel %s.\n", channelName(c.String("channel")))
+		return nil
+	}
+	fmt.Fprintf(c.App.Writer, "Version %s is available on channel %s, run self-update to install it.\n", latest, channelName(c.String("channel")))
+	return nil
+}
+
+// noticeNewerVersion log a notice when a newer release is available on configured channel if update_check is set,
+// it never blocks launch and errors are only logged in debug
+func noticeNewerVersion(conf config.Sidecars, current string) {
+	if !conf.UpdateCheck || !isReleaseVersion(current) {
+		return
+	}
+	go func() {
+		entry := log.WithField("component", "version-check")
+		// GITHUB_TOKEN of app env must not be sent to github
+		client := githubClient{Client: &http.Client{Timeout: versionCheckTimeout}}
+		release, err := fetchChannelRelease(client, defaultGithubApi, defaultReleasesRepo, conf.UpdateChannel)
+		if err != nil {
+			entry.Debugf("Could not check for a newer version: %s", err.Error())
+			return
+		}
+		latest := strings.TrimPrefix(release.TagName, "v")
+		if utils.IsNewerVersion(latest, current) {
+			entry.Infof("Version %s of cloud-sidecars is available on channel %s (current version is %s).",
+				latest, channelName(conf.UpdateChannel), current)
+		}
+	}()
+}
+
+func channelName(channel string) string {
+	if channel == "" {
+		return ReleaseChannelStable
+	}
+	return channel
+}
+
+// isReleaseVersion check that binary has been built from a release, dev builds are not checked
+func isReleaseVersion(version string) bool {
+	return version != "" && version != "0.0.0" && version != "dev"
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+)
+
+// SidecarsBuilder build a config in go code for tools generating sidecars dynamically,
+// config is validated in Build, e.g.:
+//
+//	conf, err := config.NewBuilder().
+//		AppCommand("./my-app").
+//		Sidecar(config.NewSidecar("envoy").Executable("envoy", "-c", "envoy.yaml").Rproxy()).
+//		Build()
+type SidecarsBuilder struct {
+	conf     Sidecars
+	sidecars []*SidecarBuilder
+}
+
+func NewBuilder() *SidecarsBuilder {
+	return &SidecarsBuilder{}
+}
+
+// Dir set directory where app and sidecars are installed
+func (b *SidecarsBuilder) Dir(dir string) *SidecarsBuilder {
+	b.conf.Dir = dir
+	return b
+}
+
+func (b *SidecarsBuilder) AppPort(port int) *SidecarsBuilder {
+	b.conf.AppPort = port
+	return b
+}
+
+func (b *SidecarsBuilder) AppCommand(command string) *SidecarsBuilder {
+	b.conf.AppCommand = command
+	return b
+}
+
+// NoStarter make launcher only run sidecars without app
+func (b *SidecarsBuilder) NoStarter() *SidecarsBuilder {
+	b.conf.NoStarter = true
+	return b
+}
+
+func (b *SidecarsBuilder) LogLevel(level string) *SidecarsBuilder {
+	b.conf.LogLevel = level
+	return b
+}
+
+// GroupOrder set groups of sidecars which are started one after the other
+func (b *SidecarsBuilder) GroupOrder(groups ...string) *SidecarsBuilder {
+	b.conf.GroupOrder = append(b.conf.GroupOrder, groups...)
+	return b
+}
+
+// Configure let set any other field of config
+func (b *SidecarsBuilder) Configure(f func(conf *Sidecars)) *SidecarsBuilder {
+	f(&b.conf)
+	return b
+}
+
+// Sidecar add sidecars, they are started in the order they have been added
+func (b *SidecarsBuilder) Sidecar(sidecars ...*SidecarBuilder) *SidecarsBuilder {
+	b.sidecars = append(b.sidecars, sidecars...)
+	return b
+}
+
+// Build give config after validating it and all its sidecars
+func (b *SidecarsBuilder) Build() (*Sidecars, error) {
+	conf := b.conf
+	conf.Sidecars = make([]*Sidecar, 0, len(b.sidecars))
+	for _, sb := range b.sidecars {
+		sidecar, err := sb.Build()
+		if err != nil {
+			return nil, err
+		}
+		conf.Sidecars = append(conf.Sidecars, sidecar)
+	}
+	err := conf.Check()
+	if err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// SidecarBuilder build a sidecar in go code, see NewSidecar
+type SidecarBuilder struct {
+	sidecar Sidecar
+}
+
+func NewSidecar(name string) *SidecarBuilder {
+	return &SidecarBuilder{sidecar: Sidecar{Name: name}}
+}
+
+// Executable set executable to run with its args, it is relative to artifact dir when an artifact is set
+func (b *SidecarBuilder) Executable(path string, args ...string) *SidecarBuilder {
+	b.sidecar.Executable = path
+	b.sidecar.Args = args
+	return b
+}
+
+// Command set a command executed directly without shell
+func (b *SidecarBuilder) Command(args ...string) *SidecarBuilder {
+	b.sidecar.Command = &Command{Args: args}
+	return b
+}
+
+// ShellCommand set a command run through a shell
+func (b *SidecarBuilder) ShellCommand(command string) *SidecarBuilder {
+	b.sidecar.Command = &Command{Args: []string{command}, Shell: true}
+	return b
+}
+
+func (b *SidecarBuilder) Group(group string) *SidecarBuilder {
+	b.sidecar.Group = group
+	return b
+}
+
+// Artifact set uri of artifact to download at setup
+func (b *SidecarBuilder) Artifact(uri string) *SidecarBuilder {
+	b.sidecar.ArtifactURI = uri
+	return b
+}
+
+func (b *SidecarBuilder) ArtifactSha1(sha1 string) *SidecarBuilder {
+	b.sidecar.ArtifactSha1 = sha1
+	return b
+}
+
+func (b *SidecarBuilder) AfterInstall(script string) *SidecarBuilder {
+	b.sidecar.AfterInstall = script
+	return b
+}
+
+// Env set an env var of sidecar, value is templated with launcher env
+func (b *SidecarBuilder) Env(key, value string) *SidecarBuilder {
+	if b.sidecar.Env == nil {
+		b.sidecar.Env = make(map[string]string)
+	}
+	b.sidecar.Env[key] = value
+	return b
+}
+
+// AppEnv set an env var of app
+func (b *SidecarBuilder) AppEnv(key, value string) *SidecarBuilder {
+	if b.sidecar.AppEnv == nil {
+		b.sidecar.AppEnv = make(map[string]string)
+	}
+	b.sidecar.AppEnv[key] = value
+	return b
+}
+
+// WaitFor add a dependency which must be reachable before starting sidecar
+func (b *SidecarBuilder) WaitFor(waitFor WaitFor) *SidecarBuilder {
+	b.sidecar.WaitFor = append(b.sidecar.WaitFor, &waitFor)
+	return b
+}
+
+// Rproxy make sidecar a reverse proxy in front of app
+func (b *SidecarBuilder) Rproxy() *SidecarBuilder {
+	b.sidecar.IsRproxy = true
+	return b
+}
+
+// Configure let set any other field of sidecar
+func (b *SidecarBuilder) Configure(f func(sidecar *Sidecar)) *SidecarBuilder {
+	f(&b.sidecar)
+	return b
+}
+
+// Build give sidecar after validating it
+func (b *SidecarBuilder) Build() (*Sidecar, error) {
+	sidecar := b.sidecar
+	err := sidecar.Prepare()
+	if err != nil {
+		if sidecar.Name == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("Invalid sidecar %s: %s", sidecar.Name, err.Error())
+	}
+	return &sidecar, nil
+}
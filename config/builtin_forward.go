@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// SidecarTypeBuiltinForward is type of sidecar forwarding a local port to a remote address with forwarder embedded in cloud-sidecars
+const SidecarTypeBuiltinForward = "builtin-forward"
+
+// BuiltinForward configure tcp forwarder embedded in cloud-sidecars
+type BuiltinForward struct {
+	// Local port to listen on
+	Port int `yaml:"port" json:"port"`
+	// Host to listen on, by default 127.0.0.1
+	Host string `yaml:"host" json:"host"`
+	// Remote address in the form host:port where connections are forwarded
+	Remote string `yaml:"remote" json:"remote"`
+	// Connect to remote address with tls when set
+	TLS *ForwardTLS `yaml:"tls" json:"tls"`
+}
+
+type ForwardTLS struct {
+	// Server name to verify, by default host of remote address
+	ServerName string `yaml:"server_name" json:"server_name"`
+	// CA file to verify remote certificate instead of system CAs
+	CaFile string `yaml:"ca_file" json:"ca_file"`
+	// Do not verify remote certificate
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
+
+func (c BuiltinForward) Check() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("Builtin forward sidecar must have a valid port")
+	}
+	_, _, err := net.SplitHostPort(c.Remote)
+	if err != nil {
+		return fmt.Errorf("Builtin forward sidecar must have a remote address in the form host:port: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+)
+
+// SidecarTypeBuiltinRproxy is type of sidecar running reverse proxy embedded in cloud-sidecars
+const SidecarTypeBuiltinRproxy = "builtin-rproxy"
+
+// BuiltinRproxy configure reverse proxy embedded in cloud-sidecars
+type BuiltinRproxy struct {
+	// Headers set on requests forwarded to app
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Headers set on responses sent to clients
+	ResponseHeaders map[string]string `yaml:"response_headers" json:"response_headers"`
+	// Basic auth required to access app
+	BasicAuth *BasicAuth `yaml:"basic_auth" json:"basic_auth"`
+	// Certificate to serve https instead of http
+	TLS *RproxyTLS `yaml:"tls" json:"tls"`
+}
+
+type BasicAuth struct {
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	// Realm given to clients, by default cloud-sidecars
+	Realm string `yaml:"realm" json:"realm"`
+}
+
+type RproxyTLS struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+}
+
+func (c BuiltinRproxy) Check() error {
+	if c.BasicAuth != nil && (c.BasicAuth.Username == "" || c.BasicAuth.Password == "") {
+		return fmt.Errorf("Basic auth of builtin reverse proxy must have a username and a password")
+	}
+	if c.TLS != nil && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return fmt.Errorf("TLS of builtin reverse proxy must have a cert file and a key file")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+)
+
+// SidecarTypeBuiltinStatic is type of sidecar serving a directory with file server embedded in cloud-sidecars
+const SidecarTypeBuiltinStatic = "builtin-static"
+
+// BuiltinStatic configure file server embedded in cloud-sidecars
+type BuiltinStatic struct {
+	// Directory to serve, relative to sidecar work dir if not absolute
+	Dir string `yaml:"dir" json:"dir"`
+	// Port to listen on
+	Port int `yaml:"port" json:"port"`
+	// Host to listen on, by default all interfaces
+	Host string `yaml:"host" json:"host"`
+	// Do not list files of directories without index.html
+	NoListing bool `yaml:"no_listing" json:"no_listing"`
+	// File in directory served for paths not found (e.g.: index.html for single page apps or a maintenance page)
+	Fallback string `yaml:"fallback" json:"fallback"`
+}
+
+func (c BuiltinStatic) Check() error {
+	if c.Dir == "" {
+		return fmt.Errorf("Builtin static sidecar must have a directory to serve")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("Builtin static sidecar must have a valid port")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CapabilityNames are linux capabilities which can be dropped or kept, index of a name is number of capability
+var CapabilityNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid", "setuid",
+	"setpcap", "linux_immutable", "net_bind_service", "net_broadcast", "net_admin", "net_raw",
+	"ipc_lock", "ipc_owner", "sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time", "sys_tty_config", "mknod",
+	"lease", "audit_write", "audit_control", "setfcap", "mac_override", "mac_admin", "syslog",
+	"wake_alarm", "block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+// Capabilities are linux capabilities removed from a sidecar process before it is executed
+type Capabilities struct {
+	// Capabilities to drop (e.g.: net_raw or cap_net_raw), all drops every capabilities
+	Drop []string `yaml:"drop" json:"drop"`
+	// Capabilities to keep, every other capabilities are dropped
+	Keep []string `yaml:"keep" json:"keep"`
+	// Prevent sidecar and its children to gain privileges through setuid or file capabilities executables
+	NoNewPrivs bool `yaml:"no_new_privs" json:"no_new_privs"`
+}
+
+func (c Capabilities) Check() error {
+	if len(c.Drop) > 0 && len(c.Keep) > 0 {
+		return fmt.Errorf("Only one of drop or keep capabilities can be set")
+	}
+	if len(c.Drop) == 0 && len(c.Keep) == 0 {
+		return fmt.Errorf("Drop or keep capabilities must be set")
+	}
+	for _, name := range append(append([]string{}, c.Drop...), c.Keep...) {
+		if strings.ToLower(name) == "all" && len(c.Drop) > 0 {
+			continue
+		}
+		if capabilityNumber(name) < 0 {
+			return fmt.Errorf("Capability '%s' is not a known linux capability", name)
+		}
+	}
+	return nil
+}
+
+// Dropped give numbers of capabilities to drop
+func (c Capabilities) Dropped() []int {
+	dropped := make([]int, 0)
+	for number := range CapabilityNames {
+		if c.drops(number) {
+			dropped = append(dropped, number)
+		}
+	}
+	return dropped
+}
+
+func (c Capabilities) drops(number int) bool {
+	if len(c.Keep) > 0 {
+		for _, name := range c.Keep {
+			if capabilityNumber(name) == number {
+				return false
+			}
+		}
+		return true
+	}
+	for _, name := range c.Drop {
+		if strings.ToLower(name) == "all" || capabilityNumber(name) == number {
+			return true
+		}
+	}
+	return false
+}
+
+// capabilityNumber give number of capability name given with or without cap_ prefix, -1 when it is unknown
+func capabilityNumber(name string) int {
+	name = strings.TrimPrefix(strings.ToLower(name), "cap_")
+	for number, capName := range CapabilityNames {
+		if capName == name {
+			return number
+		}
+	}
+	return -1
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Command to run a sidecar, it can be defined as a string which will be run through a shell
+// or as a list of arguments which will be executed directly without shell
+type Command struct {
+	Args  []string
+	Shell bool
+}
+
+func (c *Command) fromInterface(data interface{}) error {
+	switch v := data.(type) {
+	case string:
+		c.Args = []string{v}
+		c.Shell = true
+	case []interface{}:
+		c.Args = make([]string, len(v))
+		for i, arg := range v {
+			c.Args[i] = fmt.Sprint(arg)
+		}
+		c.Shell = false
+	case []string:
+		c.Args = v
+		c.Shell = false
+	default:
+		return fmt.Errorf("Command must be a string or a list of arguments")
+	}
+	return nil
+}
+
+func (c Command) toInterface() interface{} {
+	if c.Shell && len(c.Args) > 0 {
+		return c.Args[0]
+	}
+	return c.Args
+}
+
+func (c Command) Check() error {
+	if len(c.Args) == 0 || c.Args[0] == "" {
+		return fmt.Errorf("Command must not be empty")
+	}
+	return nil
+}
+
+func (c *Command) UnmarshalCloud(data interface{}) error {
+	return c.fromInterface(data)
+}
+
+func (c *Command) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	return c.fromInterface(v)
+}
+
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	err := unmarshal(&v)
+	if err != nil {
+		return err
+	}
+	return c.fromInterface(v)
+}
+
+func (c Command) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toInterface())
+}
+
+func (c Command) MarshalYAML() (interface{}, error) {
+	return c.toInterface(), nil
+}
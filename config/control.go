@@ -0,0 +1,11 @@
+package config
+
+type ControlTLS struct {
+	// CertFile and KeyFile are certificate and private key served by control api
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	// CaFile is used by cli to verify certificate of control api, system CAs are used when empty
+	CaFile string `yaml:"ca_file" json:"ca_file"`
+	// InsecureSkipVerify make cli not verify certificate of control api
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
@@ -0,0 +1,10 @@
+package config
+
+type CoreDump struct {
+	// Dir is where core dumps of crashed sidecars are collected, relative to app dir if not absolute
+	Dir string `yaml:"dir" json:"dir"`
+	// MaxSize is max size in MB of a core dump, 0 means no limit
+	MaxSize int `yaml:"max_size" json:"max_size"`
+	// MaxDumps is number of core dumps kept in dir, oldest are removed
+	MaxDumps int `yaml:"max_dumps" json:"max_dumps"`
+}
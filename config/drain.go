@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Drain is an action run on a reverse proxy sidecar before it is asked to stop
+// to let in-flight requests complete
+type Drain struct {
+	// Command run through bash with sidecar env
+	Command string `yaml:"command" json:"command" expand:"-"`
+	// Http url called on sidecar
+	Http string `yaml:"http" json:"http"`
+	// Method used to call http url, by default POST
+	Method string `yaml:"method" json:"method"`
+	// Delay in seconds to wait after command or http call before sending stop signal
+	Delay int `yaml:"delay" json:"delay"`
+	// Timeout in seconds of command or http call, by default 10 seconds
+	Timeout int `yaml:"timeout" json:"timeout"`
+}
+
+func (d Drain) Check() error {
+	if d.Command != "" && d.Http != "" {
+		return fmt.Errorf("Drain cannot have both command and http")
+	}
+	if d.Delay < 0 || d.Timeout < 0 {
+		return fmt.Errorf("Drain delay and timeout must be positive numbers")
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+package config
+
+type Events struct {
+	// Fd is a file descriptor opened by parent process where events are written, e.g.: 3
+	Fd int `yaml:"fd" json:"fd"`
+	// Address where events are sent, a unix socket (e.g.: unix:///var/run/agent.sock) or a tcp address (e.g.: tcp://127.0.0.1:9000)
+	Address string `yaml:"address" json:"address"`
+}
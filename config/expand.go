@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+)
+
+var envVarRegex = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}`)
+
+// ExpandEnvString replace ${VAR}, ${VAR:-default} (default used when var is unset or empty)
+// and ${VAR-default} (default used when var is unset) by value found with lookup.
+// Unset vars without default are left as is to let them be expanded later by a shell,
+// $${VAR} can be used to keep ${VAR} as is.
+func ExpandEnvString(s string, lookup func(string) (string, bool)) string {
+	return envVarRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if match[1] == '$' {
+			return match[1:]
+		}
+		sub := envVarRegex.FindStringSubmatch(match)
+		name, op, def := sub[1], sub[2], sub[3]
+		value, ok := lookup(name)
+		switch {
+		case op == ":-" && (!ok || value == ""):
+			return def
+		case op == "-" && !ok:
+			return def
+		case !ok:
+			return match
+		}
+		return value
+	})
+}
+
+// ExpandEnv expand env vars in all string fields of config, see ExpandEnvString
+// fields tagged with `expand:"-"` are left untouched: scripts are expanded by shell when run,
+// and command, args and env of sidecars are templated at launch with env computed by launcher (e.g.: PROXY_APP_PORT)
+func (c *Sidecars) ExpandEnv() {
+	expandValue(reflect.ValueOf(c).Elem(), os.LookupEnv)
+}
+
+func expandValue(v reflect.Value, lookup func(string) (string, bool)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			expandValue(v.Elem(), lookup)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).Tag.Get("expand") == "-" || !v.Field(i).CanSet() {
+				continue
+			}
+			expandValue(v.Field(i), lookup)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			expandValue(v.Index(i), lookup)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			expanded := ExpandEnvString(v.MapIndex(key).String(), lookup)
+			v.SetMapIndex(key, reflect.ValueOf(expanded).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(ExpandEnvString(v.String(), lookup))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mapLookup(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestExpandEnvString(t *testing.T) {
+	lookup := mapLookup(map[string]string{"HOST": "localhost", "EMPTY": ""})
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"http://${HOST}:8080", "http://localhost:8080"},
+		{"${UNSET}", "${UNSET}"},
+		{"${UNSET:-default}", "default"},
+		{"${EMPTY:-default}", "default"},
+		{"${UNSET-default}", "default"},
+		{"${EMPTY-default}", ""},
+		{"$${HOST}", "${HOST}"},
+		{"$HOST", "$HOST"},
+	}
+	for _, test := range tests {
+		expanded := ExpandEnvString(test.value, lookup)
+		if expanded != test.expected {
+			t.Errorf("Expected %q to be expanded to %q, got %q", test.value, test.expected, expanded)
+		}
+	}
+}
+
+func TestExpandEnvSkipsProcessFields(t *testing.T) {
+	lookup := mapLookup(map[string]string{"PORT": "8080", "PROXY_APP_PORT": "9090", "ARTIFACT_HOST": "example.com"})
+	conf := &Sidecars{
+		AppCommand: "./app --port ${PORT}",
+		Sidecars: []*Sidecar{{
+			Name:          "rproxy",
+			ArtifactURI:   "https://${ARTIFACT_HOST}/rproxy.zip",
+			Command:       &Command{Args: []string{"rproxy", "${PORT}"}},
+			Args:          []string{"--upstream", "${PROXY_APP_PORT}"},
+			Env:           map[string]string{"UPSTREAM": "http://localhost:${PROXY_APP_PORT}"},
+			AppEnv:        map[string]string{"RPROXY_PORT": "${PORT}"},
+			AfterInstall:  "echo ${PORT}",
+			VerifyCommand: "./rproxy --port ${PORT}",
+		}},
+	}
+
+	expandValue(reflect.ValueOf(conf).Elem(), lookup)
+
+	sidecar := conf.Sidecars[0]
+	if sidecar.ArtifactURI != "https://example.com/rproxy.zip" {
+		t.Fatalf("Expected artifact uri to be expanded, got %s", sidecar.ArtifactURI)
+	}
+	if conf.AppCommand != "./app --port ${PORT}" {
+		t.Fatalf("Expected app command to be left untouched, got %s", conf.AppCommand)
+	}
+	if !reflect.DeepEqual(sidecar.Command.Args, []string{"rproxy", "${PORT}"}) {
+		t.Fatalf("Expected command to be left untouched, got %v", sidecar.Command.Args)
+	}
+	if !reflect.DeepEqual(sidecar.Args, []string{"--upstream", "${PROXY_APP_PORT}"}) {
+		t.Fatalf("Expected args to be left untouched, got %v", sidecar.Args)
+	}
+	if sidecar.Env["UPSTREAM"] != "http://localhost:${PROXY_APP_PORT}" {
+		t.Fatalf("Expected env to be left untouched, got %v", sidecar.Env)
+	}
+	if sidecar.AppEnv["RPROXY_PORT"] != "${PORT}" {
+		t.Fatalf("Expected app env to be left untouched, got %v", sidecar.AppEnv)
+	}
+	if sidecar.AfterInstall != "echo ${PORT}" || sidecar.VerifyCommand != "./rproxy --port ${PORT}" {
+		t.Fatalf("Expected scripts to be left untouched, got %q and %q", sidecar.AfterInstall, sidecar.VerifyCommand)
+	}
+}
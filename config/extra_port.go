@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var extraPortNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ExtraPort is an additional port of app (e.g.: a grpc listener) besides port given by platform
+type ExtraPort struct {
+	// Name of port, used as suffix of env vars giving this port (e.g.: grpc gives PORT_GRPC)
+	Name string `yaml:"name" json:"name"`
+	// Port exposed by platform for this listener
+	Port int `yaml:"port" json:"port"`
+	// Port app listens on when a reverse proxy sidecar fronts it, a free port is picked at launch by default
+	AppPort int `yaml:"app_port" json:"app_port"`
+}
+
+func (p ExtraPort) Check() error {
+	if !extraPortNameRegex.MatchString(p.Name) {
+		return fmt.Errorf("Extra port name '%s' must only contain letters, digits and underscores", p.Name)
+	}
+	if p.Port <= 0 || p.AppPort < 0 {
+		return fmt.Errorf("Extra port %s must be a positive number", p.Name)
+	}
+	return nil
+}
+
+// EnvSuffix give suffix of env vars giving this port
+func (p ExtraPort) EnvSuffix() string {
+	return "_" + strings.ToUpper(p.Name)
+}
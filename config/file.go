@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const sidecarsKey = "sidecars"
+
+// AppendSidecarToFile add a sidecar definition at the end of sidecars list in config file.
+// Existing content (comments, ordering, other keys) is kept as is.
+// Config file is created if it doesn't exist.
+func AppendSidecarToFile(path string, sidecar *Sidecar) error {
+	err := sidecar.Check()
+	if err != nil {
+		return err
+	}
+	if IsSopsFile(path) {
+		return fmt.Errorf("Config file %s is encrypted with sops, use sops to edit it", path)
+	}
+	doc, err := loadYamlDocument(path)
+	if err != nil {
+		return err
+	}
+	sidecarsNode, err := sidecarsSequence(doc)
+	if err != nil {
+		return err
+	}
+	for _, n := range sidecarsNode.Content {
+		if sidecarNodeName(n) == sidecar.Name {
+			return fmt.Errorf("Sidecar '%s' already exists in %s", sidecar.Name, path)
+		}
+	}
+
+	sidecarNode := &yaml.Node{}
+	err = sidecarNode.Encode(sidecar)
+	if err != nil {
+		return err
+	}
+	pruneEmptyFields(sidecarNode)
+	sidecarsNode.Content = append(sidecarsNode.Content, sidecarNode)
+
+	return writeYamlDocument(path, doc)
+}
+
+func loadYamlDocument(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	err = yaml.Unmarshal(b, doc)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read %s: %s", path, err.Error())
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("Config file %s must be a yaml map", path)
+	}
+	return doc, nil
+}
+
+func writeYamlDocument(path string, doc *yaml.Node) error {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	err := enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	err = enc.Close()
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		perm = stat.Mode().Perm()
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), perm)
+}
+
+func sidecarsSequence(doc *yaml.Node) (*yaml.Node, error) {
+	root := doc.Content[0]
+	seq := mappingValue(root, sidecarsKey)
+	if seq == nil {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sidecarsKey},
+			seq,
+		)
+	}
+	if seq.Kind == yaml.ScalarNode && seq.Tag == "!!null" {
+		seq.Kind = yaml.SequenceNode
+		seq.Tag = "!!seq"
+		seq.Value = ""
+	}
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("Key '%s' must be a list of sidecars", sidecarsKey)
+	}
+	return seq, nil
+}
+
+func sidecarNodeName(n *yaml.Node) string {
+	nameNode := mappingValue(n, "name")
+	if nameNode == nil {
+		return ""
+	}
+	return nameNode.Value
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// pruneEmptyFields remove keys with zero value from a mapping node
+// to only write in file what user has really set
+func pruneEmptyFields(n *yaml.Node) {
+	content := make([]*yaml.Node, 0, len(n.Content))
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if isEmptyNode(n.Content[i+1]) {
+			continue
+		}
+		content = append(content, n.Content[i], n.Content[i+1])
+	}
+	n.Content = content
+}
+
+func isEmptyNode(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(n.Content) == 0
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!null":
+			return true
+		case "!!bool":
+			return n.Value == "false"
+		case "!!int", "!!float":
+			return n.Value == "0"
+		}
+		return n.Value == ""
+	}
+	return false
+}
+
+// PinnedSha1 is sha1 of a sidecar artifact to pin in config file
+type PinnedSha1 struct {
+	Name string
+	Sha1 string
+	// Sha1 previously set in config file
+	Previous string
+	// Reason why sha1 has not been pinned, empty when it has been pinned
+	Skipped string
+}
+
+// PinSidecarsSha1InFile set artifact_sha1 of sidecars in config file to their pinned sha1.
+// Sidecars with artifact uri or sha1 templated with platform are skipped as their sha1 depends on platform.
+// Existing content (comments, ordering, other keys) is kept as is.
+func PinSidecarsSha1InFile(path string, pins []*PinnedSha1) error {
+	if IsSopsFile(path) {
+		return fmt.Errorf("Config file %s is encrypted with sops, use sops to edit it", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	doc, err := loadYamlDocument(path)
+	if err != nil {
+		return err
+	}
+	sidecarsNode, err := sidecarsSequence(doc)
+	if err != nil {
+		return err
+	}
+	for _, pin := range pins {
+		var sidecarNode *yaml.Node
+		for _, n := range sidecarsNode.Content {
+			if sidecarNodeName(n) == pin.Name {
+				sidecarNode = n
+			}
+		}
+		if sidecarNode == nil {
+			pin.Skipped = "not declared in config file"
+			continue
+		}
+		uriNode := mappingValue(sidecarNode, "artifact_uri")
+		sha1Node := mappingValue(sidecarNode, "artifact_sha1")
+		if (uriNode != nil && strings.Contains(uriNode.Value, "{{")) || (sha1Node != nil && strings.Contains(sha1Node.Value, "{{")) {
+			pin.Skipped = "artifact depends on platform"
+			continue
+		}
+		if sha1Node != nil {
+			pin.Previous = sha1Node.Value
+			sha1Node.Value = pin.Sha1
+			sha1Node.Tag = "!!str"
+			continue
+		}
+		setMappingValueAfter(sidecarNode, "artifact_sha1", pin.Sha1, "artifact_uri")
+	}
+	return writeYamlDocument(path, doc)
+}
+
+// setMappingValueAfter add key with a string value to a mapping node just after key previous, or at the end if not found
+func setMappingValueAfter(n *yaml.Node, key, value, previous string) {
+	nodes := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == previous {
+			content := append([]*yaml.Node{}, n.Content[:i+2]...)
+			content = append(content, nodes...)
+			n.Content = append(content, n.Content[i+2:]...)
+			return
+		}
+	}
+	n.Content = append(n.Content, nodes...)
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Isolation are linux namespaces where a sidecar process runs apart from launcher and app
+type Isolation struct {
+	// Run sidecar in a new mount namespace with its own empty /tmp
+	PrivateTmp bool `yaml:"private_tmp" json:"private_tmp"`
+	// Run sidecar in a new network namespace where only loopback is available
+	NoNetwork bool `yaml:"no_network" json:"no_network"`
+}
+
+// IsEnabled check if sidecar runs in at least one new namespace
+func (i Isolation) IsEnabled() bool {
+	return i.PrivateTmp || i.NoNetwork
+}
+
+// checkIsolation prevent cutting network of sidecars which must be reached by launcher or app
+func (c Sidecar) checkIsolation() error {
+	if c.Isolation == nil || !c.Isolation.NoNetwork {
+		return nil
+	}
+	if c.IsRproxy || c.Type == SidecarTypeBuiltinForward || c.Type == SidecarTypeBuiltinStatic {
+		return fmt.Errorf("Sidecar %s can't be isolated from network as it must be reached by app or platform", c.Name)
+	}
+	return nil
+}
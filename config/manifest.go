@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+)
+
+// SidecarManifestFile is name of manifest which can be shipped at root of an artifact
+const SidecarManifestFile = "sidecar.yml"
+
+// SidecarManifest describe how to run a sidecar from its artifact,
+// values set in local config always take precedence over the ones from manifest
+type SidecarManifest struct {
+	Executable string            `yaml:"executable" json:"executable"`
+	Command    *Command          `yaml:"command" json:"command"`
+	Args       []string          `yaml:"args" json:"args"`
+	Env        map[string]string `yaml:"env" json:"env"`
+	AppEnv     map[string]string `yaml:"app_env" json:"app_env"`
+	WaitFor    []*WaitFor        `yaml:"wait_for" json:"wait_for"`
+}
+
+// LoadSidecarManifest read manifest file, nil is given when file does not exist
+func LoadSidecarManifest(path string) (*SidecarManifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var manifest SidecarManifest
+	err = yaml.Unmarshal(b, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read manifest %s: %s", path, err.Error())
+	}
+	return &manifest, nil
+}
+
+// MergeManifest fill sidecar with values from manifest which are not set in local config,
+// env and app env from manifest are defaults which can be overridden by local config
+func (c *Sidecar) MergeManifest(manifest SidecarManifest) error {
+	if c.Executable == "" && c.Command == nil {
+		c.Executable = manifest.Executable
+		c.Command = manifest.Command
+		if len(c.Args) == 0 {
+			c.Args = manifest.Args
+		}
+	}
+	c.Env = mergeDefaults(manifest.Env, c.Env)
+	c.AppEnv = mergeDefaults(manifest.AppEnv, c.AppEnv)
+	if len(c.WaitFor) == 0 {
+		c.WaitFor = manifest.WaitFor
+	}
+	if c.Executable == "" && c.Command == nil {
+		return fmt.Errorf("Manifest %s must provide an executable path or a command when not set in config", SidecarManifestFile)
+	}
+	return c.Prepare()
+}
+
+func mergeDefaults(defaults, values map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return values
+	}
+	merged := make(map[string]string)
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
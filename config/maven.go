@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MavenScheme = "mvn://"
+	// DefaultMavenRepository is maven central, used when maven_repository is not set
+	DefaultMavenRepository = "https://repo1.maven.org/maven2"
+
+	defaultMavenExtension = "jar"
+)
+
+// MavenCoordinates identify an artifact in a maven repository,
+// they are given as mvn://group:artifact:version[:classifier][@extension] in artifact uri
+type MavenCoordinates struct {
+	GroupId    string
+	ArtifactId string
+	Version    string
+	Classifier string
+	// Extension of artifact file, jar by default
+	Extension string
+}
+
+// IsMavenUri check if uri is given as maven coordinates
+func IsMavenUri(uri string) bool {
+	return strings.HasPrefix(uri, MavenScheme)
+}
+
+func ParseMavenUri(uri string) (MavenCoordinates, error) {
+	coords := strings.TrimPrefix(uri, MavenScheme)
+	extension := defaultMavenExtension
+	if i := strings.LastIndex(coords, "@"); i >= 0 {
+		extension = coords[i+1:]
+		coords = coords[:i]
+	}
+	parts := strings.Split(coords, ":")
+	invalid := extension == "" || len(parts) < 3 || len(parts) > 4
+	for _, part := range parts {
+		invalid = invalid || part == ""
+	}
+	if invalid {
+		return MavenCoordinates{}, fmt.Errorf("Invalid maven uri '%s', it must be in the form mvn://group:artifact:version[:classifier][@extension]", uri)
+	}
+	c := MavenCoordinates{
+		GroupId:    parts[0],
+		ArtifactId: parts[1],
+		Version:    parts[2],
+		Extension:  extension,
+	}
+	if len(parts) == 4 {
+		c.Classifier = parts[3]
+	}
+	return c, nil
+}
+
+// FileName give name of artifact file in repository
+func (c MavenCoordinates) FileName() string {
+	name := c.ArtifactId + "-" + c.Version
+	if c.Classifier != "" {
+		name += "-" + c.Classifier
+	}
+	return name + "." + c.Extension
+}
+
+// Url give url of artifact in repository with the default maven layout
+func (c MavenCoordinates) Url(repository string) string {
+	if repository == "" {
+		repository = DefaultMavenRepository
+	}
+	return strings.Join([]string{
+		strings.TrimSuffix(repository, "/"),
+		strings.Replace(c.GroupId, ".", "/", -1),
+		c.ArtifactId,
+		c.Version,
+		c.FileName(),
+	}, "/")
+}
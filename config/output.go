@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	OutputTypePort   = "port"
+	OutputTypeSocket = "socket"
+	OutputTypeFile   = "file"
+)
+
+var outputNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Output is a value provided by a sidecar (e.g.: port or socket path it listens on)
+// which is given to app and other sidecars in an env var
+type Output struct {
+	// Name of output, env var is SIDECAR_<SIDECAR NAME>_<NAME> in upper case
+	Name string `yaml:"name" json:"name"`
+	// Type of output: port, socket or file
+	Type string `yaml:"type" json:"type"`
+	// Value templated with launcher env, a free port is chosen at launch for a port without value
+	// and relative paths are relative to app dir
+	Value string `yaml:"value" json:"value"`
+	// Env var name to use instead of SIDECAR_<SIDECAR NAME>_<NAME>
+	Env string `yaml:"env" json:"env"`
+}
+
+func (o Output) Check() error {
+	if !outputNameRegex.MatchString(o.Name) {
+		return fmt.Errorf("Output must have a name made of letters, digits or _")
+	}
+	switch o.Type {
+	case OutputTypePort:
+	case OutputTypeSocket, OutputTypeFile:
+		if o.Value == "" {
+			return fmt.Errorf("Output %s of type %s must have a value", o.Name, o.Type)
+		}
+	default:
+		return fmt.Errorf("Output %s must have type %s, %s or %s", o.Name, OutputTypePort, OutputTypeSocket, OutputTypeFile)
+	}
+	return nil
+}
+
+// EnvKey give env var name of output for sidecar
+func (o Output) EnvKey(sidecarName string) string {
+	if o.Env != "" {
+		return o.Env
+	}
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, sidecarName)
+	return strings.ToUpper(fmt.Sprintf("SIDECAR_%s_%s", name, o.Name))
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OverridesEnvKey is env var containing a json object merged in config
+	OverridesEnvKey = "SIDECARS_OVERRIDES"
+	// OverrideEnvPrefix is prefix of env vars overriding a field of a sidecar
+	// e.g.: SIDECARS_OVERRIDE_<name>_ENV_FOO=bar or SIDECARS_OVERRIDE_<name>_ARTIFACT_URI=https://...
+	OverrideEnvPrefix = "SIDECARS_OVERRIDE_"
+)
+
+var nonAlnumRegex = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// ApplyOverrides merge overrides found in environ (in the form of os.Environ()) into config
+func (c *Sidecars) ApplyOverrides(environ []string) error {
+	current, err := toJsonMap(c)
+	if err != nil {
+		return err
+	}
+	overrides := make(map[string]interface{})
+	hasOverride := false
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := parts[0], parts[1]
+		if key == OverridesEnvKey {
+			jsonOverrides := make(map[string]interface{})
+			err := json.Unmarshal([]byte(value), &jsonOverrides)
+			if err != nil {
+				return fmt.Errorf("Invalid json in %s: %s", OverridesEnvKey, err.Error())
+			}
+			overrides = mergeJsonMap(overrides, jsonOverrides)
+			hasOverride = true
+			continue
+		}
+		if !strings.HasPrefix(key, OverrideEnvPrefix) {
+			continue
+		}
+		sidecarOverride := c.sidecarOverrideFromEnv(current, strings.TrimPrefix(key, OverrideEnvPrefix), value)
+		if sidecarOverride == nil {
+			log.WithField("component", "config").Warnf("Override %s is ignored: no sidecar found matching it", key)
+			continue
+		}
+		overrides = mergeJsonMap(overrides, map[string]interface{}{
+			"sidecars": []interface{}{sidecarOverride},
+		})
+		hasOverride = true
+	}
+	if !hasOverride {
+		return nil
+	}
+	b, err := json.Marshal(mergeJsonMap(current, overrides))
+	if err != nil {
+		return err
+	}
+	overridden := Sidecars{}
+	err = json.Unmarshal(b, &overridden)
+	if err != nil {
+		return fmt.Errorf("Invalid config after overrides: %s", err.Error())
+	}
+	*c = overridden
+	return nil
+}
+
+// sidecarOverrideFromEnv give partial sidecar to merge from an env var key without prefix,
+// sidecar name is matched case insensitively, nil is given when no sidecar matches key
+func (c Sidecars) sidecarOverrideFromEnv(current map[string]interface{}, key, value string) map[string]interface{} {
+	var sidecar *Sidecar
+	field := ""
+	upperKey := strings.ToUpper(key)
+	for _, s := range c.Sidecars {
+		namePrefix := overrideEnvName(s.Name) + "_"
+		// longest name wins when a sidecar name is prefix of another one
+		if strings.HasPrefix(upperKey, namePrefix) && (sidecar == nil || len(s.Name) > len(sidecar.Name)) {
+			sidecar = s
+			field = key[len(namePrefix):]
+		}
+	}
+	if sidecar == nil {
+		return nil
+	}
+	override := map[string]interface{}{
+		"name": sidecar.Name,
+	}
+	upperField := strings.ToUpper(field)
+	switch {
+	case strings.HasPrefix(upperField, "APP_ENV_"):
+		override["app_env"] = map[string]interface{}{field[len("APP_ENV_"):]: value}
+	case strings.HasPrefix(upperField, "ENV_"):
+		override["env"] = map[string]interface{}{field[len("ENV_"):]: value}
+	default:
+		jsonField := sidecarJsonField(strings.ToLower(field))
+		currentValue := currentSidecarField(current, sidecar.Name, jsonField)
+		if _, isString := currentValue.(string); isString {
+			override[jsonField] = value
+			break
+		}
+		var v interface{}
+		err := json.Unmarshal([]byte(value), &v)
+		if err != nil {
+			// not a json value, this is taken as a string
+			v = value
+		}
+		override[jsonField] = v
+	}
+	return override
+}
+
+// sidecarJsonField give json name of a sidecar field given by its yaml name, overrides are merged as json
+// and some fields have another json name (e.g.: after_install is after_download in json)
+func sidecarJsonField(yamlField string) string {
+	t := reflect.TypeOf(Sidecar{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		if strings.Split(tag.Get("yaml"), ",")[0] == yamlField {
+			return strings.Split(tag.Get("json"), ",")[0]
+		}
+	}
+	return yamlField
+}
+
+func currentSidecarField(current map[string]interface{}, name, field string) interface{} {
+	sidecars, _ := current["sidecars"].([]interface{})
+	for _, s := range sidecars {
+		sidecar, _ := s.(map[string]interface{})
+		if sidecar["name"] == name {
+			return sidecar[field]
+		}
+	}
+	return nil
+}
+
+func overrideEnvName(name string) string {
+	return strings.Trim(nonAlnumRegex.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
+
+// mergeJsonMap deep merge src into dst, sidecars are merged by name
+func mergeJsonMap(dst, src map[string]interface{}) map[string]interface{} {
+	for k, srcValue := range src {
+		dstValue, ok := dst[k]
+		if !ok {
+			dst[k] = srcValue
+			continue
+		}
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = mergeJsonMap(dstMap, srcMap)
+			continue
+		}
+		srcList, srcIsList := srcValue.([]interface{})
+		dstList, dstIsList := dstValue.([]interface{})
+		if k == "sidecars" && srcIsList && dstIsList {
+			dst[k] = mergeSidecarsList(dstList, srcList)
+			continue
+		}
+		dst[k] = srcValue
+	}
+	return dst
+}
+
+func mergeSidecarsList(dst, src []interface{}) []interface{} {
+	for _, srcSidecar := range src {
+		srcMap, ok := srcSidecar.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		merged := false
+		for i, dstSidecar := range dst {
+			dstMap, ok := dstSidecar.(map[string]interface{})
+			if ok && dstMap["name"] == srcMap["name"] {
+				dst[i] = mergeJsonMap(dstMap, srcMap)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			dst = append(dst, srcMap)
+		}
+	}
+	return dst
+}
+
+func toJsonMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]interface{})
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func overridesTestConfig() *Sidecars {
+	return &Sidecars{
+		LogLevel: "info",
+		Statsd:   &Statsd{Address: "localhost:8125", Prefix: "app"},
+		Sidecars: []*Sidecar{
+			{
+				Name:       "my-sidecar",
+				Executable: "./sidecar",
+				Args:       []string{"--port", "8080"},
+				Env:        map[string]string{"FOO": "foo", "KEEP": "keep"},
+			},
+			{
+				Name:       "my-sidecar-agent",
+				Executable: "./agent",
+			},
+		},
+	}
+}
+
+func TestApplyOverridesTakePrecedenceOverConfig(t *testing.T) {
+	conf := overridesTestConfig()
+
+	err := conf.ApplyOverrides([]string{
+		"SIDECARS_OVERRIDE_MY_SIDECAR_ENV_FOO=bar",
+		"SIDECARS_OVERRIDE_my_sidecar_APP_ENV_URL=http://localhost",
+		"SIDECARS_OVERRIDE_MY_SIDECAR_EXECUTABLE=./other",
+		"SIDECARS_OVERRIDES={\"log_level\": \"debug\"}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sidecar := conf.SidecarByName("my-sidecar")
+	if sidecar.Executable != "./other" {
+		t.Fatalf("Expected executable to be overridden, got %s", sidecar.Executable)
+	}
+	if !reflect.DeepEqual(sidecar.Env, map[string]string{"FOO": "bar", "KEEP": "keep"}) {
+		t.Fatalf("Expected env to be merged with overrides, got %v", sidecar.Env)
+	}
+	if !reflect.DeepEqual(sidecar.AppEnv, map[string]string{"URL": "http://localhost"}) {
+		t.Fatalf("Expected app env to be overridden, got %v", sidecar.AppEnv)
+	}
+	if conf.LogLevel != "debug" {
+		t.Fatalf("Expected log level to be overridden, got %s", conf.LogLevel)
+	}
+	if agent := conf.SidecarByName("my-sidecar-agent"); agent.Executable != "./agent" || len(agent.Env) > 0 {
+		t.Fatalf("Expected other sidecar to be left untouched, got %+v", agent)
+	}
+}
+
+func TestApplyOverridesLaterEnvVarsWin(t *testing.T) {
+	conf := overridesTestConfig()
+
+	err := conf.ApplyOverrides([]string{
+		"SIDECARS_OVERRIDES={\"sidecars\": [{\"name\": \"my-sidecar\", \"env\": {\"FOO\": \"json\"}}]}",
+		"SIDECARS_OVERRIDE_MY_SIDECAR_ENV_FOO=env",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value := conf.SidecarByName("my-sidecar").Env["FOO"]; value != "env" {
+		t.Fatalf("Expected last override to win, got %s", value)
+	}
+}
+
+func TestApplyOverridesNestedFields(t *testing.T) {
+	conf := overridesTestConfig()
+
+	err := conf.ApplyOverrides([]string{
+		"SIDECARS_OVERRIDES={\"statsd\": {\"address\": \"statsd:8125\"}}",
+		"SIDECARS_OVERRIDE_MY_SIDECAR_AGENT_INSTANCES=2",
+		"SIDECARS_OVERRIDE_MY_SIDECAR_AGENT_AFTER_INSTALL=chmod +x agent",
+		"SIDECARS_OVERRIDE_MY_SIDECAR_ARGS=[\"--port\", \"9090\"]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.Statsd.Address != "statsd:8125" || conf.Statsd.Prefix != "app" {
+		t.Fatalf("Expected statsd to be deep merged, got %+v", conf.Statsd)
+	}
+	agent := conf.SidecarByName("my-sidecar-agent")
+	// longest sidecar name matching env var wins
+	if agent.Instances != 2 {
+		t.Fatalf("Expected instances of agent to be overridden as a number, got %d", agent.Instances)
+	}
+	if agent.AfterInstall != "chmod +x agent" {
+		t.Fatalf("Expected after install to be overridden by its yaml name, got %q", agent.AfterInstall)
+	}
+	if conf.SidecarByName("my-sidecar").Instances != 0 {
+		t.Fatal("Expected instances of my-sidecar to be left untouched")
+	}
+	if args := conf.SidecarByName("my-sidecar").Args; !reflect.DeepEqual(args, []string{"--port", "9090"}) {
+		t.Fatalf("Expected args to be overridden from json list, got %v", args)
+	}
+}
+
+func TestApplyOverridesIgnoresUnknownSidecar(t *testing.T) {
+	conf := overridesTestConfig()
+	expected := overridesTestConfig()
+
+	err := conf.ApplyOverrides([]string{
+		"SIDECARS_OVERRIDE_UNKNOWN_ENV_FOO=bar",
+		"OTHER=value",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(conf, expected) {
+		t.Fatalf("Expected config to be left untouched, got %+v", conf)
+	}
+}
+
+func TestApplyOverridesRejectsInvalidJson(t *testing.T) {
+	conf := overridesTestConfig()
+
+	err := conf.ApplyOverrides([]string{"SIDECARS_OVERRIDES={"})
+	if err == nil {
+		t.Fatal("Expected invalid json overrides to be rejected")
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"text/template"
+)
+
+// unameArchs give architecture as named by uname -m (often used in release asset names) for go architectures
+var unameArchs = map[string]string{
+	"amd64":   "x86_64",
+	"386":     "i386",
+	"arm64":   "aarch64",
+	"arm":     "armv7l",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// platformFuncs give functions describing platform where cloud-sidecars runs
+func platformFuncs() template.FuncMap {
+	return template.FuncMap{
+		"os":       func() string { return runtime.GOOS },
+		"arch":     func() string { return runtime.GOARCH },
+		"platform": CurrentPlatform,
+		"uname_arch": func() string {
+			if arch, ok := unameArchs[runtime.GOARCH]; ok {
+				return arch
+			}
+			return runtime.GOARCH
+		},
+	}
+}
+
+// TemplatePlatform render {{ os }}, {{ arch }}, {{ platform }} and {{ uname_arch }} in s,
+// s is given as is when it has no template
+func TemplatePlatform(s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tpl, err := template.New("platform").Funcs(platformFuncs()).Parse(s)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	err = tpl.Execute(buf, nil)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// templateArtifact render platform templates in artifact fields to use same config on any platform
+func (c *Sidecar) templateArtifact() error {
+	fields := map[string]*string{
+		"artifact_uri":     &c.ArtifactURI,
+		"artifact_sha1":    &c.ArtifactSha1,
+		"artifact_subpath": &c.ArtifactSubpath,
+	}
+	for name, field := range fields {
+		templated, err := TemplatePlatform(*field)
+		if err != nil {
+			return fmt.Errorf("Error when templating %s of sidecar %s: %s", name, c.Name, err.Error())
+		}
+		*field = templated
+	}
+	return nil
+}
+
+// CurrentPlatform give platform where cloud-sidecars runs as <os>/<arch>
+func CurrentPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// SupportsPlatform check if sidecar can run on current platform, sidecar without platforms runs everywhere
+func (c Sidecar) SupportsPlatform() bool {
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	for _, platform := range c.Platforms {
+		if platform == runtime.GOOS || platform == CurrentPlatform() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPlatforms validate platforms format and fail when a required sidecar can't run on current platform
+func (c Sidecar) checkPlatforms() error {
+	for _, platform := range c.Platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) > 2 || parts[0] == "" || (len(parts) == 2 && parts[1] == "") {
+			return fmt.Errorf("Platform '%s' of sidecar %s must be set as <os>/<arch> or <os>", platform, c.Name)
+		}
+	}
+	if c.Required && !c.SupportsPlatform() {
+		return fmt.Errorf(
+			"Sidecar %s is required but can only run on %s, current platform is %s",
+			c.Name, strings.Join(c.Platforms, ", "), CurrentPlatform(),
+		)
+	}
+	return nil
+}
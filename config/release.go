@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ReleasePhaseLaunch = "launch"
+	ReleasePhaseSetup  = "setup"
+)
+
+// Release is a command run once per deploy before sidecars and app are started (e.g.: database migrations),
+// like release phase of heroku
+type Release struct {
+	// Command run with bash in app dir
+	Command string `yaml:"command" json:"command" expand:"-"`
+	// Phase where command is run: launch (default) runs it at first launch after a setup, setup runs it at end of setup
+	Phase string `yaml:"phase" json:"phase"`
+	// Time in seconds given to command before being killed, 0 means no timeout
+	Timeout int `yaml:"timeout" json:"timeout"`
+	// Run command at launch of every app instance instead of only first one (CF_INSTANCE_INDEX 0)
+	AllInstances bool `yaml:"all_instances" json:"all_instances"`
+}
+
+func (r Release) Check() error {
+	if r.Command == "" {
+		return fmt.Errorf("Release must have a command")
+	}
+	if r.Phase != "" && r.Phase != ReleasePhaseLaunch && r.Phase != ReleasePhaseSetup {
+		return fmt.Errorf("Release phase %s is not supported, use %s or %s", r.Phase, ReleasePhaseLaunch, ReleasePhaseSetup)
+	}
+	if r.Timeout < 0 {
+		return fmt.Errorf("Release timeout must be a positive number")
+	}
+	return nil
+}
+
+// RunAt check if command is run at phase
+func (r Release) RunAt(phase string) bool {
+	if r.Phase == "" {
+		return phase == ReleasePhaseLaunch
+	}
+	return r.Phase == phase
+}
+
+func (r Release) TimeoutDuration() time.Duration {
+	return time.Duration(r.Timeout) * time.Second
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RlimitUnlimited is value of a resource limit without limit
+const RlimitUnlimited = -1
+
+// RlimitNames are resources which can be limited in rlimits of a sidecar (as named by prlimit),
+// values are in units of bash ulimit: KB for as, core, data, fsize, memlock, rss and stack, seconds for cpu,
+// bytes for msgqueue and a number for others
+var RlimitNames = []string{
+	"as", "core", "cpu", "data", "fsize", "locks", "memlock", "msgqueue",
+	"nice", "nofile", "nproc", "rss", "rtprio", "sigpending", "stack",
+}
+
+// checkRlimits validate resources and values of rlimits
+func (c Sidecar) checkRlimits() error {
+	names := make([]string, 0, len(c.Rlimits))
+	for name := range c.Rlimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		known := false
+		for _, rlimitName := range RlimitNames {
+			known = known || name == rlimitName
+		}
+		if !known {
+			return fmt.Errorf("Rlimit %s of sidecar %s is not supported, use one of %s", name, c.Name, strings.Join(RlimitNames, ", "))
+		}
+		if c.Rlimits[name] < RlimitUnlimited {
+			return fmt.Errorf("Rlimit %s of sidecar %s must be a positive number or -1 for unlimited", name, c.Name)
+		}
+	}
+	return nil
+}
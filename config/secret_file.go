@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SecretFile is a file containing a secret written with 0600 mode before sidecar is started,
+// for sidecars only reading credentials from files
+type SecretFile struct {
+	// Path of file, relative paths are relative to .sidecars/secrets/<sidecar name> in app dir
+	Path string `yaml:"path" json:"path"`
+	// Secret given in config (e.g.: from an env var expanded at config loading)
+	Value string `yaml:"value" json:"value"`
+	// Env var giving secret
+	Env string `yaml:"env" json:"env"`
+	// Name of CredHub credential giving secret
+	Credhub string `yaml:"credhub" json:"credhub"`
+	// Path of Vault secret giving secret (e.g.: secret/data/my-app)
+	Vault string `yaml:"vault" json:"vault"`
+	// Field of CredHub credential or Vault secret to write (e.g.: private_key of a certificate credential)
+	Key string `yaml:"key" json:"key"`
+	// Overwrite file before removing it when launcher stops
+	Shred bool `yaml:"shred" json:"shred"`
+}
+
+func (f SecretFile) Check() error {
+	if f.Path == "" {
+		return fmt.Errorf("Secret file must have a path")
+	}
+	path := filepath.Clean(f.Path)
+	if !filepath.IsAbs(path) && (path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator))) {
+		return fmt.Errorf("Secret file %s must not escape secrets dir of sidecar, use an absolute path instead", f.Path)
+	}
+	sources := 0
+	for _, source := range []string{f.Value, f.Env, f.Credhub, f.Vault} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("Secret file %s must have exactly one of value, env, credhub or vault", f.Path)
+	}
+	if f.Vault != "" && f.Key == "" {
+		return fmt.Errorf("Secret file %s must have a key to pick in vault secret", f.Path)
+	}
+	return nil
+}
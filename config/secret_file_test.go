@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSecretFileCheckRejectsPathsEscapingSecretsDir(t *testing.T) {
+	tests := []struct {
+		path  string
+		valid bool
+	}{
+		{"token", true},
+		{"certs/../key.pem", true},
+		{"/etc/ssl/key.pem", true},
+		{"..", false},
+		{"../../app/main.go", false},
+		{"certs/../../key.pem", false},
+	}
+	for _, test := range tests {
+		err := SecretFile{Path: test.path, Env: "SECRET"}.Check()
+		if test.valid && err != nil {
+			t.Errorf("Expected secret file path %s to be valid, got: %s", test.path, err.Error())
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected secret file path %s to be rejected", test.path)
+		}
+	}
+}
@@ -4,52 +4,498 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/cloudfoundry-community/gautocloud/decoder"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"gopkg.in/alessio/shellescape.v1"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type Sidecars struct {
-	Sidecars  []*Sidecar `yaml:"sidecars" json:"sidecars"`
-	NoStarter bool       `yaml:"no_starter" json:"no_starter"`
-	LogLevel  string     `json:"log_level" yaml:"log_level"`
-	Dir       string     `json:"dir" yaml:"dir"`
-	LogJson   bool       `json:"log_json" yaml:"log_json"`
-	NoColor   bool       `json:"no_color" yaml:"no_color"`
-	AppPort   int        `json:"app_port" yaml:"app_port"`
+	Sidecars         []*Sidecar        `yaml:"sidecars" json:"sidecars"`
+	NoStarter        bool              `yaml:"no_starter" json:"no_starter"`
+	LogLevel         string            `json:"log_level" yaml:"log_level"`
+	Dir              string            `json:"dir" yaml:"dir"`
+	LogJson          bool              `json:"log_json" yaml:"log_json"`
+	LogLevels        map[string]string `json:"log_levels" yaml:"log_levels"`
+	Quiet            bool              `json:"quiet" yaml:"quiet"`
+	NoColor          bool              `json:"no_color" yaml:"no_color"`
+	LogTimestamp     bool              `json:"log_timestamp" yaml:"log_timestamp"`
+	LogStreamTag     bool              `json:"log_stream_tag" yaml:"log_stream_tag"`
+	AppPort          int               `json:"app_port" yaml:"app_port"`
+	AppHost          string            `json:"app_host" yaml:"app_host"`
+	AppScheme        string            `json:"app_scheme" yaml:"app_scheme"`
+	ProxyBindAddress string            `json:"proxy_bind_address" yaml:"proxy_bind_address"`
+	LogBufferSize    int               `json:"log_buffer_size" yaml:"log_buffer_size"`
+	ControlAddr      string            `json:"control_addr" yaml:"control_addr"`
+	NoControlApi     bool              `json:"no_control_api" yaml:"no_control_api"`
+	ControlTokenFile string            `json:"control_token_file" yaml:"control_token_file"`
+	ControlTLS       *ControlTLS       `json:"control_tls" yaml:"control_tls"`
+	GrpcControlAddr  string            `json:"grpc_control_addr" yaml:"grpc_control_addr"`
+	ReadyFile        string            `json:"ready_file" yaml:"ready_file"`
+	PortConflict     string            `json:"port_conflict" yaml:"port_conflict"`
+	AppWaitFor       []*WaitFor        `json:"app_wait_for" yaml:"app_wait_for"`
+	AppCommand       string            `json:"app_command" yaml:"app_command" expand:"-"`
+	SourceProfileD   bool              `json:"source_profile_d" yaml:"source_profile_d"`
+	VerifyAtLaunch   string            `json:"verify_at_launch" yaml:"verify_at_launch"`
+	UpdateChannel    string            `json:"update_channel" yaml:"update_channel"`
+	UpdateCheck      bool              `json:"update_check" yaml:"update_check"`
+	GroupOrder       []string          `json:"group_order" yaml:"group_order"`
+	StopTimeout      int               `json:"stop_timeout" yaml:"stop_timeout"`
+	StopStageTimeout int               `json:"stop_stage_timeout" yaml:"stop_stage_timeout"`
+	Subreaper        bool              `json:"subreaper" yaml:"subreaper"`
+	LaunchTimeout    int               `json:"launch_timeout" yaml:"launch_timeout"`
+	StartStagger     int               `json:"start_stagger" yaml:"start_stagger"`
+	WatchConfig      bool              `json:"watch_config" yaml:"watch_config"`
+	ExitCodeFrom     string            `json:"exit_code_from" yaml:"exit_code_from"`
+	MaxArtifactSize  int               `json:"max_artifact_size" yaml:"max_artifact_size"`
+	MavenRepository  string            `json:"maven_repository" yaml:"maven_repository"`
+	CoreDump         *CoreDump         `json:"core_dump" yaml:"core_dump"`
+	Events           *Events           `json:"events" yaml:"events"`
+	Loki             *Loki             `json:"loki" yaml:"loki"`
+	Statsd           *Statsd           `json:"statsd" yaml:"statsd"`
+	ExtraPorts       []*ExtraPort      `json:"extra_ports" yaml:"extra_ports"`
+	EnvKeys          map[string]string `json:"env_keys" yaml:"env_keys"`
+	EnvKeyPrefix     string            `json:"env_key_prefix" yaml:"env_key_prefix"`
+	Release          *Release          `json:"release" yaml:"release"`
 }
 
+// Check validate config and all its sidecars
+func (c Sidecars) Check() error {
+	// names of instances are also processes names which must not collide with other sidecars
+	names := make(map[string]string)
+	for _, sidecar := range c.Sidecars {
+		err := sidecar.Check()
+		if err != nil {
+			return err
+		}
+		for _, name := range sidecar.processNames() {
+			if owner, ok := names[name]; ok {
+				if owner == name && name == sidecar.Name {
+					return fmt.Errorf("Sidecar name %s is used more than once", name)
+				}
+				return fmt.Errorf("Name %s of sidecar %s is also used by sidecar %s (instances are named <name>-<index>)", name, sidecar.Name, owner)
+			}
+			names[name] = sidecar.Name
+		}
+	}
+	err := c.checkInstallDirs()
+	if err != nil {
+		return err
+	}
+	if c.AppScheme != "" && c.AppScheme != "http" && c.AppScheme != "https" {
+		return fmt.Errorf("App scheme %s is not supported, use http or https", c.AppScheme)
+	}
+	portNames := make(map[string]bool)
+	for _, port := range c.ExtraPorts {
+		err := port.Check()
+		if err != nil {
+			return err
+		}
+		if portNames[port.EnvSuffix()] {
+			return fmt.Errorf("Extra port name %s is used more than once", port.Name)
+		}
+		portNames[port.EnvSuffix()] = true
+	}
+	for _, group := range c.GroupOrder {
+		if !c.HasGroup(group) {
+			return fmt.Errorf("Group %s of group order has no sidecar", group)
+		}
+	}
+	if c.LaunchTimeout < 0 || c.StopTimeout < 0 || c.StopStageTimeout < 0 {
+		return fmt.Errorf("Timeouts must be positive numbers")
+	}
+	if c.StartStagger < 0 {
+		return fmt.Errorf("Start stagger must be a positive number")
+	}
+	if c.MaxArtifactSize < 0 {
+		return fmt.Errorf("Max artifact size must be a positive number")
+	}
+	switch c.PortConflict {
+	case "", PortConflictFail, PortConflictReallocate, PortConflictIgnore:
+	default:
+		return fmt.Errorf("Port conflict %s is not valid, it must be %s, %s or %s", c.PortConflict, PortConflictFail, PortConflictReallocate, PortConflictIgnore)
+	}
+	if c.LogBufferSize < 0 {
+		return fmt.Errorf("Log buffer size must be a positive number")
+	}
+	if c.Release != nil {
+		err := c.Release.Check()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkInstallDirs make sure that install dirs, which are wiped when installing sidecars, are only used by one sidecar
+// and are not in or above launcher dir
+func (c Sidecars) checkInstallDirs() error {
+	installDirs := make(map[string]string)
+	for _, sidecar := range c.Sidecars {
+		if sidecar.InstallDir == "" {
+			continue
+		}
+		installDir := filepath.Clean(sidecar.InstallDir)
+		for _, part := range strings.Split(filepath.ToSlash(installDir), "/") {
+			if part == launcherDir {
+				return fmt.Errorf("Install dir of sidecar %s cannot be in %s dir used by launcher", sidecar.Name, launcherDir)
+			}
+		}
+		for otherDir, otherName := range installDirs {
+			if installDir == otherDir || isSubDir(installDir, otherDir) || isSubDir(otherDir, installDir) {
+				return fmt.Errorf("Install dir of sidecar %s overlaps install dir of sidecar %s", sidecar.Name, otherName)
+			}
+		}
+		installDirs[installDir] = sidecar.Name
+	}
+	return nil
+}
+
+// isSubDir check if cleaned relative path dir is inside cleaned relative path parent
+func isSubDir(dir, parent string) bool {
+	return strings.HasPrefix(dir, parent+string(filepath.Separator))
+}
+
+// HasGroup check if a sidecar is in group name
+func (c Sidecars) HasGroup(name string) bool {
+	for _, sidecar := range c.Sidecars {
+		if sidecar.Group != "" && sidecar.Group == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Sidecars) SidecarByName(name string) *Sidecar {
+	for _, sidecar := range c.Sidecars {
+		if sidecar.Name == name {
+			return sidecar
+		}
+	}
+	return nil
+}
+
+// Policies when a port used by launcher is already in use
+const (
+	PortConflictFail       = "fail"
+	PortConflictReallocate = "reallocate"
+	PortConflictIgnore     = "ignore"
+)
+
+// launcherDir is dir where launcher keeps its files in app dir
+const launcherDir = ".sidecars"
+
+// StartOrderAfterApp make sidecar start once app is started
+const StartOrderAfterApp = "after_app"
+
+// forwardableSignals are signals received by launcher which can be forwarded to sidecars
+var forwardableSignals = []string{"SIGHUP", "SIGUSR1", "SIGUSR2"}
+
 type Sidecar struct {
-	Name                string            `yaml:"name" json:"name"`
-	Executable          string            `yaml:"executable" json:"executable"`
-	ArtifactURI         string            `yaml:"artifact_uri" json:"artifact_uri"`
-	ArtifactType        string            `yaml:"artifact_type" json:"artifact_type"`
-	ArtifactSha1        string            `yaml:"artifact_sha1" json:"artifact_sha1"`
-	AfterInstall        string            `yaml:"after_install" json:"after_download"`
-	Args                []string          `yaml:"args" json:"args"`
-	Env                 map[string]string `yaml:"env" json:"env"`
-	AppEnv              map[string]string `yaml:"app_env" json:"app_env"`
-	ProfileD            string            `yaml:"profiled" json:"profiled"`
-	WorkDir             string            `yaml:"work_dir" json:"work_dir"`
-	NoLogPrefix         bool              `yaml:"no_log_prefix" json:"no_log_prefix"`
-	IsRproxy            bool              `yaml:"is_rproxy" json:"is_rproxy"`
-	NoInterruptWhenStop bool              `yaml:"no_interrupt_when_stop" json:"no_interrupt_when_stop"`
+	Name                       string            `yaml:"name" json:"name"`
+	Type                       string            `yaml:"type" json:"type"`
+	BuiltinRproxy              *BuiltinRproxy    `yaml:"builtin_rproxy" json:"builtin_rproxy"`
+	BuiltinStatic              *BuiltinStatic    `yaml:"builtin_static" json:"builtin_static"`
+	BuiltinForward             *BuiltinForward   `yaml:"builtin_forward" json:"builtin_forward"`
+	Group                      string            `yaml:"group" json:"group"`
+	Executable                 string            `yaml:"executable" json:"executable"`
+	Command                    *Command          `yaml:"command" json:"command" expand:"-"`
+	UseShell                   *bool             `yaml:"use_shell" json:"use_shell"`
+	ArtifactURI                string            `yaml:"artifact_uri" json:"artifact_uri"`
+	ArtifactType               string            `yaml:"artifact_type" json:"artifact_type"`
+	ArtifactSha1               string            `yaml:"artifact_sha1" json:"artifact_sha1"`
+	ArtifactSymlink            bool              `yaml:"artifact_symlink" json:"artifact_symlink"`
+	MaxArtifactSize            int               `yaml:"max_artifact_size" json:"max_artifact_size"`
+	ArtifactSubpath            string            `yaml:"artifact_subpath" json:"artifact_subpath"`
+	StripComponents            int               `yaml:"strip_components" json:"strip_components"`
+	InstallDir                 string            `yaml:"install_dir" json:"install_dir"`
+	AfterInstall               string            `yaml:"after_install" json:"after_download" expand:"-"`
+	VerifyCommand              string            `yaml:"verify_command" json:"verify_command" expand:"-"`
+	AfterInstallTimeout        int               `yaml:"after_install_timeout" json:"after_install_timeout"`
+	Args                       []string          `yaml:"args" json:"args" expand:"-"`
+	Env                        map[string]string `yaml:"env" json:"env" expand:"-"`
+	AppEnv                     map[string]string `yaml:"app_env" json:"app_env" expand:"-"`
+	ProfileD                   string            `yaml:"profiled" json:"profiled" expand:"-"`
+	WorkDir                    string            `yaml:"work_dir" json:"work_dir"`
+	NoLogPrefix                bool              `yaml:"no_log_prefix" json:"no_log_prefix"`
+	MaxLogLinesPerSec          int               `yaml:"max_log_lines_per_sec" json:"max_log_lines_per_sec"`
+	MaxLineBytes               int               `yaml:"max_line_bytes" json:"max_line_bytes"`
+	WaitFor                    []*WaitFor        `yaml:"wait_for" json:"wait_for"`
+	StartDelay                 int               `yaml:"start_delay" json:"start_delay"`
+	StartOrder                 string            `yaml:"start_order" json:"start_order"`
+	Instances                  int               `yaml:"instances" json:"instances"`
+	InstanceBasePort           int               `yaml:"instance_base_port" json:"instance_base_port"`
+	Outputs                    []*Output         `yaml:"outputs" json:"outputs"`
+	WatchFiles                 []string          `yaml:"watch_files" json:"watch_files"`
+	IsRproxy                   bool              `yaml:"is_rproxy" json:"is_rproxy"`
+	Drain                      *Drain            `yaml:"drain" json:"drain"`
+	RollingRestart             bool              `yaml:"rolling_restart" json:"rolling_restart"`
+	NoInterruptWhenStop        bool              `yaml:"no_interrupt_when_stop" json:"no_interrupt_when_stop"`
+	ForwardSignals             []string          `yaml:"forward_signals" json:"forward_signals"`
+	IgnoreStopSignalForwarding bool              `yaml:"ignore_stop_signal_forwarding" json:"ignore_stop_signal_forwarding"`
+	OwnProcessGroup            *bool             `yaml:"own_process_group" json:"own_process_group"`
+	Platforms                  []string          `yaml:"platforms" json:"platforms"`
+	Required                   bool              `yaml:"required" json:"required"`
+	Rlimits                    map[string]int    `yaml:"rlimits" json:"rlimits"`
+	OomScoreAdj                *int              `yaml:"oom_score_adj" json:"oom_score_adj"`
+	Capabilities               *Capabilities     `yaml:"capabilities" json:"capabilities"`
+	Isolation                  *Isolation        `yaml:"isolation" json:"isolation"`
+	SecretFiles                []*SecretFile     `yaml:"secret_files" json:"secret_files"`
 }
 
 func (c Sidecar) Check() error {
 	if c.Name == "" {
 		return fmt.Errorf("You must provide a name to your sidecar")
 	}
-	if c.Executable == "" {
-		return fmt.Errorf("You must provide an executable path to your sidecar")
+	err := c.checkType()
+	if err != nil {
+		return err
+	}
+	err = c.checkPlatforms()
+	if err != nil {
+		return err
+	}
+	err = c.checkRlimits()
+	if err != nil {
+		return err
+	}
+	if c.OomScoreAdj != nil && (*c.OomScoreAdj < -1000 || *c.OomScoreAdj > 1000) {
+		return fmt.Errorf("Oom score adjustment of sidecar %s must be between -1000 and 1000", c.Name)
+	}
+	if c.Capabilities != nil {
+		err = c.Capabilities.Check()
+		if err != nil {
+			return fmt.Errorf("Invalid capabilities of sidecar %s: %s", c.Name, err.Error())
+		}
+	}
+	err = c.checkIsolation()
+	if err != nil {
+		return err
+	}
+	for _, secretFile := range c.SecretFiles {
+		err = secretFile.Check()
+		if err != nil {
+			return fmt.Errorf("Invalid secret file of sidecar %s: %s", c.Name, err.Error())
+		}
+	}
+	// executable or command can also be given by manifest of artifact
+	if !c.IsBuiltin() && c.Executable == "" && c.Command == nil && c.ArtifactURI == "" {
+		return fmt.Errorf("You must provide an executable path or a command to your sidecar")
+	}
+	if c.Executable != "" && c.Command != nil {
+		return fmt.Errorf("Executable and command cannot be set together on your sidecar")
+	}
+	if c.Command != nil {
+		err := c.Command.Check()
+		if err != nil {
+			return err
+		}
+	}
+	if IsMavenUri(c.ArtifactURI) {
+		_, err := ParseMavenUri(c.ArtifactURI)
+		if err != nil {
+			return err
+		}
+	}
+	if c.ArtifactSymlink && c.ArtifactURI == "" {
+		return fmt.Errorf("Artifact symlink can only be used with a local artifact uri")
+	}
+	if c.InstallDir != "" {
+		if c.ArtifactURI == "" {
+			return fmt.Errorf("Install dir can only be used with an artifact uri")
+		}
+		installDir := filepath.Clean(c.InstallDir)
+		if filepath.IsAbs(installDir) || installDir == "." || installDir == ".." || strings.HasPrefix(installDir, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("Install dir must be a sub directory of app dir")
+		}
+	}
+	if c.AfterInstallTimeout < 0 {
+		return fmt.Errorf("After install timeout must be a positive number")
+	}
+	if c.MaxArtifactSize < 0 {
+		return fmt.Errorf("Max artifact size must be a positive number")
+	}
+	if c.StripComponents < 0 {
+		return fmt.Errorf("Strip components must be a positive number")
+	}
+	if c.StartDelay < 0 {
+		return fmt.Errorf("Start delay must be a positive number")
+	}
+	if c.Instances < 0 || c.InstanceBasePort < 0 {
+		return fmt.Errorf("Instances and instance base port must be positive numbers")
+	}
+	if c.Instances > 1 && c.IsRproxy {
+		return fmt.Errorf("Reverse proxy sidecar can only have one instance")
+	}
+	if c.StartOrder != "" && c.StartOrder != StartOrderAfterApp {
+		return fmt.Errorf("Start order can only be %s", StartOrderAfterApp)
+	}
+	if c.MaxLogLinesPerSec < 0 || c.MaxLineBytes < 0 {
+		return fmt.Errorf("Output limits must be positive numbers")
+	}
+	for _, waitFor := range c.WaitFor {
+		err := waitFor.Check()
+		if err != nil {
+			return err
+		}
+	}
+	outputNames := make(map[string]bool)
+	for _, output := range c.Outputs {
+		err := output.Check()
+		if err != nil {
+			return err
+		}
+		if outputNames[output.Name] {
+			return fmt.Errorf("Output %s is declared more than once", output.Name)
+		}
+		outputNames[output.Name] = true
+	}
+	for _, sig := range c.ForwardSignals {
+		if !utils.InStrings(sig, forwardableSignals) {
+			return fmt.Errorf("Signal %s cannot be forwarded, only %s can be", sig, strings.Join(forwardableSignals, ", "))
+		}
+	}
+	if c.RollingRestart && !c.IsRproxy {
+		return fmt.Errorf("Rolling restart can only be set on a reverse proxy sidecar")
+	}
+	if c.Drain != nil {
+		if !c.IsRproxy {
+			return fmt.Errorf("Drain can only be set on a reverse proxy sidecar")
+		}
+		err := c.Drain.Check()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstanceName give name of process of instance at index when sidecar has more than one instance
+func (c Sidecar) InstanceName(index int) string {
+	return fmt.Sprintf("%s-%d", c.Name, index)
+}
+
+// processNames give name of sidecar and names of its instances
+func (c Sidecar) processNames() []string {
+	names := []string{c.Name}
+	for i := 0; c.Instances > 1 && i < c.Instances; i++ {
+		names = append(names, c.InstanceName(i))
+	}
+	return names
+}
+
+// ScriptTimeout give timeout of after install and verify scripts, defaultTimeout in seconds is used when not set
+func (c Sidecar) ScriptTimeout(defaultTimeout int) time.Duration {
+	if c.AfterInstallTimeout > 0 {
+		return time.Duration(c.AfterInstallTimeout) * time.Second
+	}
+	return time.Duration(defaultTimeout) * time.Second
+}
+
+// HasOwnProcessGroup tell if sidecar is run in its own process group to send signals to its children too,
+// this is the default
+func (c Sidecar) HasOwnProcessGroup() bool {
+	return c.OwnProcessGroup == nil || *c.OwnProcessGroup
+}
+
+// ExecutableName give the executable to run, this is empty when command must be run through a shell
+func (c Sidecar) ExecutableName() string {
+	if c.Command == nil {
+		return c.Executable
+	}
+	if c.Command.Shell {
+		return ""
+	}
+	return c.Command.Args[0]
+}
+
+// IsBuiltin check if sidecar is run by cloud-sidecars itself
+func (c Sidecar) IsBuiltin() bool {
+	switch c.Type {
+	case SidecarTypeBuiltinRproxy, SidecarTypeBuiltinStatic, SidecarTypeBuiltinForward:
+		return true
+	}
+	return false
+}
+
+// checkType validate type of sidecar and config of builtin sidecars
+func (c Sidecar) checkType() error {
+	if c.Type != "" && !c.IsBuiltin() {
+		return fmt.Errorf("Sidecar type can only be %s, %s or %s", SidecarTypeBuiltinRproxy, SidecarTypeBuiltinStatic, SidecarTypeBuiltinForward)
+	}
+	if c.IsBuiltin() && (c.Executable != "" || c.Command != nil || c.ArtifactURI != "") {
+		return fmt.Errorf("Builtin sidecar cannot have executable, command or artifact")
+	}
+	if c.Type != SidecarTypeBuiltinRproxy && c.BuiltinRproxy != nil {
+		return fmt.Errorf("Builtin reverse proxy config can only be set on a sidecar of type %s", SidecarTypeBuiltinRproxy)
+	}
+	if c.Type != SidecarTypeBuiltinStatic && c.BuiltinStatic != nil {
+		return fmt.Errorf("Builtin static config can only be set on a sidecar of type %s", SidecarTypeBuiltinStatic)
+	}
+	if c.Type != SidecarTypeBuiltinForward && c.BuiltinForward != nil {
+		return fmt.Errorf("Builtin forward config can only be set on a sidecar of type %s", SidecarTypeBuiltinForward)
+	}
+	switch c.Type {
+	case SidecarTypeBuiltinRproxy:
+		if c.BuiltinRproxy != nil {
+			return c.BuiltinRproxy.Check()
+		}
+	case SidecarTypeBuiltinStatic:
+		if c.BuiltinStatic == nil {
+			return fmt.Errorf("Builtin static sidecar must have a builtin_static config")
+		}
+		if c.IsRproxy {
+			return fmt.Errorf("Builtin static sidecar cannot be a reverse proxy")
+		}
+		return c.BuiltinStatic.Check()
+	case SidecarTypeBuiltinForward:
+		if c.BuiltinForward == nil {
+			return fmt.Errorf("Builtin forward sidecar must have a builtin_forward config")
+		}
+		if c.IsRproxy {
+			return fmt.Errorf("Builtin forward sidecar cannot be a reverse proxy")
+		}
+		return c.BuiltinForward.Check()
 	}
 	return nil
 }
 
+// Prepare apply use_shell on command and check sidecar
+func (c *Sidecar) Prepare() error {
+	if c.Type == SidecarTypeBuiltinRproxy {
+		c.IsRproxy = true
+	}
+	err := c.templateArtifact()
+	if err != nil {
+		return err
+	}
+	if c.Command == nil || c.UseShell == nil || c.Command.Shell == *c.UseShell {
+		return c.Check()
+	}
+	if *c.UseShell {
+		quoted := make([]string, len(c.Command.Args))
+		for i, arg := range c.Command.Args {
+			quoted[i] = shellescape.Quote(arg)
+		}
+		c.Command = &Command{Args: []string{strings.Join(quoted, " ")}, Shell: true}
+		return c.Check()
+	}
+	args, err := utils.SplitArgs(c.Command.Args[0])
+	if err != nil {
+		return err
+	}
+	c.Command = &Command{Args: args}
+	return c.Check()
+}
+
 func (c *Sidecar) UnmarshalCloud(data interface{}) error {
 	type plain Sidecar
 	err := decoder.Unmarshal(data.(map[string]interface{}), (*plain)(c))
 	if err != nil {
 		return err
 	}
-	return c.Check()
+	return c.Prepare()
 }
 
 func (c *Sidecar) UnmarshalJSON(data []byte) error {
@@ -58,7 +504,7 @@ func (c *Sidecar) UnmarshalJSON(data []byte) error {
 	if err != nil {
 		return err
 	}
-	return c.Check()
+	return c.Prepare()
 }
 
 func (c *Sidecar) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -67,5 +513,5 @@ func (c *Sidecar) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err = unmarshal((*plain)(c)); err != nil {
 		return err
 	}
-	return c.Check()
+	return c.Prepare()
 }
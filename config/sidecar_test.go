@@ -0,0 +1,92 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestCheckInstallDirs(t *testing.T) {
+	tests := []struct {
+		installDirs []string
+		valid       bool
+	}{
+		{[]string{"datadog", "agents/newrelic"}, true},
+		{[]string{"datadog", "./datadog/"}, false},
+		{[]string{"agents", "agents/newrelic"}, false},
+		{[]string{"agents/newrelic", "agents"}, false},
+		{[]string{".sidecars"}, false},
+		{[]string{".sidecars/datadog"}, false},
+		{[]string{"vendor/.sidecars"}, false},
+		{[]string{"datadog", ""}, true},
+	}
+	for _, test := range tests {
+		conf := Sidecars{}
+		for i, installDir := range test.installDirs {
+			conf.Sidecars = append(conf.Sidecars, &Sidecar{
+				Name:        string(rune('a' + i)),
+				ArtifactURI: "https://example.com/sidecar.zip",
+				InstallDir:  installDir,
+			})
+		}
+		err := conf.Check()
+		if test.valid && err != nil {
+			t.Errorf("Expected install dirs %v to be valid, got: %s", test.installDirs, err.Error())
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected install dirs %v to be rejected", test.installDirs)
+		}
+	}
+}
+
+func TestCheckInstanceNames(t *testing.T) {
+	tests := []struct {
+		sidecars []*Sidecar
+		valid    bool
+	}{
+		{[]*Sidecar{{Name: "web", Instances: 2}, {Name: "api", Instances: 2}}, true},
+		{[]*Sidecar{{Name: "web", Instances: 2}, {Name: "web-2"}}, true},
+		{[]*Sidecar{{Name: "web"}, {Name: "web"}}, false},
+		{[]*Sidecar{{Name: "web", Instances: 2}, {Name: "web-1"}}, false},
+		{[]*Sidecar{{Name: "web-1"}, {Name: "web", Instances: 2}}, false},
+		{[]*Sidecar{{Name: "web", Instances: 2}, {Name: "web-0", Instances: 2}}, false},
+	}
+	for _, test := range tests {
+		conf := Sidecars{Sidecars: test.sidecars}
+		names := make([]string, 0)
+		for _, sidecar := range test.sidecars {
+			sidecar.ArtifactURI = "https://example.com/sidecar.zip"
+			sidecar.InstallDir = sidecar.Name
+			names = append(names, sidecar.Name)
+		}
+		err := conf.Check()
+		if test.valid && err != nil {
+			t.Errorf("Expected sidecars %v to be valid, got: %s", names, err.Error())
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected sidecars %v to be rejected", names)
+		}
+	}
+}
+
+func TestCheckRejectsNegativeMaxArtifactSize(t *testing.T) {
+	conf := Sidecars{MaxArtifactSize: -1}
+	if err := conf.Check(); err == nil {
+		t.Fatal("Expected negative max artifact size to be rejected")
+	}
+	conf.MaxArtifactSize = 100
+	if err := conf.Check(); err != nil {
+		t.Fatalf("Expected max artifact size to be valid, got: %s", err.Error())
+	}
+}
+
+func TestCheckPortConflict(t *testing.T) {
+	for _, policy := range []string{"", "fail", "reallocate", "ignore"} {
+		conf := Sidecars{PortConflict: policy}
+		if err := conf.Check(); err != nil {
+			t.Errorf("Expected port conflict %q to be valid, got: %s", policy, err.Error())
+		}
+	}
+	conf := Sidecars{PortConflict: "reallocte"}
+	if err := conf.Check(); err == nil {
+		t.Fatal("Expected unknown port conflict to be rejected")
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const sopsBinary = "sops"
+
+// IsSopsFile check if file is a yaml or json document encrypted with sops,
+// sops adds its metadata with a message authentication code in a top-level sops key
+func IsSopsFile(path string) bool {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var doc struct {
+		Sops map[string]interface{} `yaml:"sops"`
+	}
+	if yaml.Unmarshal(b, &doc) != nil {
+		return false
+	}
+	_, hasMac := doc.Sops["mac"]
+	return hasMac
+}
+
+// DecryptSopsFile give decrypted content of file as yaml, decryption is done by sops binary
+// which finds keys as usual (age, aws/gcp kms, azure key vault, pgp or vault from sops env vars and config)
+func DecryptSopsFile(path string) ([]byte, error) {
+	sopsPath, err := exec.LookPath(sopsBinary)
+	if err != nil {
+		return nil, fmt.Errorf("Config file %s is encrypted with sops but sops is not found in PATH", path)
+	}
+	inputType := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		inputType = "json"
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(sopsPath, "--decrypt", "--input-type", inputType, "--output-type", "yaml", path)
+	cmd.Stderr = stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Could not decrypt config file %s with sops: %s", path, strings.TrimSpace(stderr.String()))
+	}
+	return b, nil
+}
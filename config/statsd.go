@@ -0,0 +1,12 @@
+package config
+
+type Statsd struct {
+	// Address of statsd server, e.g.: 127.0.0.1:8125
+	Address string `yaml:"address" json:"address"`
+	// Prefix added before each metric name
+	Prefix string `yaml:"prefix" json:"prefix"`
+	// Tags are added on each metric, this needs a dogstatsd server
+	Tags map[string]string `yaml:"tags" json:"tags"`
+	// NoTags must be set to true when server is a plain statsd which does not support tags
+	NoTags bool `yaml:"no_tags" json:"no_tags"`
+}
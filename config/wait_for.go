@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+)
+
+// WaitFor is an external dependency which must be reachable before starting a process
+type WaitFor struct {
+	// Tcp address in the form host:port which must accept connections
+	Tcp string `yaml:"tcp" json:"tcp"`
+	// Http url which must respond
+	Http string `yaml:"http" json:"http"`
+	// Status expected from http url, by default any status lower than 400 is accepted
+	Status int `yaml:"status" json:"status"`
+	// Timeout in seconds to wait for dependency, by default 60 seconds
+	Timeout int `yaml:"timeout" json:"timeout"`
+}
+
+func (w WaitFor) Check() error {
+	if (w.Tcp == "") == (w.Http == "") {
+		return fmt.Errorf("Wait for must have exactly one of tcp or http")
+	}
+	if w.Timeout < 0 {
+		return fmt.Errorf("Wait for timeout must be a positive number")
+	}
+	return nil
+}
+
+func (w WaitFor) String() string {
+	if w.Tcp != "" {
+		return "tcp://" + w.Tcp
+	}
+	return w.Http
+}
@@ -0,0 +1,496 @@
+package sidecars
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	controlApiPrefix  = "/v1"
+	controlSocketName = "control.sock"
+)
+
+// ControlAddress give network and address where control api listen,
+// by default this is a unix socket in sidecars directory
+func ControlAddress(sConfig config.Sidecars) (network string, address string) {
+	if sConfig.ControlAddr == "" {
+		return "unix", filepath.Join(sConfig.Dir, PathSidecarsWd, controlSocketName)
+	}
+	return splitControlAddr(sConfig.ControlAddr)
+}
+
+// splitControlAddr give network and address of unix://<path>, tcp://<host:port> or <host:port> address
+func splitControlAddr(addr string) (network string, address string) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		return "tcp", strings.TrimPrefix(addr, "tcp://")
+	}
+	return "tcp", addr
+}
+
+type controlServer struct {
+	sConfig  config.Sidecars
+	pFactory *ProcessFactory
+	listener net.Listener
+	server   *http.Server
+	network  string
+	address  string
+	done     chan struct{}
+}
+
+func newControlServer(sConfig config.Sidecars, pFactory *ProcessFactory) *controlServer {
+	network, address := ControlAddress(sConfig)
+	s := &controlServer{
+		sConfig:  sConfig,
+		pFactory: pFactory,
+		network:  network,
+		address:  address,
+		done:     make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleStatusPage)
+	mux.HandleFunc(controlApiPrefix+"/status", s.handleStatus)
+	mux.HandleFunc(controlApiPrefix+"/logs/", s.handleLogs)
+	mux.HandleFunc(controlApiPrefix+"/restart/", s.handleRestart)
+	mux.HandleFunc(controlApiPrefix+"/groups/", s.handleGroupRestart)
+	s.server = &http.Server{
+		Handler: mux,
+	}
+	return s
+}
+
+func (s *controlServer) Start() error {
+	token, err := ControlToken(s.sConfig)
+	if err != nil {
+		return err
+	}
+	if token == "" && s.network != "unix" {
+		return fmt.Errorf("A token must be set with %s env var or control_token_file when control api listen on a tcp address", ControlTokenEnvKey)
+	}
+	if token != "" {
+		s.server.Handler = bearerAuth(token, s.server.Handler)
+	}
+	tlsConfig, err := controlServerTLS(s.sConfig)
+	if err != nil {
+		return err
+	}
+	listener, err := listenControl(s.network, s.address)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s.listener = listener
+	go s.server.Serve(listener)
+	tlsMess := ""
+	if tlsConfig != nil {
+		tlsMess = " with tls"
+	}
+	log.WithField("component", "control").Infof("Control api listening on %s://%s%s", s.network, s.address, tlsMess)
+	return nil
+}
+
+// listenControl listen on address of control api, a unix socket is only usable by user running launcher
+// as no token is required on it by default
+func listenControl(network, address string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+	// remove socket left by a previous launcher
+	os.Remove(address)
+	err := os.MkdirAll(filepath.Dir(address), 0755)
+	if err != nil {
+		return nil, err
+	}
+	// socket is created in a private dir and moved to address once restricted,
+	// it is never reachable by other users with permissions given by umask
+	privateDir, err := ioutil.TempDir(filepath.Dir(address), ".control")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(privateDir)
+	privateAddress := filepath.Join(privateDir, "s")
+	listener, err := net.Listen(network, privateAddress)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chmod(privateAddress, 0600)
+	if err == nil {
+		err = os.Rename(privateAddress, address)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("Could not create control api socket with restricted permissions: %s", err.Error())
+	}
+	return listener, nil
+}
+
+func (s *controlServer) Stop() {
+	if s.listener == nil {
+		return
+	}
+	// stop following logs to let server shutdown
+	close(s.done)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.server.Shutdown(ctx)
+	if s.network == "unix" {
+		os.Remove(s.address)
+	}
+}
+
+func (s *controlServer) handleStatus(w http.ResponseWriter, req *http.Request) {
+	if acceptsHtml(req) {
+		req.URL.Path = "/"
+		s.handleStatusPage(w, req)
+		return
+	}
+	statuses := make([]ProcessStatus, 0)
+	for _, p := range s.pFactory.Processes() {
+		statuses = append(statuses, p.Status())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+func (s *controlServer) handleLogs(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, controlApiPrefix+"/logs/")
+	p := s.pFactory.ProcessByName(name)
+	if p == nil {
+		http.Error(w, fmt.Sprintf("Process %s not found", name), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if req.URL.Query().Get("follow") != "true" || p.output == nil {
+		w.Write(p.Output())
+		return
+	}
+	s.followLogs(w, req, p)
+}
+
+// followLogs stream last output of process and then its output as it is written until client disconnects
+func (s *controlServer) followLogs(w http.ResponseWriter, req *http.Request, p *process) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	last, output, unsubscribe := p.output.Subscribe()
+	defer unsubscribe()
+	w.Write(last)
+	flusher.Flush()
+	for {
+		select {
+		case b := <-output:
+			w.Write(b)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *controlServer) handleRestart(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(req.URL.Path, controlApiPrefix+"/restart/")
+	p := s.pFactory.ProcessByName(name)
+	if p == nil {
+		http.Error(w, fmt.Sprintf("Process %s not found", name), http.StatusNotFound)
+		return
+	}
+	s.restart(w, []*process{p})
+}
+
+func (s *controlServer) handleGroupRestart(w http.ResponseWriter, req *http.Request) {
+	group := strings.TrimPrefix(req.URL.Path, controlApiPrefix+"/groups/")
+	if !strings.HasSuffix(group, "/restart") {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	group = strings.TrimSuffix(group, "/restart")
+	processes := s.pFactory.ProcessesByGroup(group)
+	if len(processes) == 0 {
+		http.Error(w, fmt.Sprintf("Group %s not found", group), http.StatusNotFound)
+		return
+	}
+	s.restart(w, processes)
+}
+
+// restart restart given processes and give names of restarted processes
+func (s *controlServer) restart(w http.ResponseWriter, processes []*process) {
+	restarted, err := restartProcesses(processes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restarted)
+}
+
+// restartProcesses restart given processes and give names of restarted processes,
+// an error is given only when none of them could be restarted
+func restartProcesses(processes []*process) ([]string, error) {
+	restarted := make([]string, 0, len(processes))
+	errMessages := make([]string, 0)
+	for _, p := range processes {
+		err := p.Restart()
+		if err != nil {
+			errMessages = append(errMessages, err.Error())
+			continue
+		}
+		restarted = append(restarted, p.name)
+	}
+	if len(restarted) == 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errMessages, ", "))
+	}
+	return restarted, nil
+}
+
+// ControlClient request control api of a running launcher
+type ControlClient struct {
+	httpClient *http.Client
+	baseUrl    string
+	token      string
+}
+
+func NewControlClient(network, address string) *ControlClient {
+	baseUrl := "http://" + address
+	if network == "unix" {
+		baseUrl = "http://unix"
+	}
+	return &ControlClient{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, address)
+				},
+			},
+		},
+		baseUrl: baseUrl + controlApiPrefix,
+	}
+}
+
+// SetAuth send token as bearer token and use tls with given config if not nil
+func (c *ControlClient) SetAuth(token string, tlsConfig *tls.Config) {
+	c.token = token
+	if tlsConfig == nil {
+		return
+	}
+	c.baseUrl = "https" + strings.TrimPrefix(c.baseUrl, "http")
+	c.httpClient.Transport.(*http.Transport).TLSClientConfig = tlsConfig
+}
+
+func (c ControlClient) do(method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseUrl+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.httpClient.Do(req)
+}
+
+func (c ControlClient) get(path string) ([]byte, error) {
+	resp, err := c.do(http.MethodGet, path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not reach control api, is launcher running ? (%s)", err.Error())
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Control api error: %s", strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+func (c ControlClient) post(path string) ([]byte, error) {
+	resp, err := c.do(http.MethodPost, path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not reach control api, is launcher running ? (%s)", err.Error())
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Control api error: %s", strings.TrimSpace(string(b)))
+	}
+	return b, nil
+}
+
+func (c ControlClient) Status() ([]ProcessStatus, error) {
+	b, err := c.get("/status")
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]ProcessStatus, 0)
+	err = json.Unmarshal(b, &statuses)
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func (c ControlClient) Logs(name string) ([]byte, error) {
+	return c.get("/logs/" + name)
+}
+
+// FollowLogs write last output of a process and then its output as it is written until launcher stops
+func (c ControlClient) FollowLogs(name string, w io.Writer) error {
+	resp, err := c.do(http.MethodGet, "/logs/"+name+"?follow=true")
+	if err != nil {
+		return fmt.Errorf("Could not reach control api, is launcher running ? (%s)", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Control api error: %s", strings.TrimSpace(string(b)))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Restart restart a sidecar and give names of restarted processes
+func (c ControlClient) Restart(name string) ([]string, error) {
+	return c.restart("/restart/" + name)
+}
+
+// RestartGroup restart all sidecars of a group and give names of restarted processes
+func (c ControlClient) RestartGroup(group string) ([]string, error) {
+	return c.restart("/groups/" + group + "/restart")
+}
+
+func (c ControlClient) restart(path string) ([]string, error) {
+	b, err := c.post(path)
+	if err != nil {
+		return nil, err
+	}
+	restarted := make([]string, 0)
+	err = json.Unmarshal(b, &restarted)
+	if err != nil {
+		return nil, err
+	}
+	return restarted, nil
+}
+
+func (l Launcher) controlClient() (*ControlClient, error) {
+	client := NewControlClient(ControlAddress(l.sConfig))
+	token, err := ControlToken(l.sConfig)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := controlClientTLS(l.sConfig)
+	if err != nil {
+		return nil, err
+	}
+	client.SetAuth(token, tlsConfig)
+	return client, nil
+}
+
+// ShowProcessesStatus print status of processes from a running launcher
+func (l Launcher) ShowProcessesStatus() error {
+	client, err := l.controlClient()
+	if err != nil {
+		return err
+	}
+	statuses, err := client.Status()
+	if err != nil {
+		return err
+	}
+	table := newTable(l.stdout)
+	table.SetHeader([]string{"Name", "Type", "Group", "State", "Pid", "Uptime", "Error"})
+	for _, status := range statuses {
+		pid := "-"
+		if status.Pid > 0 {
+			pid = fmt.Sprintf("%d", status.Pid)
+		}
+		uptime := "-"
+		if status.StartedAt != nil && status.ExitedAt == nil {
+			uptime = time.Since(*status.StartedAt).Truncate(time.Second).String()
+		}
+		group := status.Group
+		if group == "" {
+			group = "-"
+		}
+		table.Append([]string{status.Name, status.Type, group, status.State, pid, uptime, status.Error})
+	}
+	table.Render()
+	return nil
+}
+
+// ShowProcessLogs print last output of a process from a running launcher,
+// output written after is also printed when follow is true
+func (l Launcher) ShowProcessLogs(name string, follow bool) error {
+	client, err := l.controlClient()
+	if err != nil {
+		return err
+	}
+	if follow {
+		return client.FollowLogs(name, l.stdout)
+	}
+	b, err := client.Logs(name)
+	if err != nil {
+		return err
+	}
+	_, err = l.stdout.Write(b)
+	return err
+}
+
+// RestartProcesses restart sidecars with given names and all sidecars in given groups of a running launcher
+func (l Launcher) RestartProcesses(names []string, groups []string) error {
+	client, err := l.controlClient()
+	if err != nil {
+		return err
+	}
+	restarted := make([]string, 0)
+	for _, name := range names {
+		r, err := client.Restart(name)
+		if err != nil {
+			return err
+		}
+		restarted = append(restarted, r...)
+	}
+	for _, group := range groups {
+		r, err := client.RestartGroup(group)
+		if err != nil {
+			return err
+		}
+		restarted = append(restarted, r...)
+	}
+	for _, name := range restarted {
+		fmt.Fprintf(l.stdout, "Restarting %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package sidecars
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ControlTokenEnvKey is env var giving bearer token of control api, it takes precedence over control_token_file
+const ControlTokenEnvKey = "SIDECARS_CONTROL_TOKEN"
+
+// ControlToken give bearer token required by control api, empty if no token has been set
+func ControlToken(sConfig config.Sidecars) (string, error) {
+	if token := os.Getenv(ControlTokenEnvKey); token != "" {
+		return token, nil
+	}
+	if sConfig.ControlTokenFile == "" {
+		return "", nil
+	}
+	tokenFile := sConfig.ControlTokenFile
+	if !filepath.IsAbs(tokenFile) {
+		tokenFile = filepath.Join(sConfig.Dir, tokenFile)
+	}
+	b, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("Could not read control api token: %s", err.Error())
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("Control api token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
+
+// controlServerTLS give tls config of control api, nil when tls is not enabled
+func controlServerTLS(sConfig config.Sidecars) (*tls.Config, error) {
+	conf := sConfig.ControlTLS
+	if conf == nil || (conf.CertFile == "" && conf.KeyFile == "") {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(controlPath(sConfig, conf.CertFile), controlPath(sConfig, conf.KeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("Could not load control api certificate: %s", err.Error())
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// controlClientTLS give tls config to request control api, nil when tls is not enabled
+func controlClientTLS(sConfig config.Sidecars) (*tls.Config, error) {
+	conf := sConfig.ControlTLS
+	if conf == nil || (conf.CertFile == "" && conf.KeyFile == "") {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+	if conf.CaFile == "" {
+		return tlsConfig, nil
+	}
+	b, err := ioutil.ReadFile(controlPath(sConfig, conf.CaFile))
+	if err != nil {
+		return nil, fmt.Errorf("Could not read control api CA: %s", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("No certificate found in control api CA file %s", conf.CaFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+func controlPath(sConfig config.Sidecars, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(sConfig.Dir, path)
+}
+
+// bearerAuth only let pass requests with token in Authorization header,
+// token is never read from url to not leak in access logs
+func bearerAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		given, ok := bearerToken(req.Header.Get("Authorization"))
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cloud-sidecars"`)
+			if acceptsHtml(req) {
+				// browsers can't send token by themselves, status page needs a client adding it
+				http.Error(w, "Unauthorized: status page requires an 'Authorization: Bearer <token>' header, "+
+					"open it with a browser extension or a client adding this header", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// bearerToken give token of an authorization value using bearer scheme (case insensitive),
+// false is given for any other value (e.g.: a token without scheme)
+func bearerToken(authorization string) (string, bool) {
+	const scheme = "bearer "
+	if len(authorization) <= len(scheme) || !strings.EqualFold(authorization[:len(scheme)], scheme) {
+		return "", false
+	}
+	return authorization[len(scheme):], true
+}
@@ -0,0 +1,82 @@
+package sidecars
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// statusPageLogLines is number of last output lines of each process shown on status page
+const statusPageLogLines = 20
+
+var statusPageTpl = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>cloud-sidecars status</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.3em 1em; border-bottom: 1px solid #ddd; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+.running { color: #2a7d2a; }
+.failed { color: #c0392b; }
+</style>
+</head>
+<body>
+<h1>cloud-sidecars status</h1>
+<p>{{ .Time.Format "2006-01-02 15:04:05 MST" }}, refreshed every 5 seconds</p>
+<table>
+<tr><th>Name</th><th>Type</th><th>Group</th><th>State</th><th>Pid</th><th>Uptime</th><th>Error</th></tr>
+{{ range .Processes }}<tr>
+<td>{{ .Name }}</td><td>{{ .Type }}</td><td>{{ .Group }}</td><td class="{{ .State }}">{{ .State }}</td>
+<td>{{ if .Pid }}{{ .Pid }}{{ end }}</td><td>{{ .Uptime }}</td><td>{{ .Error }}</td>
+</tr>{{ end }}
+</table>
+{{ range .Processes }}
+<h2>{{ .Name }}</h2>
+<pre>{{ range .LastOutput }}{{ . }}
+{{ else }}No output{{ end }}</pre>
+{{ end }}
+</body>
+</html>
+`))
+
+type statusPageProcess struct {
+	ProcessStatus
+	Uptime     string
+	LastOutput []string
+}
+
+// handleStatusPage serve an html page with state and last output of processes for humans
+func (s *controlServer) handleStatusPage(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	processes := make([]statusPageProcess, 0)
+	for _, p := range s.pFactory.Processes() {
+		status := p.Status()
+		uptime := ""
+		if status.StartedAt != nil && status.ExitedAt == nil {
+			uptime = time.Since(*status.StartedAt).Truncate(time.Second).String()
+		}
+		processes = append(processes, statusPageProcess{
+			ProcessStatus: status,
+			Uptime:        uptime,
+			LastOutput:    lastLines(string(p.Output()), statusPageLogLines),
+		})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	statusPageTpl.Execute(w, struct {
+		Time      time.Time
+		Processes []statusPageProcess
+	}{time.Now(), processes})
+}
+
+// acceptsHtml check if request come from a browser
+func acceptsHtml(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), "text/html")
+}
@@ -0,0 +1,74 @@
+package sidecars
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenControlRestrictsSocketPermissions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-control")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	address := filepath.Join(dir, PathSidecarsWd, controlSocketName)
+
+	listener, err := listenControl("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	info, err := os.Stat(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("Expected control socket permissions to be 0600, got %o", perm)
+	}
+	conn, err := net.Dial("unix", address)
+	if err != nil {
+		t.Fatalf("Expected control socket to be reachable at %s, got: %s", address, err.Error())
+	}
+	conn.Close()
+	files, err := ioutil.ReadDir(filepath.Dir(address))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected only control socket in its dir, got %d files", len(files))
+	}
+}
+
+func TestBearerAuthOnlyAcceptsAuthorizationHeader(t *testing.T) {
+	handler := bearerAuth("secret", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	tests := []struct {
+		url           string
+		authorization string
+		status        int
+	}{
+		{"/v1/status", "Bearer secret", http.StatusOK},
+		{"/v1/status", "bearer secret", http.StatusOK},
+		{"/v1/status", "Bearer wrong", http.StatusUnauthorized},
+		{"/v1/status", "secret", http.StatusUnauthorized},
+		{"/v1/status", "Basic secret", http.StatusUnauthorized},
+		{"/v1/status", "Bearer ", http.StatusUnauthorized},
+		{"/v1/status", "", http.StatusUnauthorized},
+		{"/v1/status?token=secret", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("Expected status %d for %s with authorization %q, got %d", test.status, test.url, test.authorization, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+// Package controlpb contains grpc service of control api and its generated go client
+package controlpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative control.proto
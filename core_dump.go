@@ -0,0 +1,156 @@
+package sidecars
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const DefaultMaxCoreDumps = 3
+
+// coreDumpCollector move core dumps of sidecars killed by a fatal signal in a dump dir
+type coreDumpCollector struct {
+	dir      string
+	maxDumps int
+	pattern  string
+}
+
+// setupCoreDumps enable core dumps for processes started after and collect the ones of crashed sidecars,
+// app inherits core dumps limit but its core dumps are not collected
+func (l Launcher) setupCoreDumps() error {
+	conf := l.sConfig.CoreDump
+	dir := conf.Dir
+	if dir == "" {
+		dir = filepath.Join(PathSidecarsWd, "cores")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(l.sConfig.Dir, dir)
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	err = enableCoreDumps(uint64(conf.MaxSize) * 1024 * 1024)
+	if err != nil {
+		return err
+	}
+	pattern := corePattern()
+	if strings.HasPrefix(pattern, "|") {
+		return fmt.Errorf("Core dumps are sent by system to '%s', they can't be collected", strings.TrimPrefix(pattern, "|"))
+	}
+	maxDumps := conf.MaxDumps
+	if maxDumps == 0 {
+		maxDumps = DefaultMaxCoreDumps
+	}
+	l.processFactory.SetCoreDumpCollector(&coreDumpCollector{
+		dir:      dir,
+		maxDumps: maxDumps,
+		pattern:  pattern,
+	})
+	return nil
+}
+
+// coreDumped check if process exit error come from a signal which has created a core dump
+func coreDumped(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.CoreDump()
+}
+
+// collect move core dump written by cmd since startedAt in dump dir and remove oldest dumps
+func (c *coreDumpCollector) collect(name string, cmd *exec.Cmd, startedAt time.Time) {
+	entry := log.WithField("sidecar", name)
+	// kernel write core dump as core_pattern says, relative pattern is relative to process workdir
+	patternDir := filepath.Dir(c.pattern)
+	if !filepath.IsAbs(patternDir) {
+		patternDir = filepath.Join(cmd.Dir, patternDir)
+	}
+	files, err := ioutil.ReadDir(patternDir)
+	if err != nil {
+		entry.Warnf("Core dump could not be collected: %s", err.Error())
+		return
+	}
+	collected := false
+	for _, file := range files {
+		if !file.Mode().IsRegular() || !c.isCoreDump(file.Name()) || file.ModTime().Before(startedAt) {
+			continue
+		}
+		dest := filepath.Join(c.dir, fmt.Sprintf("%s-%s-%d.core", name, time.Now().Format("20060102T150405"), cmd.Process.Pid))
+		err := moveFile(filepath.Join(patternDir, file.Name()), dest)
+		if err != nil {
+			entry.Warnf("Core dump could not be collected: %s", err.Error())
+			continue
+		}
+		collected = true
+		entry.Infof("Core dump of sidecar %s has been collected in %s", name, dest)
+	}
+	if !collected {
+		entry.Warnf("Sidecar %s has dumped core but core dump has not been found in %s", name, patternDir)
+		return
+	}
+	c.removeOldest()
+}
+
+// isCoreDump check if file name can be the one of a core dump written by kernel with core_pattern,
+// without format specifier in pattern, kernel can add pid as extension (see core_uses_pid)
+func (c *coreDumpCollector) isCoreDump(name string) bool {
+	base := filepath.Base(c.pattern)
+	if i := strings.Index(base, "%"); i >= 0 {
+		return strings.HasPrefix(name, base[:i])
+	}
+	if name == base {
+		return true
+	}
+	_, err := strconv.Atoi(strings.TrimPrefix(name, base+"."))
+	return strings.HasPrefix(name, base+".") && err == nil
+}
+
+// removeOldest only keep maxDumps most recent core dumps in dump dir
+func (c *coreDumpCollector) removeOldest() {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	for i := c.maxDumps; i < len(files); i++ {
+		os.Remove(filepath.Join(c.dir, files[i].Name()))
+	}
+}
+
+// moveFile rename file or copy it when it is on another filesystem
+func moveFile(src, dest string) error {
+	err := os.Rename(src, dest)
+	if err == nil {
+		return nil
+	}
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+	destFile, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+	_, err = io.Copy(destFile, srcFile)
+	if err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return os.Remove(src)
+}
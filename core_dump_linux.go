@@ -0,0 +1,38 @@
+//go:build linux
+// +build linux
+
+package sidecars
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"strings"
+)
+
+// enableCoreDumps set core dumps size limit of launcher, inherited by processes it starts,
+// maxBytes is capped by hard limit and 0 means as big as allowed
+func enableCoreDumps(maxBytes uint64) error {
+	var limit unix.Rlimit
+	err := unix.Getrlimit(unix.RLIMIT_CORE, &limit)
+	if err != nil {
+		return err
+	}
+	limit.Cur = maxBytes
+	if maxBytes == 0 || (limit.Max != unix.RLIM_INFINITY && maxBytes > limit.Max) {
+		limit.Cur = limit.Max
+	}
+	if limit.Cur == 0 {
+		return fmt.Errorf("Core dumps are disabled by hard limit of launcher")
+	}
+	return unix.Setrlimit(unix.RLIMIT_CORE, &limit)
+}
+
+// corePattern give pattern used by kernel to name core dumps
+func corePattern() string {
+	b, err := ioutil.ReadFile("/proc/sys/kernel/core_pattern")
+	if err != nil {
+		return "core"
+	}
+	return strings.TrimSpace(string(b))
+}
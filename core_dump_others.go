@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package sidecars
+
+import (
+	"fmt"
+)
+
+// enableCoreDumps is not supported on this platform
+func enableCoreDumps(maxBytes uint64) error {
+	return fmt.Errorf("Core dumps collection is only supported on linux")
+}
+
+func corePattern() string {
+	return "core"
+}
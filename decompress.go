@@ -0,0 +1,194 @@
+package sidecars
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"github.com/ArthurHlt/zipper"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var compressedFileExts = []string{".gz", ".gzip", ".bz2", ".bz", ".tgz", ".tbz2", ".tbz", ".tar"}
+
+// decompressSingleFile rewrite zip made by zipper for an artifact whose name shows a gzip or bzip2 compressed file
+// or a tar archive (e.g.: my-agent.gz), which zipper left as is: compressed file is replaced by its content
+// or by the files inside tar archive. Decompressed content bigger than maxSize bytes is rejected (0 means no limit)
+func decompressSingleFile(zipFilePath, fileName string, maxSize int64) error {
+	if !hasCompressedExt(fileName) {
+		return nil
+	}
+	r, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if len(r.File) != 1 || r.File[0].FileInfo().IsDir() {
+		return nil
+	}
+	zf := r.File[0]
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	content, err := decompressReader(bufio.NewReader(rc))
+	if err != nil || content == nil {
+		return err
+	}
+	br := bufio.NewReader(limitDecompressed(content, maxSize))
+	isTar := isTarContent(br)
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(zipFilePath), ".decompress-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	zw := zip.NewWriter(tmpFile)
+	if isTar {
+		err = tarToZip(br, zw)
+	} else {
+		err = fileToZip(br, zw, zf)
+	}
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	r.Close()
+	return os.Rename(tmpFile.Name(), zipFilePath)
+}
+
+// hasCompressedExt check if file name has extension of a compressed file or of a tar archive
+func hasCompressedExt(fileName string) bool {
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, compressedExt := range compressedFileExts {
+		if ext == compressedExt {
+			return true
+		}
+	}
+	return false
+}
+
+// limitDecompressed make reading decompressed content fail after maxSize bytes (0 means no limit),
+// compressed size is already limited when downloading but content of a gzip bomb is not
+func limitDecompressed(r io.Reader, maxSize int64) io.Reader {
+	if maxSize <= 0 {
+		return r
+	}
+	return &sizeLimitBody{ReadCloser: ioutil.NopCloser(r), maxSize: maxSize}
+}
+
+// decompressReader give decompressed content for gzip and bzip2 data, uncompressed tar data is given as is
+// and nil is given when data is neither compressed nor a tar
+func decompressReader(br *bufio.Reader) (io.Reader, error) {
+	magic, _ := br.Peek(3)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(br)
+	case bytes.Equal(magic, []byte("BZh")):
+		return bzip2.NewReader(br), nil
+	case isTarContent(br):
+		return br, nil
+	}
+	return nil, nil
+}
+
+// isTarContent check tar magic which is at offset 257 of a tar archive
+func isTarContent(br *bufio.Reader) bool {
+	header, _ := br.Peek(262)
+	return len(header) == 262 && string(header[257:262]) == "ustar"
+}
+
+func fileToZip(r *bufio.Reader, zw *zip.Writer, zf *zip.File) error {
+	name := zf.Name
+	ext := strings.ToLower(path.Ext(name))
+	for _, compressedExt := range compressedFileExts {
+		if ext == compressedExt {
+			name = strings.TrimSuffix(name, path.Ext(name))
+			break
+		}
+	}
+	mode := zf.Mode()
+	head, _ := r.Peek(4)
+	if zipper.IsExecutable(bytes.NewReader(head)) {
+		mode |= 0755
+	}
+	header := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	}
+	header.SetMode(mode)
+	header.Modified = zf.Modified
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// tarToZip write tar entries in zip, like zipper does leading directory is removed if archive starts with one
+func tarToZip(r io.Reader, zw *zip.Writer) error {
+	tr := tar.NewReader(r)
+	rootFolder := ""
+	first := true
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fileInfo := header.FileInfo()
+		if first && fileInfo.IsDir() {
+			rootFolder = strings.TrimSuffix(path.Clean(header.Name), "/") + "/"
+			first = false
+			continue
+		}
+		first = false
+		name := path.Clean(header.Name)
+		if rootFolder != "" {
+			name = strings.TrimPrefix(name, rootFolder)
+		}
+		zipHeader, err := zip.FileInfoHeader(fileInfo)
+		if err != nil {
+			return err
+		}
+		zipHeader.Name = name
+		if fileInfo.IsDir() {
+			zipHeader.Name += "/"
+		} else {
+			zipHeader.Method = zip.Deflate
+		}
+		w, err := zw.CreateHeader(zipHeader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case header.Typeflag == tar.TypeSymlink:
+			_, err = w.Write([]byte(header.Linkname))
+		case fileInfo.Mode().IsRegular():
+			_, err = io.Copy(w, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package sidecars
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZipOfGzip write a zip containing a single gzip compressed file as zipper does for a .gz artifact
+func writeZipOfGzip(t *testing.T, path, name string, content []byte) {
+	gz := &bytes.Buffer{}
+	gw := gzip.NewWriter(gz)
+	_, err := gw.Write(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = w.Write(gz.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func zipEntryNames(t *testing.T, path string) []string {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	names := make([]string, 0)
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestDecompressSingleFileOnlyForCompressedArtifactName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-decompress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	zipPath := filepath.Join(dir, "sidecar.zip")
+
+	writeZipOfGzip(t, zipPath, "agent.gz", []byte("agent"))
+	err = decompressSingleFile(zipPath, "bundle.zip", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := zipEntryNames(t, zipPath); len(names) != 1 || names[0] != "agent.gz" {
+		t.Fatalf("Expected zip artifact to be left as is, got %v", names)
+	}
+
+	err = decompressSingleFile(zipPath, "agent.gz", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := zipEntryNames(t, zipPath); len(names) != 1 || names[0] != "agent" {
+		t.Fatalf("Expected gzip artifact to be decompressed, got %v", names)
+	}
+}
+
+func TestDecompressSingleFileRejectsContentBiggerThanMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-decompress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	zipPath := filepath.Join(dir, "sidecar.zip")
+	writeZipOfGzip(t, zipPath, "agent.gz", make([]byte, 2*bytesPerMB))
+
+	err = decompressSingleFile(zipPath, "agent.gz", bytesPerMB)
+	if err == nil {
+		t.Fatal("Expected decompressed content bigger than max size to be rejected")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build linux
+// +build linux
+
+package sidecars
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// freeDiskSpace give available bytes on filesystem containing path
+func freeDiskSpace(path string) (uint64, bool) {
+	var stat unix.Statfs_t
+	err := unix.Statfs(path, &stat)
+	if err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
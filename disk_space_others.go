@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package sidecars
+
+// freeDiskSpace is not supported on this platform, free space is considered unknown
+func freeDiskSpace(path string) (uint64, bool) {
+	return 0, false
+}
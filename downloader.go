@@ -12,7 +12,7 @@ import (
 func DownloadSidecar(zipFilePath string, c *config.Sidecar) error {
 	entry := log.WithField("component", "Downloader").WithField("sidecar", c.Name)
 	entry.Infof("Downloading from %s ...", c.ArtifactURI)
-	err := DownloadArtifact(zipFilePath, c.ArtifactURI, c.ArtifactType, c.ArtifactSha1)
+	err := DownloadArtifactWithLimit(zipFilePath, c.ArtifactURI, c.ArtifactType, c.ArtifactSha1, int64(c.MaxArtifactSize)*bytesPerMB)
 	if err != nil {
 		return err
 	}
@@ -20,8 +20,17 @@ func DownloadSidecar(zipFilePath string, c *config.Sidecar) error {
 	return nil
 }
 
+// DownloadArtifact download artifact as a zip file
 func DownloadArtifact(zipFilePath, uri, fileType, sha1 string) error {
+	return DownloadArtifactWithLimit(zipFilePath, uri, fileType, sha1, 0)
+}
+
+// DownloadArtifactWithLimit download artifact as a zip file, http downloads bigger than maxSize bytes fail (0 means no limit)
+func DownloadArtifactWithLimit(zipFilePath, uri, fileType, sha1 string, maxSize int64) error {
 	s, err := ZipperSess(uri, fileType)
+	if maxSize > 0 {
+		s, err = limitedZipperSess(uri, fileType, maxSize)
+	}
 	if err != nil {
 		return err
 	}
@@ -46,16 +55,20 @@ func DownloadArtifact(zipFilePath, uri, fileType, sha1 string) error {
 		zipFile.Close()
 		return err
 	}
-	defer zipLocal.Close()
 
 	_, err = io.Copy(zipLocal, zipFile)
 	if err != nil {
+		zipLocal.Close()
 		zipFile.Close()
 		return err
 	}
 	zipFile.Close()
+	err = zipLocal.Close()
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return decompressSingleFile(zipFilePath, artifactFileName(uri), maxSize)
 }
 
 func ZipperSess(uri, fileType string) (*zipper.Session, error) {
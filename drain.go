@@ -0,0 +1,76 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+// DefaultDrainTimeout is time in seconds given to drain command or http call
+const DefaultDrainTimeout = 10
+
+// runDrain run drain command or call drain url of a sidecar and wait for drain delay
+func (f *ProcessFactory) runDrain(sidecar *config.Sidecar, env map[string]string, wd string) error {
+	drain := sidecar.Drain
+	entry := log.WithField("sidecar", sidecar.Name)
+	timeout := time.Duration(drain.Timeout) * time.Second
+	if drain.Timeout == 0 {
+		timeout = DefaultDrainTimeout * time.Second
+	}
+	var err error
+	switch {
+	case drain.Command != "":
+		entry.Infof("Draining with '%s' ...", drain.Command)
+		var script string
+		script, err = TemplatingScript(env, drain.Command)
+		if err != nil {
+			break
+		}
+		err = runScript(
+			script,
+			wd,
+			utils.EnvMapToOsEnv(env),
+			f.stdout, f.stderr,
+			fmt.Sprintf("[drain:%s]", sidecar.Name),
+			timeout,
+		)
+	case drain.Http != "":
+		entry.Infof("Draining with call to %s ...", drain.Http)
+		err = callDrainUrl(drain, timeout)
+	}
+	if drain.Delay > 0 {
+		entry.Infof("Waiting %d seconds for connections to drain ...", drain.Delay)
+		time.Sleep(time.Duration(drain.Delay) * time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("Drain failed: %s", err.Error())
+	}
+	entry.Info("Finished draining.")
+	return nil
+}
+
+func callDrainUrl(drain *config.Drain, timeout time.Duration) error {
+	method := drain.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, drain.Http, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout: timeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d received", resp.StatusCode)
+	}
+	return nil
+}
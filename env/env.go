@@ -0,0 +1,72 @@
+// Package env manipulates environments given as maps of env var names to values.
+//
+// Functions never modify maps given as parameters, they always give a new map.
+// When environments are layered (see Merge and Override), a layer given after another one
+// takes precedence over it, and environments are converted to lists sorted by name
+// to give deterministic command environments.
+package env
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// FromOs give environment of current process
+func FromOs() map[string]string {
+	return FromList(os.Environ())
+}
+
+// FromList give environment from a list of KEY=VALUE entries (e.g.: os.Environ()),
+// entries without = are ignored and last entry of a key takes precedence
+func FromList(environ []string) map[string]string {
+	env := make(map[string]string)
+	for _, e := range environ {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		env[kv[0]] = kv[1]
+	}
+	return env
+}
+
+// ToList give a list of KEY=VALUE entries sorted by name to be used as environment of a command
+func ToList(env map[string]string) []string {
+	environ := make([]string, 0, len(env))
+	for _, k := range Keys(env) {
+		environ = append(environ, k+"="+env[k])
+	}
+	return environ
+}
+
+// Keys give names of env vars sorted
+func Keys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge give a new environment with all layers, a layer overrides values of previous ones
+func Merge(layers ...map[string]string) map[string]string {
+	env := make(map[string]string)
+	for _, layer := range layers {
+		for k, v := range layer {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// Override give base environment overridden by values, values are templated with base environment first
+// (e.g.: PATH: "/my/bin:$PATH" or URL: "http://localhost:{{ .PORT }}")
+func Override(base, values map[string]string) (map[string]string, error) {
+	templated, err := TemplateValues(base, values)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(base, templated), nil
+}
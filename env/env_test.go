@@ -0,0 +1,81 @@
+package env
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeLaterLayersTakePrecedence(t *testing.T) {
+	base := map[string]string{"A": "base", "B": "base"}
+	layer1 := map[string]string{"B": "layer1", "C": "layer1"}
+	layer2 := map[string]string{"C": "layer2"}
+
+	merged := Merge(base, layer1, layer2)
+
+	expected := map[string]string{"A": "base", "B": "layer1", "C": "layer2"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+	if !reflect.DeepEqual(base, map[string]string{"A": "base", "B": "base"}) {
+		t.Fatalf("Base layer has been modified: %v", base)
+	}
+}
+
+func TestOverrideValuesTakePrecedenceOverBase(t *testing.T) {
+	base := map[string]string{"PATH": "/bin", "PORT": "8080", "KEEP": "base"}
+	values := map[string]string{
+		"PATH": "/my/bin:$PATH",
+		"URL":  "http://localhost:{{ .PORT }}",
+		"PORT": "9090",
+	}
+
+	overridden, err := Override(base, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"PATH": "/my/bin:/bin",
+		// values are templated with base env, not with each other
+		"URL":  "http://localhost:8080",
+		"PORT": "9090",
+		"KEEP": "base",
+	}
+	if !reflect.DeepEqual(overridden, expected) {
+		t.Fatalf("Expected %v, got %v", expected, overridden)
+	}
+	if base["PATH"] != "/bin" || base["PORT"] != "8080" {
+		t.Fatalf("Base env has been modified: %v", base)
+	}
+}
+
+func TestFromListKeepsEqualSignsInValues(t *testing.T) {
+	env := FromList([]string{"A=b=c", "EMPTY=", "IGNORED", "DUP=first", "DUP=last"})
+
+	expected := map[string]string{"A": "b=c", "EMPTY": "", "DUP": "last"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Fatalf("Expected %v, got %v", expected, env)
+	}
+}
+
+func TestFromOsKeepsEqualSignsInValues(t *testing.T) {
+	t.Setenv("SIDECARS_ENV_TEST", "key=value==")
+
+	env := FromOs()
+
+	if env["SIDECARS_ENV_TEST"] != "key=value==" {
+		t.Fatalf("Expected value key=value==, got %s", env["SIDECARS_ENV_TEST"])
+	}
+}
+
+func TestToListIsSortedByName(t *testing.T) {
+	env := map[string]string{"C": "3", "A": "1=1", "B": "2"}
+
+	for i := 0; i < 10; i++ {
+		list := ToList(env)
+		expected := []string{"A=1=1", "B=2", "C=3"}
+		if !reflect.DeepEqual(list, expected) {
+			t.Fatalf("Expected %v, got %v", expected, list)
+		}
+	}
+}
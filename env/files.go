@@ -0,0 +1,99 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gliderlabs/sigil"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// fileFuncs are template functions reading files, e.g. to take values from service binding files:
+// {{ file "/etc/secrets/token" }}, {{ jsonPath "bindings/db.json" "credentials.uri" }}
+// or {{ yamlPath "bindings/db.yml" "hosts.0" }}, relative paths are relative to working directory
+var fileFuncs = template.FuncMap{
+	"file":     file,
+	"jsonPath": jsonPath,
+	"yamlPath": yamlPath,
+}
+
+func init() {
+	sigil.Register(fileFuncs)
+}
+
+// file give content of a file without trailing new lines
+func file(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// jsonPath give value at path in a json file
+func jsonPath(path, valuePath string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var data interface{}
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return "", fmt.Errorf("File %s is not valid json: %s", path, err.Error())
+	}
+	return pathValue(path, data, valuePath)
+}
+
+// yamlPath give value at path in a yaml file
+func yamlPath(path, valuePath string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var data interface{}
+	err = yaml.Unmarshal(b, &data)
+	if err != nil {
+		return "", fmt.Errorf("File %s is not valid yaml: %s", path, err.Error())
+	}
+	return pathValue(path, data, valuePath)
+}
+
+// pathValue give value at a path of keys and list indexes separated by dots (e.g.: credentials.hosts.0),
+// values which are not strings are given as json
+func pathValue(path string, data interface{}, valuePath string) (string, error) {
+	current := data
+	if valuePath != "" && valuePath != "." {
+		for _, key := range strings.Split(strings.TrimPrefix(valuePath, "."), ".") {
+			var ok bool
+			switch node := current.(type) {
+			case map[string]interface{}:
+				current, ok = node[key]
+			case map[interface{}]interface{}:
+				current, ok = node[key]
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				ok = err == nil && i >= 0 && i < len(node)
+				if ok {
+					current = node[i]
+				}
+			}
+			if !ok {
+				return "", fmt.Errorf("Path %s not found in file %s", valuePath, path)
+			}
+		}
+	}
+	switch v := current.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	}
+	b, err := json.Marshal(current)
+	if err != nil {
+		return "", fmt.Errorf("Value at path %s in file %s can't be given as json: %s", valuePath, path, err.Error())
+	}
+	return string(b), nil
+}
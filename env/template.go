@@ -0,0 +1,110 @@
+package env
+
+import (
+	"fmt"
+	"github.com/gliderlabs/sigil"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// sigilMutex protect sigil global posix preprocess setting
+var sigilMutex sync.Mutex
+
+func init() {
+	sigil.PosixPreprocess = true
+}
+
+// Template render a go template with env vars of environment,
+// env vars can be referenced as {{ .VAR }}, $VAR or ${VAR}
+func Template(env map[string]string, s string) (string, error) {
+	return TemplateFuncs(env, s, nil)
+}
+
+// TemplateFuncs render a go template like Template with extra template functions,
+// functions are only available during this rendering
+func TemplateFuncs(env map[string]string, s string, funcs template.FuncMap) (string, error) {
+	sigilMutex.Lock()
+	defer sigilMutex.Unlock()
+	if len(funcs) > 0 {
+		sigil.Register(funcs)
+		defer sigil.Register(unavailableFuncs(funcs))
+	}
+	buf, err := sigil.Execute([]byte(s), toInterfaces(env), "env-tpl")
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// TemplateValues give a new map of values templated with environment,
+// values are not templated with each other
+func TemplateValues(env, values map[string]string) (map[string]string, error) {
+	return TemplateValuesFuncs(env, values, nil)
+}
+
+// TemplateValuesFuncs give a new map of values templated like TemplateValues with extra template functions
+func TemplateValuesFuncs(env, values map[string]string, funcs template.FuncMap) (map[string]string, error) {
+	templated := make(map[string]string)
+	for k, v := range values {
+		tplV, err := TemplateFuncs(env, v, funcs)
+		if err != nil {
+			return nil, err
+		}
+		templated[k] = tplV
+	}
+	return templated, nil
+}
+
+// TemplateArgs give a new list of arguments templated with environment
+func TemplateArgs(env map[string]string, args ...string) ([]string, error) {
+	templated := make([]string, len(args))
+	for i, arg := range args {
+		tplArg, err := Template(env, arg)
+		if err != nil {
+			return nil, err
+		}
+		templated[i] = tplArg
+	}
+	return templated, nil
+}
+
+// TemplateScript render templates of a shell script with environment,
+// unlike Template $VAR are left as is to be expanded by shell
+func TemplateScript(env map[string]string, script string) (string, error) {
+	if !strings.Contains(script, "{{") {
+		return script, nil
+	}
+	sigilMutex.Lock()
+	defer sigilMutex.Unlock()
+	sigil.PosixPreprocess = false
+	defer func() {
+		sigil.PosixPreprocess = true
+	}()
+	buf, err := sigil.Execute([]byte(script), toInterfaces(env), "script-tpl")
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func toInterfaces(env map[string]string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for k, v := range env {
+		m[k] = v
+	}
+	return m
+}
+
+// unavailableFuncs give functions failing when used outside of rendering which registered them,
+// sigil keeps registered functions forever
+func unavailableFuncs(funcs template.FuncMap) template.FuncMap {
+	unavailable := make(template.FuncMap)
+	for name := range funcs {
+		name := name
+		unavailable[name] = func(args ...interface{}) (string, error) {
+			return "", fmt.Errorf("Function %s can't be used here", name)
+		}
+	}
+	return unavailable
+}
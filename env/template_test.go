@@ -0,0 +1,81 @@
+package env
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplateValuesIsDeterministic(t *testing.T) {
+	base := map[string]string{"HOST": "localhost", "PORT": "8080"}
+	values := make(map[string]string)
+	for _, k := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+		values[k] = k + "=http://{{ .HOST }}:$PORT"
+	}
+
+	first, err := TemplateValues(base, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		templated, err := TemplateValues(base, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(templated, first) {
+			t.Fatalf("Templated values differ between renderings: %v and %v", first, templated)
+		}
+	}
+	if first["A"] != "A=http://localhost:8080" {
+		t.Fatalf("Expected A=http://localhost:8080, got %s", first["A"])
+	}
+	if values["A"] != "A=http://{{ .HOST }}:$PORT" {
+		t.Fatalf("Values have been modified: %v", values)
+	}
+}
+
+func TestTemplateFuncsAreUnavailableAfterRendering(t *testing.T) {
+	env := map[string]string{"NAME": "world"}
+	funcs := template.FuncMap{
+		"sidecarsTestFunc": func(s string) string {
+			return "hello " + s
+		},
+	}
+
+	rendered, err := TemplateFuncs(env, `{{ sidecarsTestFunc .NAME }}`, funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered != "hello world" {
+		t.Fatalf("Expected hello world, got %s", rendered)
+	}
+
+	_, err = Template(env, `{{ sidecarsTestFunc .NAME }}`)
+	if err == nil {
+		t.Fatal("Expected an error when using function outside of rendering which registered it")
+	}
+	if !strings.Contains(err.Error(), "Function sidecarsTestFunc can't be used here") {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+}
+
+func TestTemplateScriptKeepsShellVars(t *testing.T) {
+	env := map[string]string{"PORT": "8080"}
+
+	script, err := TemplateScript(env, `echo {{ .PORT }} $HOME`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script != "echo 8080 $HOME" {
+		t.Fatalf("Expected echo 8080 $HOME, got %s", script)
+	}
+	// posix expansion is restored after rendering a script
+	rendered, err := Template(env, `$PORT`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered != "8080" {
+		t.Fatalf("Expected 8080, got %s", rendered)
+	}
+}
@@ -0,0 +1,107 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// reservedEnvKeys are env vars set by launcher for sidecars and app which can be renamed with env_keys and env_key_prefix
+var reservedEnvKeys = []string{
+	ProxyAppPortEnvKey,
+	AppPortEnvKey,
+	AppDirEnvKey,
+	SidecarDirEnvKey,
+	SidecarNameEnvKey,
+	SidecarSecretsDirEnvKey,
+	ProxyAppHostEnvKey,
+	ProxyAppSchemeEnvKey,
+	ProxyAppUrlEnvKey,
+	ProxyBindAddressEnvKey,
+	ProxyListenAddrEnvKey,
+}
+
+// envKeyNames rename env vars set by launcher when they collide with env vars used by app or sidecars,
+// zero value keeps default names
+type envKeyNames struct {
+	names        map[string]string
+	prefix       string
+	portSuffixes []string
+}
+
+func newEnvKeyNames(conf config.Sidecars) envKeyNames {
+	// keys are matched whatever their case as config loading can lower them
+	names := make(map[string]string)
+	for key, name := range conf.EnvKeys {
+		names[strings.ToUpper(key)] = name
+	}
+	suffixes := make([]string, len(conf.ExtraPorts))
+	for i, extraPort := range conf.ExtraPorts {
+		suffixes[i] = extraPort.EnvSuffix()
+	}
+	return envKeyNames{
+		names:        names,
+		prefix:       conf.EnvKeyPrefix,
+		portSuffixes: suffixes,
+	}
+}
+
+func (l Launcher) envKeyNames() envKeyNames {
+	return newEnvKeyNames(l.sConfig)
+}
+
+// warnUnknownEnvKeys warn about keys of env_keys which are not set by launcher
+func warnUnknownEnvKeys(conf config.Sidecars) {
+	for key := range conf.EnvKeys {
+		if !utils.InStrings(strings.ToUpper(key), reservedEnvKeys) {
+			log.WithField("component", "Launcher").Warnf(
+				"Env var %s of env_keys is not set by launcher, only %s can be renamed",
+				key, strings.Join(reservedEnvKeys, ", "),
+			)
+		}
+	}
+}
+
+// forSidecar give names of env vars for sidecar, builtin sidecars are run by launcher and always get default names
+func (n envKeyNames) forSidecar(sidecar *config.Sidecar) envKeyNames {
+	if sidecar.IsBuiltin() {
+		return envKeyNames{}
+	}
+	return n
+}
+
+// name give name of env var set by launcher as key
+func (n envKeyNames) name(key string) string {
+	if !utils.InStrings(key, reservedEnvKeys) {
+		return key
+	}
+	if name, ok := n.names[key]; ok && name != "" {
+		return name
+	}
+	return n.prefix + key
+}
+
+// rename give env set by launcher with its keys renamed,
+// ports of extra ports (e.g.: PROXY_APP_PORT_GRPC) are renamed as port of app they are suffixing
+func (n envKeyNames) rename(env map[string]string) map[string]string {
+	if len(n.names) == 0 && n.prefix == "" {
+		return env
+	}
+	renamed := make(map[string]string)
+	for k, v := range env {
+		renamed[n.keyName(k)] = v
+	}
+	return renamed
+}
+
+func (n envKeyNames) keyName(key string) string {
+	for _, suffix := range n.portSuffixes {
+		for _, base := range []string{ProxyAppPortEnvKey, AppPortEnvKey} {
+			if key == base+suffix {
+				return n.name(base) + suffix
+			}
+		}
+	}
+	return n.name(key)
+}
@@ -0,0 +1,200 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	EventStarted     = "started"
+	EventExited      = "exited"
+	EventProbeFailed = "probe_failed"
+	EventRestarting  = "restarting"
+	EventStopping    = "stopping"
+
+	eventsBufferSize  = 1000
+	eventsDialTimeout = 5 * time.Second
+)
+
+// Event is a lifecycle event of launcher or of a process, it is written as a json line
+type Event struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	Type     string    `json:"type,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Pid      int       `json:"pid,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Signal   string    `json:"signal,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// EventEmitter write lifecycle events on a file descriptor or a socket for platform agents,
+// a nil emitter can be used and does nothing
+type EventEmitter struct {
+	out     io.WriteCloser
+	network string
+	address string
+	events  chan Event
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewEventEmitter(conf config.Events) (*EventEmitter, error) {
+	e := &EventEmitter{
+		events: make(chan Event, eventsBufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if conf.Address != "" {
+		e.network, e.address = "tcp", conf.Address
+		switch {
+		case strings.HasPrefix(conf.Address, "unix://"):
+			e.network, e.address = "unix", strings.TrimPrefix(conf.Address, "unix://")
+		case strings.HasPrefix(conf.Address, "tcp://"):
+			e.address = strings.TrimPrefix(conf.Address, "tcp://")
+		}
+		return e, nil
+	}
+	if conf.Fd <= 2 {
+		return nil, fmt.Errorf("Events must be sent on an address or on a file descriptor greater than 2")
+	}
+	f := os.NewFile(uintptr(conf.Fd), "events")
+	if f == nil {
+		return nil, fmt.Errorf("File descriptor %d is not valid", conf.Fd)
+	}
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("File descriptor %d is not opened: %s", conf.Fd, err.Error())
+	}
+	e.out = f
+	return e, nil
+}
+
+func (e *EventEmitter) Start() {
+	if e == nil {
+		return
+	}
+	go e.run()
+}
+
+// Stop write remaining events and close output
+func (e *EventEmitter) Stop() {
+	if e == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+}
+
+// Emit send an event without blocking, event is dropped if emitter is too slow
+func (e *EventEmitter) Emit(event Event) {
+	if e == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	select {
+	case <-e.stop:
+		return
+	case e.events <- event:
+	default:
+		log.WithField("component", "events").Debugf("Event %s has been dropped", event.Event)
+	}
+}
+
+// emitProcess send an event about a process
+func (e *EventEmitter) emitProcess(event string, p *process, err error) {
+	if e == nil {
+		return
+	}
+	ev := Event{
+		Event: event,
+		Type:  processEventType(p),
+		Name:  p.name,
+		Pid:   p.pid(),
+	}
+	if event == EventStarted || event == EventExited {
+		// e.g.: sidecar_started or app_exited
+		ev.Event = ev.Type + "_" + event
+	}
+	if event == EventExited {
+		status := p.Status()
+		ev.ExitCode = status.ExitCode
+		ev.Signal = status.Signal
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	e.Emit(ev)
+}
+
+func (e *EventEmitter) run() {
+	defer close(e.done)
+	for {
+		select {
+		case event := <-e.events:
+			e.send(event)
+		case <-e.stop:
+			e.flush()
+			return
+		}
+	}
+}
+
+// flush send events still waiting and close output
+func (e *EventEmitter) flush() {
+	for {
+		select {
+		case event := <-e.events:
+			e.send(event)
+		default:
+			if e.out != nil {
+				e.out.Close()
+			}
+			return
+		}
+	}
+}
+
+func (e *EventEmitter) send(event Event) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	err = e.write(append(b, '\n'))
+	if err != nil {
+		log.WithField("component", "events").Debugf("Event %s could not be sent: %s", event.Event, err.Error())
+	}
+}
+
+// write send line to output, connection is opened again when an error occurred on previous one
+func (e *EventEmitter) write(line []byte) error {
+	if e.out == nil {
+		conn, err := net.DialTimeout(e.network, e.address, eventsDialTimeout)
+		if err != nil {
+			return err
+		}
+		e.out = conn
+	}
+	_, err := e.out.Write(line)
+	if err != nil && e.address != "" {
+		e.out.Close()
+		e.out = nil
+	}
+	return err
+}
+
+// processEventType give type of process in events, app process is named app
+func processEventType(p *process) string {
+	if p.typeP == "cloud" {
+		return "app"
+	}
+	return p.typeP
+}
@@ -0,0 +1,162 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/capabilities"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/isolation"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ulimitFlags give option of bash ulimit setting each resource limit
+var ulimitFlags = map[string]string{
+	"as":         "-v",
+	"core":       "-c",
+	"cpu":        "-t",
+	"data":       "-d",
+	"fsize":      "-f",
+	"locks":      "-x",
+	"memlock":    "-l",
+	"msgqueue":   "-q",
+	"nice":       "-e",
+	"nofile":     "-n",
+	"nproc":      "-u",
+	"rss":        "-m",
+	"rtprio":     "-r",
+	"sigpending": "-i",
+	"stack":      "-s",
+}
+
+// withExecSetup give command setting resource limits and oom score adjustment of sidecar before executing cmd,
+// they are set by bash in the process which then executes sidecar to only apply them to sidecar and its children,
+// launcher and app keep their own settings.
+func withExecSetup(cmd *exec.Cmd, sidecar *config.Sidecar) (*exec.Cmd, error) {
+	steps, err := rlimitsSteps(sidecar.Rlimits)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, oomScoreAdjSteps(sidecar)...)
+	if len(steps) == 0 {
+		return cmd, nil
+	}
+	// executable is given as $0 and its args as positional parameters to not be interpreted by shell
+	script := strings.Join(steps, " && ") + ` && exec "$0" "$@"`
+	wrapped := exec.Command("bash", append([]string{"-c", script, cmd.Path}, cmd.Args[1:]...)...)
+	wrapped.Env = cmd.Env
+	wrapped.Dir = cmd.Dir
+	wrapped.SysProcAttr = cmd.SysProcAttr
+	return wrapped, nil
+}
+
+// rlimitsSteps give bash ulimit commands setting rlimits, values are in units of ulimit (see RlimitNames) and -1 means unlimited.
+// Plain ulimit sets both soft and hard limits so sidecar can't raise them again.
+func rlimitsSteps(rlimits map[string]int) ([]string, error) {
+	if len(rlimits) == 0 {
+		return nil, nil
+	}
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("Rlimits are not supported on %s", runtime.GOOS)
+	}
+	names := make([]string, 0, len(rlimits))
+	for name := range rlimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	steps := make([]string, len(names))
+	for i, name := range names {
+		flag, ok := ulimitFlags[name]
+		if !ok {
+			return nil, fmt.Errorf("Rlimit %s is not supported", name)
+		}
+		value := strconv.Itoa(rlimits[name])
+		if rlimits[name] < 0 {
+			value = "unlimited"
+		}
+		steps[i] = fmt.Sprintf("ulimit %s %s", flag, value)
+	}
+	return steps, nil
+}
+
+// oomScoreAdjSteps give bash command setting oom score adjustment of sidecar, it is only available on linux
+func oomScoreAdjSteps(sidecar *config.Sidecar) []string {
+	if sidecar.OomScoreAdj == nil {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		log.WithField("sidecar", sidecar.Name).Warnf("Oom score adjustment is ignored, it is only supported on linux")
+		return nil
+	}
+	return []string{fmt.Sprintf("echo %d > /proc/self/oom_score_adj", *sidecar.OomScoreAdj)}
+}
+
+// withCapabilities give command executing cmd through launcher binary which drops linux capabilities of sidecar first,
+// it must be the last step before executing sidecar as setting rlimits or oom score adjustment can require capabilities
+func withCapabilities(cmd *exec.Cmd, sidecar *config.Sidecar) (*exec.Cmd, error) {
+	if sidecar.Capabilities == nil {
+		return cmd, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("Capabilities are only supported on linux")
+	}
+	if sidecar.Isolation != nil && sidecar.Isolation.IsEnabled() {
+		// dropped by isolation step once namespaces are set up, launcher binary may be hidden by private /tmp
+		return cmd, nil
+	}
+	return selfExecCmd(cmd, capabilities.Command)
+}
+
+// withIsolation give command executing cmd in new namespaces through launcher binary which sets them up first
+func withIsolation(cmd *exec.Cmd, sidecar *config.Sidecar) (*exec.Cmd, error) {
+	if sidecar.Isolation == nil || !sidecar.Isolation.IsEnabled() {
+		return cmd, nil
+	}
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("Isolation is only supported on linux")
+	}
+	wrapped, err := selfExecCmd(cmd, isolation.Command)
+	if err != nil {
+		return nil, err
+	}
+	wrapped.SysProcAttr = isolationSysProcAttr(*sidecar.Isolation)
+	return wrapped, nil
+}
+
+// selfExecCmd give command executing cmd through launcher binary run with command as first argument
+func selfExecCmd(cmd *exec.Cmd, command string) (*exec.Cmd, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	wrapped := exec.Command(exePath, append([]string{command, cmd.Path}, cmd.Args[1:]...)...)
+	wrapped.Env = cmd.Env
+	wrapped.Dir = cmd.Dir
+	return wrapped, nil
+}
+
+// execSetupEnv give sidecar env with capabilities and isolation config read by launcher binary when executing sidecar
+func execSetupEnv(sidecar *config.Sidecar, env map[string]string) (map[string]string, error) {
+	confs := map[string]interface{}{}
+	if sidecar.Capabilities != nil {
+		confs[capabilities.ConfigEnvKey] = sidecar.Capabilities
+	}
+	if sidecar.Isolation != nil && sidecar.Isolation.IsEnabled() {
+		confs[isolation.ConfigEnvKey] = sidecar.Isolation
+	}
+	setupEnv := make(map[string]string)
+	for key, conf := range confs {
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+		setupEnv[key] = string(b)
+	}
+	return utils.MergeEnv(env, setupEnv), nil
+}
@@ -0,0 +1,96 @@
+package sidecars
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	ExitCodeFromApp          = "app"
+	ExitCodeFromFirstFailure = "first_failure"
+)
+
+// ExitCodeError is given by launcher when a process failed, launcher should exit with its code
+type ExitCodeError struct {
+	Code int
+	err  error
+}
+
+func (e ExitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e ExitCodeError) ExitCode() int {
+	return e.Code
+}
+
+// exitCode give code of a process exit error as shell does: exit status or 128 + signal number
+func exitCode(err error) int {
+	code, _ := exitStatus(err)
+	if code == 0 {
+		return 1
+	}
+	return code
+}
+
+// exitStatus give exit code of a process from its exit error and name of signal which killed it (if any)
+func exitStatus(err error) (int, string) {
+	if err == nil {
+		return 0, ""
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return 1, ""
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), signalName(status.Signal())
+	}
+	return exitErr.ExitCode(), ""
+}
+
+// exitCodeError give error to return for launch according to exit_code_from policy:
+// exit code of app (by default), of a sidecar or of the first process which failed
+func (l Launcher) exitCodeError(err error) error {
+	var failed *process
+	policy := l.sConfig.ExitCodeFrom
+	switch policy {
+	case "", ExitCodeFromApp:
+		for _, p := range l.processFactory.Processes() {
+			if p.typeP == "cloud" {
+				failed = p
+			}
+		}
+	case ExitCodeFromFirstFailure:
+		for _, p := range l.processFactory.Processes() {
+			code, failedAt := p.Failure()
+			if code == 0 {
+				continue
+			}
+			if failed == nil {
+				failed = p
+				continue
+			}
+			if _, firstAt := failed.Failure(); failedAt.Before(firstAt) {
+				failed = p
+			}
+		}
+	default:
+		failed = l.processFactory.ProcessByName(policy)
+		if failed == nil {
+			log.WithField("component", "Launcher").Warnf("Sidecar %s given in exit_code_from has not been launched", policy)
+		}
+	}
+	if failed == nil {
+		return err
+	}
+	code, _ := failed.Failure()
+	if code == 0 {
+		return err
+	}
+	if err == nil {
+		err = fmt.Errorf("%s %s exited with code %d", failed.typeP, failed.name, code)
+	}
+	return ExitCodeError{Code: code, err: err}
+}
@@ -0,0 +1,57 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"strconv"
+)
+
+// ExtraPortEnvKey is prefix of env var giving port of an extra port where app listens, suffixed by name of port (e.g.: PORT_GRPC)
+const ExtraPortEnvKey = "PORT"
+
+// computeExtraPortsEnv give env vars of extra ports of app given to sidecars and app:
+// SIDECAR_APP_PORT_<NAME> is port exposed by platform, PROXY_APP_PORT_<NAME> and PORT_<NAME> are port where app listens.
+// When a reverse proxy sidecar fronts app, app listens on app_port of extra port or on a free port when allocate is true,
+// port where app listens is omitted when it can't be known.
+func (l Launcher) computeExtraPortsEnv(allocate bool) (map[string]string, error) {
+	env := make(map[string]string)
+	proxied := l.hasRproxy()
+	for _, extraPort := range l.sConfig.ExtraPorts {
+		suffix := extraPort.EnvSuffix()
+		env[AppPortEnvKey+suffix] = strconv.Itoa(extraPort.Port)
+		appPort := extraPort.Port
+		if proxied {
+			appPort = extraPort.AppPort
+			var err error
+			switch {
+			case appPort == 0 && !allocate:
+				continue
+			case appPort == 0:
+				appPort, err = utils.FreePort()
+			case allocate:
+				appPort, err = l.ensurePortFree(appPort, fmt.Sprintf("app extra port %s", extraPort.Name), true)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		env[ProxyAppPortEnvKey+suffix] = strconv.Itoa(appPort)
+		env[ExtraPortEnvKey+suffix] = strconv.Itoa(appPort)
+	}
+	return env, nil
+}
+
+// setExtraPortsEnv compute env vars of extra ports, they are kept to give same ports to sidecars restarted by a reload
+func (l Launcher) setExtraPortsEnv(allocate bool) error {
+	env, err := l.computeExtraPortsEnv(allocate)
+	if err != nil {
+		return err
+	}
+	for key := range l.extraPortsEnv {
+		delete(l.extraPortsEnv, key)
+	}
+	for key, value := range env {
+		l.extraPortsEnv[key] = value
+	}
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 )
 
 type CmdHandlerFactory func(*exec.Cmd) (CmdHandler, error)
@@ -19,14 +21,29 @@ func NoOpCmdHandlerFactory(cmd *exec.Cmd) (CmdHandler, error) {
 }
 
 type ProcessFactory struct {
-	errChan    chan error
-	signalChan chan os.Signal
-	wg         *sync.WaitGroup
-	wd         string
-	stdout     io.Writer
-	stderr     io.Writer
-	cStarter   starter.Starter
-	cmdFactory CmdHandlerFactory
+	errChan     chan error
+	signalChan  chan os.Signal
+	wg          *sync.WaitGroup
+	wd          string
+	stdout      io.Writer
+	stderr      io.Writer
+	appStdin    io.Reader
+	appTty      bool
+	prefixOpts  PrefixOptions
+	bufferSize  int
+	processes   []*process
+	processesMu sync.Mutex
+	lokiPusher  *LokiPusher
+	metrics     *StatsdClient
+	events      *EventEmitter
+	startedChan chan *process
+	stopChan    chan struct{}
+	stopOnce    sync.Once
+	appWaitFor  []*config.WaitFor
+	appCommand  string
+	cStarter    starter.Starter
+	cmdFactory  CmdHandlerFactory
+	coreDumps   *coreDumpCollector
 }
 
 func NewProcessFactory(
@@ -34,14 +51,16 @@ func NewProcessFactory(
 	cStarter starter.Starter,
 	wd string) *ProcessFactory {
 	return &ProcessFactory{
-		errChan:    make(chan error, 100),
-		signalChan: make(chan os.Signal, 100),
-		wg:         &sync.WaitGroup{},
-		stderr:     stderr,
-		stdout:     stdout,
-		wd:         wd,
-		cStarter:   cStarter,
-		cmdFactory: NoOpCmdHandlerFactory,
+		errChan:     make(chan error, 100),
+		signalChan:  make(chan os.Signal, 100),
+		startedChan: make(chan *process, 100),
+		stopChan:    make(chan struct{}),
+		wg:          &sync.WaitGroup{},
+		stderr:      stderr,
+		stdout:      stdout,
+		wd:          wd,
+		cStarter:    cStarter,
+		cmdFactory:  NoOpCmdHandlerFactory,
 	}
 }
 
@@ -49,6 +68,91 @@ func (f *ProcessFactory) SetCmdHandlerFactory(cmdFactory CmdHandlerFactory) {
 	f.cmdFactory = cmdFactory
 }
 
+// SetPrefixOptions set options used when prefixing sidecars output
+func (f *ProcessFactory) SetPrefixOptions(opts PrefixOptions) {
+	f.prefixOpts = opts
+}
+
+// SetOutputBufferSize set size in bytes of last output kept in memory for each process
+func (f *ProcessFactory) SetOutputBufferSize(size int) {
+	f.bufferSize = size
+}
+
+// SetLokiPusher send output of processes created after to loki
+func (f *ProcessFactory) SetLokiPusher(pusher *LokiPusher) {
+	f.lokiPusher = pusher
+}
+
+// SetMetrics send metrics about processes created after with given client
+func (f *ProcessFactory) SetMetrics(metrics *StatsdClient) {
+	f.metrics = metrics
+}
+
+// SetEventEmitter send lifecycle events of processes created after with given emitter
+func (f *ProcessFactory) SetEventEmitter(events *EventEmitter) {
+	f.events = events
+}
+
+// SetCoreDumpCollector collect core dumps of sidecars created after
+func (f *ProcessFactory) SetCoreDumpCollector(collector *coreDumpCollector) {
+	f.coreDumps = collector
+}
+
+// Processes give all processes created by this factory
+func (f *ProcessFactory) Processes() []*process {
+	f.processesMu.Lock()
+	defer f.processesMu.Unlock()
+	return append([]*process{}, f.processes...)
+}
+
+// ProcessByName give a process created by this factory by its name
+func (f *ProcessFactory) ProcessByName(name string) *process {
+	for _, p := range f.Processes() {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// ProcessesByGroup give sidecars processes of a group
+func (f *ProcessFactory) ProcessesByGroup(group string) []*process {
+	processes := make([]*process, 0)
+	for _, p := range f.Processes() {
+		if p.group != "" && p.group == group {
+			processes = append(processes, p)
+		}
+	}
+	return processes
+}
+
+// RemoveProcess remove a process from processes created by this factory
+func (f *ProcessFactory) RemoveProcess(removed *process) {
+	f.processesMu.Lock()
+	defer f.processesMu.Unlock()
+	processes := make([]*process, 0, len(f.processes))
+	for _, p := range f.processes {
+		if p != removed {
+			processes = append(processes, p)
+		}
+	}
+	f.processes = processes
+}
+
+// addProcess add a process to processes created by this factory
+func (f *ProcessFactory) addProcess(p *process) {
+	f.processesMu.Lock()
+	defer f.processesMu.Unlock()
+	f.processes = append(f.processes, p)
+}
+
+// SetAppStdin forward given stdin to app process,
+// if tty is true app is attached to a new pseudo-terminal
+func (f *ProcessFactory) SetAppStdin(stdin io.Reader, tty bool) {
+	f.appStdin = stdin
+	f.appTty = tty
+}
+
 func (f *ProcessFactory) WaitGroup() *sync.WaitGroup {
 	return f.wg
 }
@@ -61,23 +165,70 @@ func (f *ProcessFactory) SignalChan() chan os.Signal {
 	return f.signalChan
 }
 
+// Stop make processes still waiting for their dependencies to not start
+func (f *ProcessFactory) Stop() {
+	f.stopOnce.Do(func() {
+		close(f.stopChan)
+	})
+}
+
+// SetAppWaitFor set dependencies which must be reachable before starting app
+func (f *ProcessFactory) SetAppWaitFor(waitFor []*config.WaitFor) {
+	f.appWaitFor = waitFor
+}
+
+// SetAppCommand set command to start app instead of the one detected by starter,
+// command is templated with app env
+func (f *ProcessFactory) SetAppCommand(command string) {
+	f.appCommand = command
+}
+
+// StartedChan receive each process when it has been started
+func (f *ProcessFactory) StartedChan() chan *process {
+	return f.startedChan
+}
+
 func (f *ProcessFactory) FromStarter(env map[string]string, profileDir string) (*process, error) {
-	cloudCmd, err := f.cStarter.StartCmd(
+	output := NewRingBuffer(f.bufferSize)
+	stdout, stderr := f.processWriters("launcher", output, true)
+	cStarter := f.cStarter
+	if f.appCommand != "" {
+		command, err := TemplatingFromEnv(env, f.appCommand)
+		if err != nil {
+			return nil, err
+		}
+		cmdStarter, ok := cStarter.(starter.CommandStarter)
+		if !ok {
+			return nil, fmt.Errorf("Starter %s cannot start app with another command", cStarter.Name())
+		}
+		cStarter = cmdStarter.WithCommand(command)
+	}
+	cloudCmd, err := cStarter.StartCmd(
 		utils.EnvMapToOsEnv(env),
 		profileDir,
-		f.stdout,
-		f.stderr,
+		stdout,
+		stderr,
 	)
 	if err != nil {
 		return nil, err
 	}
-	// set pgid for sending signal to child
-	cloudCmd.SysProcAttr = utils.PgidSysProcAttr(cloudCmd.SysProcAttr)
-	cmdHandler, err := f.cmdFactory(cloudCmd)
+	if f.appStdin == nil {
+		// set pgid for sending signal to child
+		// this is not done in interactive mode to let app in foreground process group and be able to read terminal
+		cloudCmd.SysProcAttr = utils.PgidSysProcAttr(cloudCmd.SysProcAttr)
+	} else {
+		cloudCmd.Stdin = f.appStdin
+	}
+	var cmdHandler CmdHandler
+	if f.appTty {
+		cmdHandler, err = newTtyCmdHandler(cloudCmd, f.appStdin, stdout)
+	} else {
+		cmdHandler, err = f.cmdFactory(cloudCmd)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &process{
+	p := &process{
 		cmd:             cloudCmd,
 		cmdHandler:      cmdHandler,
 		name:            "launcher",
@@ -87,7 +238,36 @@ func (f *ProcessFactory) FromStarter(env map[string]string, profileDir string) (
 		errChan:         f.errChan,
 		signalChan:      f.signalChan,
 		wg:              f.wg,
-	}, nil
+		output:          output,
+		metrics:         f.metrics,
+		events:          f.events,
+		startedChan:     f.startedChan,
+		waitFor:         f.appWaitFor,
+		stopChan:        f.stopChan,
+		exited:          make(chan struct{}),
+		removeChan:      make(chan struct{}),
+	}
+	f.addProcess(p)
+	return p, nil
+}
+
+// processWriters give stdout and stderr writers for a process which also write in given buffer
+// and send to loki if set and withLoki is true
+func (f *ProcessFactory) processWriters(name string, output *RingBuffer, withLoki bool) (stdout io.Writer, stderr io.Writer) {
+	stdouts := []io.Writer{f.stdout}
+	stderrs := []io.Writer{f.stderr}
+	if f.bufferSize > 0 {
+		stdouts = append(stdouts, output)
+		stderrs = append(stderrs, output)
+	}
+	if withLoki && f.lokiPusher != nil {
+		stdouts = append(stdouts, f.lokiPusher.Writer(name, "out"))
+		stderrs = append(stderrs, f.lokiPusher.Writer(name, "err"))
+	}
+	if len(stdouts) == 1 {
+		return f.stdout, f.stderr
+	}
+	return io.MultiWriter(stdouts...), io.MultiWriter(stderrs...)
 }
 
 func (f *ProcessFactory) FromSidecar(sidecar *config.Sidecar, env map[string]string) (*process, error) {
@@ -103,50 +283,189 @@ func (f *ProcessFactory) FromSidecar(sidecar *config.Sidecar, env map[string]str
 	if _, err := os.Stat(wd); os.IsNotExist(err) {
 		return nil, fmt.Errorf("Workdir '%s' doesn't exists.", wd)
 	}
+	env, err = f.tmpDirEnv(sidecar, env)
+	if err != nil {
+		return nil, err
+	}
 
-	args, err := TemplatingArgs(env, sidecar.Args...)
+	output := NewRingBuffer(f.bufferSize)
+	rebuild := func() (*exec.Cmd, CmdHandler, error) {
+		return f.sidecarCmdHandler(sidecar, env, wd, output)
+	}
+	cmd, cmdHandler, err := rebuild()
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.Command(SidecarExecPath(f.wd, sidecar), args...)
+	p := &process{
+		cmd:              cmd,
+		cmdHandler:       cmdHandler,
+		name:             sidecar.Name,
+		typeP:            "sidecar",
+		group:            sidecar.Group,
+		forwardSignals:   sidecar.ForwardSignals,
+		ignoreStopSignal: sidecar.IgnoreStopSignalForwarding,
+		isRproxy:         sidecar.IsRproxy,
+		noInterrupt:      sidecar.NoInterruptWhenStop,
+		errChan:          f.errChan,
+		signalChan:       f.signalChan,
+		wg:               f.wg,
+		output:           output,
+		metrics:          f.metrics,
+		events:           f.events,
+		startedChan:      f.startedChan,
+		waitFor:          sidecar.WaitFor,
+		startDelay:       time.Duration(sidecar.StartDelay) * time.Second,
+		stopChan:         f.stopChan,
+		exited:           make(chan struct{}),
+		removeChan:       make(chan struct{}),
+		rebuild:          rebuild,
+	}
+	if f.coreDumps != nil {
+		p.collectCoreDump = func(cmd *exec.Cmd, startedAt time.Time) {
+			f.coreDumps.collect(sidecar.Name, cmd, startedAt)
+		}
+	}
+	if sidecar.Drain != nil {
+		p.drain = func() error {
+			return f.runDrain(sidecar, env, wd)
+		}
+	}
+	f.addProcess(p)
+	return p, nil
+}
+
+// sidecarCmdHandler create command of a sidecar with its output pipeline
+func (f *ProcessFactory) sidecarCmdHandler(sidecar *config.Sidecar, env map[string]string, wd string, output *RingBuffer) (*exec.Cmd, CmdHandler, error) {
+	cmd, err := f.sidecarCmd(sidecar, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, err = withCapabilities(cmd, sidecar)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, err = withIsolation(cmd, sidecar)
+	if err != nil {
+		return nil, nil, err
+	}
+	cmd, err = withExecSetup(cmd, sidecar)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sidecar.IsBuiltin() {
+		env, err = builtinEnv(sidecar, env)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	env, err = execSetupEnv(sidecar, env)
+	if err != nil {
+		return nil, nil, err
+	}
 	cmd.Env = utils.EnvMapToOsEnv(env)
 	cmd.Dir = wd
-	// set pgid for sending signal to child
-	cmd.SysProcAttr = utils.PgidSysProcAttr(nil)
-	if !sidecar.NoLogPrefix {
-		writerPrefix := fmt.Sprintf("[sidecar:%s]", sidecar.Name)
-		err := PrefixCmdOutput(f.stdout, f.stderr, cmd, writerPrefix)
+	if sidecar.HasOwnProcessGroup() {
+		// set pgid for sending signal to child
+		cmd.SysProcAttr = utils.PgidSysProcAttr(cmd.SysProcAttr)
+	}
+	hasOutputLimits := sidecar.MaxLogLinesPerSec > 0 || sidecar.MaxLineBytes > 0
+	prefixed := !sidecar.NoLogPrefix || hasOutputLimits
+	// lines are sent to loki without prefix as sidecar name, stream and time are given by labels and entry
+	stdout, stderr := f.processWriters(sidecar.Name, output, !prefixed)
+	if prefixed {
+		// output goes through prefix pipeline without prefix when only limits are needed
+		writerPrefix := ""
+		prefixOpts := PrefixOptions{}
+		if !sidecar.NoLogPrefix {
+			writerPrefix = fmt.Sprintf("[sidecar:%s]", sidecar.Name)
+			prefixOpts = f.prefixOpts
+		}
+		prefixOpts.MaxLinesPerSec = sidecar.MaxLogLinesPerSec
+		prefixOpts.MaxLineBytes = sidecar.MaxLineBytes
+		if f.lokiPusher != nil {
+			prefixOpts.RawStdout = f.lokiPusher.Writer(sidecar.Name, "out")
+			prefixOpts.RawStderr = f.lokiPusher.Writer(sidecar.Name, "err")
+		}
+		err := PrefixCmdOutput(stdout, stderr, cmd, writerPrefix, prefixOpts)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	} else {
-		cmd.Stdout = f.stdout
-		cmd.Stderr = f.stderr
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 	}
 	cmdHandler, err := f.cmdFactory(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cmd, cmdHandler, nil
+}
+
+func (f *ProcessFactory) sidecarCmd(sidecar *config.Sidecar, env map[string]string) (*exec.Cmd, error) {
+	if sidecar.IsBuiltin() {
+		return builtinCmd(sidecar)
+	}
+	args, err := TemplatingArgs(env, sidecar.Args...)
+	if err != nil {
+		return nil, err
+	}
+	if sidecar.Command != nil && sidecar.Command.Shell {
+		// script is not templated, shell already expand env vars by itself
+		// sidecar name is given as $0 and args as positional parameters
+		shellArgs := append([]string{"-c", sidecar.Command.Args[0], sidecar.Name}, args...)
+		return exec.Command("sh", shellArgs...), nil
+	}
+	execPath, err := ResolveSidecarExecPath(f.wd, sidecar)
 	if err != nil {
 		return nil, err
 	}
-	return &process{
-		cmd:         cmd,
-		cmdHandler:  cmdHandler,
-		name:        sidecar.Name,
-		typeP:       "sidecar",
-		noInterrupt: sidecar.NoInterruptWhenStop,
-		errChan:     f.errChan,
-		signalChan:  f.signalChan,
-		wg:          f.wg,
-	}, nil
+	if sidecar.Command != nil {
+		cmdArgs, err := TemplatingArgs(env, append([]string{}, sidecar.Command.Args[1:]...)...)
+		if err != nil {
+			return nil, err
+		}
+		args = append(cmdArgs, args...)
+	}
+	return exec.Command(execPath, args...), nil
 }
 
 func SidecarExecPath(origWd string, sidecar *config.Sidecar) string {
-	execPath := sidecar.Executable
+	execPath := sidecar.ExecutableName()
+	if execPath == "" {
+		return ""
+	}
 	wd := origWd
 	if wd == "" {
 		wd, _ = os.Getwd()
 	}
 	if sidecar.ArtifactURI != "" {
-		execPath = filepath.Join(SidecarDir(wd, sidecar.Name), execPath)
+		execPath = filepath.Join(SidecarInstallDir(wd, sidecar), execPath)
 	}
 	return execPath
 }
+
+// ResolveSidecarExecPath give sidecar executable path where glob pattern (e.g.: bin/agent-*) is resolved,
+// pattern must match exactly one file
+func ResolveSidecarExecPath(origWd string, sidecar *config.Sidecar) (string, error) {
+	execPath := SidecarExecPath(origWd, sidecar)
+	if execPath == "" {
+		return "", fmt.Errorf("Sidecar has no executable, it runs through a shell")
+	}
+	if !strings.ContainsAny(execPath, "*?[") {
+		return execPath, nil
+	}
+	matches, err := filepath.Glob(execPath)
+	if err != nil {
+		return "", fmt.Errorf("Invalid executable pattern '%s': %s", sidecar.ExecutableName(), err.Error())
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("No executable found matching pattern '%s'", sidecar.ExecutableName())
+	}
+	if len(matches) > 1 {
+		return "", fmt.Errorf(
+			"Executable pattern '%s' is ambiguous, it matches: %s",
+			sidecar.ExecutableName(), strings.Join(matches, ", "),
+		)
+	}
+	return matches[0], nil
+}
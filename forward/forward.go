@@ -0,0 +1,136 @@
+// Package forward is the tcp forwarder embedded in cloud-sidecars.
+//
+// Like package rproxy, launcher runs it as a sidecar process by executing its own binary with Command as first argument
+// and config given in ConfigEnvKey env var.
+package forward
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// Command is first argument given to launcher binary to run builtin forwarder
+	Command = "builtin-forward"
+	// ConfigEnvKey is env var giving config of builtin forwarder as json
+	ConfigEnvKey = "SIDECARS_BUILTIN_FORWARD"
+
+	dialTimeout = 10 * time.Second
+	defaultHost = "127.0.0.1"
+)
+
+// RunFromEnv forward connections on local port to remote address until SIGTERM or SIGINT is received
+func RunFromEnv() error {
+	conf := config.BuiltinForward{}
+	err := json.Unmarshal([]byte(os.Getenv(ConfigEnvKey)), &conf)
+	if err != nil {
+		return fmt.Errorf("Invalid builtin forward config: %s", err.Error())
+	}
+	err = conf.Check()
+	if err != nil {
+		return err
+	}
+	dial, err := dialer(conf)
+	if err != nil {
+		return err
+	}
+	host := conf.Host
+	if host == "" {
+		host = defaultHost
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(conf.Port)))
+	if err != nil {
+		return err
+	}
+	entry := log.WithField("component", "forward")
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigs
+		entry.Info("Stopping builtin forwarder ...")
+		ln.Close()
+	}()
+
+	entry.Infof("Builtin forwarder listening on %s and forwarding to %s", ln.Addr(), conf.Remote)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			// listener is only closed when stopping
+			return nil
+		}
+		go forward(conn, dial, entry)
+	}
+}
+
+// dialer give function connecting to remote address, with tls if set in config
+func dialer(conf config.BuiltinForward) (func() (net.Conn, error), error) {
+	netDialer := &net.Dialer{Timeout: dialTimeout}
+	if conf.TLS == nil {
+		return func() (net.Conn, error) {
+			return netDialer.Dial("tcp", conf.Remote)
+		}, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         conf.TLS.ServerName,
+		InsecureSkipVerify: conf.TLS.InsecureSkipVerify,
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(conf.Remote)
+	}
+	if conf.TLS.CaFile != "" {
+		b, err := ioutil.ReadFile(conf.TLS.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read CA of builtin forwarder: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("No certificate found in CA file %s", conf.TLS.CaFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return func() (net.Conn, error) {
+		return tls.DialWithDialer(netDialer, "tcp", conf.Remote, tlsConfig)
+	}, nil
+}
+
+func forward(conn net.Conn, dial func() (net.Conn, error), entry *log.Entry) {
+	defer conn.Close()
+	remote, err := dial()
+	if err != nil {
+		entry.Warnf("Could not forward connection: %s", err.Error())
+		return
+	}
+	defer remote.Close()
+	done := make(chan struct{})
+	go func() {
+		io.Copy(remote, conn)
+		closeWrite(remote)
+		close(done)
+	}()
+	io.Copy(conn, remote)
+	closeWrite(conn)
+	<-done
+}
+
+// closeWrite close writing side of connection to let other side know that nothing more will be sent
+func closeWrite(conn net.Conn) {
+	switch c := conn.(type) {
+	case *net.TCPConn:
+		c.CloseWrite()
+	case *tls.Conn:
+		c.CloseWrite()
+	}
+}
@@ -0,0 +1,82 @@
+package sidecars
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const forwarderDialTimeout = 5 * time.Second
+
+// portForwarder listen on port of a reverse proxy sidecar and forward connections to port where
+// current instance of sidecar listen, target can be switched to a new instance without closing listening port
+type portForwarder struct {
+	listener net.Listener
+	target   int64
+}
+
+func listenForwarder(port, target int) (*portForwarder, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	f := &portForwarder{
+		listener: ln,
+		target:   int64(target),
+	}
+	go f.serve()
+	return f, nil
+}
+
+// Target give port where connections are forwarded
+func (f *portForwarder) Target() int {
+	return int(atomic.LoadInt64(&f.target))
+}
+
+// SetTarget forward next connections to port, current connections are kept on previous port
+func (f *portForwarder) SetTarget(port int) {
+	atomic.StoreInt64(&f.target, int64(port))
+}
+
+func (f *portForwarder) Close() error {
+	return f.listener.Close()
+}
+
+func (f *portForwarder) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.forward(conn)
+	}
+}
+
+func (f *portForwarder) forward(conn net.Conn) {
+	defer conn.Close()
+	upstream, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", f.Target()), forwarderDialTimeout)
+	if err != nil {
+		log.WithField("component", "forwarder").Warnf("Could not forward connection: %s", err.Error())
+		return
+	}
+	defer upstream.Close()
+	done := make(chan struct{})
+	go func() {
+		io.Copy(upstream, conn)
+		closeWrite(upstream)
+		close(done)
+	}()
+	io.Copy(conn, upstream)
+	closeWrite(conn)
+	<-done
+}
+
+// closeWrite close writing side of a tcp connection to let other side know that nothing more will be sent
+func closeWrite(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.CloseWrite()
+	}
+}
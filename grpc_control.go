@@ -0,0 +1,217 @@
+package sidecars
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/controlpb"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"os"
+	"time"
+)
+
+// grpcControlServer serve control api as a grpc service (see controlpb/control.proto) for clients in other languages,
+// it is only served when grpc_control_addr is set and use token and tls of json control api
+type grpcControlServer struct {
+	controlpb.UnimplementedControlServer
+	sConfig  config.Sidecars
+	pFactory *ProcessFactory
+	server   *grpc.Server
+	network  string
+	address  string
+	done     chan struct{}
+}
+
+func newGrpcControlServer(sConfig config.Sidecars, pFactory *ProcessFactory) *grpcControlServer {
+	network, address := splitControlAddr(sConfig.GrpcControlAddr)
+	return &grpcControlServer{
+		sConfig:  sConfig,
+		pFactory: pFactory,
+		network:  network,
+		address:  address,
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *grpcControlServer) Start() error {
+	token, err := ControlToken(s.sConfig)
+	if err != nil {
+		return err
+	}
+	if token == "" && s.network != "unix" {
+		return fmt.Errorf("A token must be set with %s env var or control_token_file when grpc control api listen on a tcp address", ControlTokenEnvKey)
+	}
+	tlsConfig, err := controlServerTLS(s.sConfig)
+	if err != nil {
+		return err
+	}
+	opts := make([]grpc.ServerOption, 0)
+	if token != "" {
+		opts = append(opts, grpc.UnaryInterceptor(grpcUnaryBearerAuth(token)), grpc.StreamInterceptor(grpcStreamBearerAuth(token)))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	listener, err := listenControl(s.network, s.address)
+	if err != nil {
+		return err
+	}
+	s.server = grpc.NewServer(opts...)
+	controlpb.RegisterControlServer(s.server, s)
+	go s.server.Serve(listener)
+	tlsMess := ""
+	if tlsConfig != nil {
+		tlsMess = " with tls"
+	}
+	log.WithField("component", "control").Infof("Grpc control api listening on %s://%s%s", s.network, s.address, tlsMess)
+	return nil
+}
+
+func (s *grpcControlServer) Stop() {
+	if s.server == nil {
+		return
+	}
+	// stop following logs to let server stop gracefully
+	close(s.done)
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		s.server.Stop()
+	}
+	if s.network == "unix" {
+		os.Remove(s.address)
+	}
+}
+
+func (s *grpcControlServer) Status(context.Context, *controlpb.StatusRequest) (*controlpb.StatusResponse, error) {
+	resp := &controlpb.StatusResponse{}
+	for _, p := range s.pFactory.Processes() {
+		resp.Processes = append(resp.Processes, grpcProcessStatus(p.Status()))
+	}
+	return resp, nil
+}
+
+func (s *grpcControlServer) Restart(_ context.Context, req *controlpb.RestartRequest) (*controlpb.RestartResponse, error) {
+	var processes []*process
+	switch {
+	case req.Name != "" && req.Group != "":
+		return nil, status.Error(codes.InvalidArgument, "Only one of name or group can be set")
+	case req.Name != "":
+		p := s.pFactory.ProcessByName(req.Name)
+		if p == nil {
+			return nil, status.Errorf(codes.NotFound, "Process %s not found", req.Name)
+		}
+		processes = []*process{p}
+	case req.Group != "":
+		processes = s.pFactory.ProcessesByGroup(req.Group)
+		if len(processes) == 0 {
+			return nil, status.Errorf(codes.NotFound, "Group %s not found", req.Group)
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Name or group must be set")
+	}
+	restarted, err := restartProcesses(processes)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &controlpb.RestartResponse{Restarted: restarted}, nil
+}
+
+// Logs send last output of process and, when follow is set, its output as it is written until client disconnects
+func (s *grpcControlServer) Logs(req *controlpb.LogsRequest, stream controlpb.Control_LogsServer) error {
+	p := s.pFactory.ProcessByName(req.Name)
+	if p == nil {
+		return status.Errorf(codes.NotFound, "Process %s not found", req.Name)
+	}
+	if !req.Follow || p.output == nil {
+		return stream.Send(&controlpb.LogsChunk{Data: p.Output()})
+	}
+	last, output, unsubscribe := p.output.Subscribe()
+	defer unsubscribe()
+	err := stream.Send(&controlpb.LogsChunk{Data: last})
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case b := <-output:
+			err := stream.Send(&controlpb.LogsChunk{Data: b})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+func grpcProcessStatus(st ProcessStatus) *controlpb.ProcessStatus {
+	pst := &controlpb.ProcessStatus{
+		Name:            st.Name,
+		Type:            st.Type,
+		Group:           st.Group,
+		State:           st.State,
+		Pid:             int64(st.Pid),
+		Signal:          st.Signal,
+		Error:           st.Error,
+		PeakMemoryBytes: st.PeakMemory,
+	}
+	if st.StartedAt != nil {
+		pst.StartedAt = timestamppb.New(*st.StartedAt)
+	}
+	if st.ExitedAt != nil {
+		pst.ExitedAt = timestamppb.New(*st.ExitedAt)
+	}
+	if st.ExitCode != nil {
+		exitCode := int64(*st.ExitCode)
+		pst.ExitCode = &exitCode
+	}
+	return pst
+}
+
+func grpcUnaryBearerAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := checkGrpcToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func grpcStreamBearerAuth(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := checkGrpcToken(stream.Context(), token)
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// checkGrpcToken only let pass calls with token in authorization metadata as a bearer token
+func checkGrpcToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	given, ok := "", false
+	if values := md.Get("authorization"); len(values) > 0 {
+		given, ok = bearerToken(values[0])
+	}
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package sidecars
+
+import (
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HttpValidators are http headers given by server to know if an artifact has changed since it has been downloaded
+type HttpValidators struct {
+	ETag         string `yaml:"etag,omitempty"`
+	LastModified string `yaml:"last_modified,omitempty"`
+}
+
+func (v HttpValidators) IsEmpty() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+var httpCacheClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// isHttpArtifact check that artifact is downloaded by zipper http handler
+func isHttpArtifact(sidecar *config.Sidecar) bool {
+	if !zipper.IsWebURL(sidecar.ArtifactURI) {
+		return false
+	}
+	s, err := ZipperSess(sidecar.ArtifactURI, sidecar.ArtifactType)
+	if err != nil {
+		return false
+	}
+	return s.Handler().Name() == "http"
+}
+
+// FetchHttpValidators retrieve etag and last modified date of an http artifact,
+// empty validators are given if artifact is not an http one or if server does not give them
+func FetchHttpValidators(sidecar *config.Sidecar) HttpValidators {
+	if !isHttpArtifact(sidecar) {
+		return HttpValidators{}
+	}
+	resp, err := httpHead(sidecar.ArtifactURI, HttpValidators{})
+	if err != nil {
+		return HttpValidators{}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return HttpValidators{}
+	}
+	return HttpValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// IsNotModified send a conditional request with given validators and return true if server answer 304
+func IsNotModified(sidecar *config.Sidecar, validators HttpValidators) (bool, error) {
+	if validators.IsEmpty() || !isHttpArtifact(sidecar) {
+		return false, nil
+	}
+	resp, err := httpHead(sidecar.ArtifactURI, validators)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusNotModified, nil
+}
+
+func httpHead(uri string, validators HttpValidators) (*http.Response, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && user.Username() != "" {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+	return httpCacheClient.Do(req)
+}
+
+// installedNotModified check if sidecar is already installed from same artifact which has not changed on server
+func (l Launcher) installedNotModified(sidecar *config.Sidecar) (bool, error) {
+	lock, ok := l.locker.Lock(sidecar)
+	if !ok || lock.HttpValidators.IsEmpty() {
+		return false, nil
+	}
+	if lock.Uri != sidecar.ArtifactURI || lock.Sha1 != sidecar.ArtifactSha1 {
+		return false, nil
+	}
+	dir := SidecarInstallDir(l.sConfig.Dir, sidecar)
+	if _, err := os.Stat(dir); err != nil {
+		return false, nil
+	}
+	checksum, err := DirChecksum(dir)
+	if err != nil || checksum != lock.Checksum {
+		return false, nil
+	}
+	return IsNotModified(sidecar, lock.HttpValidators)
+}
@@ -5,13 +5,17 @@ import (
 	"github.com/orange-cloudfoundry/cloud-sidecars/config"
 	"gopkg.in/yaml.v2"
 	"os"
+	"path/filepath"
 )
 
 type Index struct {
-	Name    string `yaml:"name"`
-	ZipFile string `yaml:"zip_file"`
-	Uri     string `yaml:"uri"`
-	Sha1    string `yaml:"sha1"`
+	Name     string `yaml:"name"`
+	ZipFile  string `yaml:"zip_file"`
+	Uri      string `yaml:"uri"`
+	Sha1     string `yaml:"sha1"`
+	Checksum string `yaml:"checksum"`
+
+	HttpValidators `yaml:",inline"`
 }
 
 func (i Index) IsDiff(sha1 string) bool {
@@ -102,18 +106,24 @@ func (i Indexer) Indexes() []Index {
 	return idxs
 }
 
-func (i *Indexer) UpdateOrCreateIndex(sidecar *config.Sidecar, zipFile string) error {
+func (i *Indexer) UpdateOrCreateIndex(sidecar *config.Sidecar, zipFile, checksum string, validators HttpValidators) error {
 	index := Index{
-		Name:    sidecar.Name,
-		Sha1:    sidecar.ArtifactSha1,
-		Uri:     sidecar.ArtifactURI,
-		ZipFile: zipFile,
+		Name:           sidecar.Name,
+		Sha1:           sidecar.ArtifactSha1,
+		Uri:            sidecar.ArtifactURI,
+		ZipFile:        zipFile,
+		Checksum:       checksum,
+		HttpValidators: validators,
 	}
 	i.indexes[sidecar.Name] = index
 	return nil
 }
 
 func (i *Indexer) Store() error {
+	err := os.MkdirAll(filepath.Dir(i.indexFile), 0755)
+	if err != nil {
+		return err
+	}
 	f, err := os.Create(i.indexFile)
 	if err != nil {
 		return err
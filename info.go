@@ -0,0 +1,121 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"os"
+	"strconv"
+)
+
+const (
+	DownloadStateNone    = "-"
+	DownloadStatePresent = "present"
+	DownloadStateMissing = "missing"
+	DownloadStateStale   = "stale"
+)
+
+type SidecarInfo struct {
+	Name          string `json:"name"`
+	ArtifactType  string `json:"artifact_type"`
+	ArtifactURI   string `json:"artifact_uri"`
+	IsRproxy      bool   `json:"is_rproxy"`
+	Port          int    `json:"port,omitempty"`
+	ProxyAppPort  int    `json:"proxy_app_port,omitempty"`
+	HasProfileD   bool   `json:"has_profiled"`
+	DownloadState string `json:"download_state"`
+}
+
+// SidecarsInfo give details on each sidecar, ports are computed as it would be on launch
+func (l Launcher) SidecarsInfo() []SidecarInfo {
+	infos := make([]SidecarInfo, len(l.sConfig.Sidecars))
+	appPort := l.appPort
+	for i, sidecar := range l.sConfig.Sidecars {
+		info := SidecarInfo{
+			Name:          sidecar.Name,
+			ArtifactType:  sidecar.ArtifactType,
+			ArtifactURI:   sidecar.ArtifactURI,
+			IsRproxy:      sidecar.IsRproxy,
+			HasProfileD:   sidecar.ProfileD != "",
+			DownloadState: l.downloadState(sidecar),
+		}
+		if info.ArtifactType == "" && info.ArtifactURI != "" {
+			if h, err := zipper.FindHandler(sidecar.ArtifactURI, ""); err == nil {
+				info.ArtifactType = h.Name()
+			}
+		}
+		if sidecar.IsRproxy {
+			info.Port = appPort
+			appPort++
+			info.ProxyAppPort = appPort
+		}
+		infos[i] = info
+	}
+	return infos
+}
+
+func (l Launcher) downloadState(sidecar *config.Sidecar) string {
+	if sidecar.ArtifactURI == "" {
+		return DownloadStateNone
+	}
+	if index, ok := l.indexer.Index(sidecar); ok {
+		if index.Uri != sidecar.ArtifactURI || index.IsDiff(sidecar.ArtifactSha1) {
+			return DownloadStateStale
+		}
+		return DownloadStatePresent
+	}
+	// index is removed once sidecar is installed, lock keeps artifact it has been installed from
+	if lock, ok := l.locker.Lock(sidecar); ok && (lock.Uri != sidecar.ArtifactURI || lock.Sha1 != sidecar.ArtifactSha1) {
+		return DownloadStateStale
+	}
+	if sidecar.ExecutableName() == "" {
+		if _, err := os.Stat(SidecarInstallDir(l.sConfig.Dir, sidecar)); err == nil {
+			return DownloadStatePresent
+		}
+		return DownloadStateMissing
+	}
+	execPath, err := ResolveSidecarExecPath(l.sConfig.Dir, sidecar)
+	if err != nil {
+		return DownloadStateMissing
+	}
+	if _, err := os.Stat(execPath); err == nil {
+		return DownloadStatePresent
+	}
+	return DownloadStateMissing
+}
+
+// ShowSidecarsInfo print sidecars details as a table or as json if asJson is true
+func (l Launcher) ShowSidecarsInfo(asJson bool) error {
+	infos := l.SidecarsInfo()
+	if asJson {
+		enc := json.NewEncoder(l.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	table := newTable(l.stdout)
+	table.SetHeader([]string{"Name", "Artifact Type", "Artifact URI", "Rproxy", "Ports", "ProfileD", "Download State"})
+	for _, info := range infos {
+		ports := "-"
+		if info.IsRproxy {
+			ports = fmt.Sprintf("%d -> %d", info.Port, info.ProxyAppPort)
+		}
+		artifactType := info.ArtifactType
+		artifactURI := info.ArtifactURI
+		if artifactURI == "" {
+			artifactType = "-"
+			artifactURI = "-"
+		}
+		table.Append([]string{
+			info.Name,
+			artifactType,
+			artifactURI,
+			strconv.FormatBool(info.IsRproxy),
+			ports,
+			strconv.FormatBool(info.HasProfileD),
+			info.DownloadState,
+		})
+	}
+	table.Render()
+	return nil
+}
@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package sidecars
+
+import (
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reapInterval is time between two scans of orphan zombies,
+// a zombie is only reaped when it is seen in two scans to let exec wait for its own children
+const reapInterval = time.Second
+
+// isInit check if launcher runs as pid 1 (e.g.: as container entrypoint)
+func isInit() bool {
+	return os.Getpid() == 1
+}
+
+// setSubreaper make orphan descendants of launcher be reparented to it instead of pid 1
+func setSubreaper() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
+}
+
+// reapOrphans reap zombie children of launcher which are not waited by anyone until done is closed,
+// this happens for orphans reparented to launcher when it is pid 1 or a subreaper
+func reapOrphans(done chan struct{}) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	seen := make(map[int]bool)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		zombies := make(map[int]bool)
+		for _, pid := range zombieChildren() {
+			if !seen[pid] {
+				zombies[pid] = true
+				continue
+			}
+			var status unix.WaitStatus
+			unix.Wait4(pid, &status, unix.WNOHANG, nil)
+		}
+		seen = zombies
+	}
+}
+
+// zombieChildren give pids of zombie children of launcher
+func zombieChildren() []int {
+	self := os.Getpid()
+	pids := make([]int, 0)
+	dirs, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return pids
+	}
+	for _, statFile := range dirs {
+		b, err := ioutil.ReadFile(statFile)
+		if err != nil {
+			continue
+		}
+		// format is: pid (comm) state ppid ..., comm can contain spaces and parenthesis
+		stat := string(b)
+		i := strings.LastIndex(stat, ")")
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(stat[i+1:])
+		if len(fields) < 2 || fields[0] != "Z" {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil || ppid != self {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(statFile)))
+		if err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
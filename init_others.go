@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package sidecars
+
+import (
+	"fmt"
+)
+
+// isInit is only detected on linux
+func isInit() bool {
+	return false
+}
+
+func setSubreaper() error {
+	return fmt.Errorf("Subreaper is only supported on linux")
+}
+
+func reapOrphans(done chan struct{}) {}
@@ -0,0 +1,92 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"strconv"
+)
+
+const (
+	InstanceIndexEnvKey = "INSTANCE_INDEX"
+	InstancePortEnvKey  = "INSTANCE_PORT"
+)
+
+// sidecarInstances give a sidecar for each instance of sidecar, instances are named <name>-<index>
+// and share installation of sidecar
+func sidecarInstances(sidecar *config.Sidecar) []*config.Sidecar {
+	if sidecar.Instances <= 1 {
+		return []*config.Sidecar{sidecar}
+	}
+	instances := make([]*config.Sidecar, sidecar.Instances)
+	for i := range instances {
+		instance := *sidecar
+		instance.Name = instanceName(sidecar, i)
+		instance.InstallDir = sidecarInstallRelDir(sidecar)
+		instanceEnv := map[string]string{
+			InstanceIndexEnvKey: strconv.Itoa(i),
+		}
+		if sidecar.InstanceBasePort > 0 {
+			instanceEnv[InstancePortEnvKey] = strconv.Itoa(sidecar.InstanceBasePort + i)
+		}
+		instance.Env = utils.MergeEnv(sidecar.Env, instanceEnv)
+		instances[i] = &instance
+	}
+	return instances
+}
+
+// instanceEnv give env vars set by launcher on an instance of sidecar
+func instanceEnv(instance *config.Sidecar) map[string]string {
+	env := make(map[string]string)
+	for _, key := range []string{InstanceIndexEnvKey, InstancePortEnvKey} {
+		if v, ok := instance.Env[key]; ok {
+			env[key] = v
+		}
+	}
+	return env
+}
+
+func instanceName(sidecar *config.Sidecar, index int) string {
+	return sidecar.InstanceName(index)
+}
+
+// sidecarOfProcess give sidecar of a process which can be an instance of sidecar
+func sidecarOfProcess(sidecars []*config.Sidecar, name string) *config.Sidecar {
+	for _, sidecar := range sidecars {
+		if sidecar.Instances <= 1 {
+			if sidecar.Name == name {
+				return sidecar
+			}
+			continue
+		}
+		for i := 0; i < sidecar.Instances; i++ {
+			if instanceName(sidecar, i) == name {
+				return sidecar
+			}
+		}
+	}
+	return nil
+}
+
+// instancesLen give number of processes to launch for sidecars
+func instancesLen(sidecars []*config.Sidecar) int {
+	n := 0
+	for _, sidecar := range sidecars {
+		if sidecar.Instances > 1 {
+			n += sidecar.Instances
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+// sidecarProcesses give launched processes of all instances of sidecar
+func (l Launcher) sidecarProcesses(sidecar *config.Sidecar) []*process {
+	processes := make([]*process, 0)
+	for _, instance := range sidecarInstances(sidecar) {
+		if p := l.processFactory.ProcessByName(instance.Name); p != nil {
+			processes = append(processes, p)
+		}
+	}
+	return processes
+}
@@ -0,0 +1,45 @@
+// Package isolation prepares linux namespaces created by launcher for a sidecar process before executing it,
+// capabilities of sidecar are also dropped there once namespaces are set up.
+//
+// Like package capabilities, launcher executes its own binary in new namespaces with Command as first argument
+// followed by executable and args of sidecar, config is given in ConfigEnvKey env var. Programs embedding launcher
+// must call ExecFromEnv with remaining args when they are executed with Command as first argument to use isolation of sidecars.
+package isolation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+const (
+	// Command is first argument given to launcher binary to execute a sidecar in new namespaces
+	Command = "exec-isolated"
+	// ConfigEnvKey is env var giving isolation of sidecar as json
+	ConfigEnvKey = "SIDECARS_ISOLATION"
+)
+
+// ExecFromEnv set up namespaces as described by ConfigEnvKey env var (e.g.: mount a private /tmp)
+// and replace current process by executable args[0] with args[1:] as arguments, it only returns on error
+func ExecFromEnv(args []string) error {
+	// ambient capabilities are cleared for calling thread only, exec must happen on the same thread,
+	// thread is never unlocked as current process is replaced or exits on error
+	runtime.LockOSThread()
+	if len(args) == 0 {
+		return fmt.Errorf("Executable to run isolated must be given")
+	}
+	conf := config.Isolation{}
+	err := json.Unmarshal([]byte(os.Getenv(ConfigEnvKey)), &conf)
+	if err != nil {
+		return fmt.Errorf("Invalid isolation config: %s", err.Error())
+	}
+	os.Unsetenv(ConfigEnvKey)
+	execPath, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return execIsolated(conf, execPath, args)
+}
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package isolation
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/capabilities"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// execIsolated prepare namespaces where launcher started current process and execute sidecar
+func execIsolated(conf config.Isolation, execPath string, args []string) error {
+	if conf.PrivateTmp {
+		err := mountPrivateTmp()
+		if err != nil {
+			return fmt.Errorf("Error when mounting private /tmp: %s", err.Error())
+		}
+	}
+	if conf.NoNetwork {
+		err := loopbackUp()
+		if err != nil {
+			return fmt.Errorf("Error when bringing up loopback: %s", err.Error())
+		}
+	}
+	if os.Geteuid() != 0 {
+		// ambient capabilities given by launcher in user namespace must not be kept by sidecar
+		err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0)
+		if err != nil {
+			return fmt.Errorf("Error when clearing ambient capabilities: %s", err.Error())
+		}
+	}
+	if _, ok := os.LookupEnv(capabilities.ConfigEnvKey); ok {
+		// capabilities are dropped once namespaces are set up as it requires them
+		return capabilities.ExecFromEnv(append([]string{execPath}, args[1:]...))
+	}
+	return unix.Exec(execPath, args, os.Environ())
+}
+
+// mountPrivateTmp mount an empty tmpfs on /tmp, mounts are first made private to not propagate it to launcher
+func mountPrivateTmp() error {
+	err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, "")
+	if err != nil {
+		return err
+	}
+	return unix.Mount("tmpfs", "/tmp", "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+}
+
+// loopbackUp bring up loopback interface of new network namespace which starts down
+func loopbackUp() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	ifr, err := unix.NewIfreq("lo")
+	if err != nil {
+		return err
+	}
+	err = unix.IoctlIfreq(fd, unix.SIOCGIFFLAGS, ifr)
+	if err != nil {
+		return err
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP)
+	return unix.IoctlIfreq(fd, unix.SIOCSIFFLAGS, ifr)
+}
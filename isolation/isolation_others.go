@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package isolation
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"runtime"
+)
+
+// execIsolated is not supported on this platform
+func execIsolated(conf config.Isolation, execPath string, args []string) error {
+	return fmt.Errorf("Isolation is not supported on %s", runtime.GOOS)
+}
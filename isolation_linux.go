@@ -0,0 +1,32 @@
+//go:build linux
+// +build linux
+
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"golang.org/x/sys/unix"
+	"os"
+	"syscall"
+)
+
+// isolationSysProcAttr give attributes starting a process in new namespaces of isolation,
+// a user namespace mapping launcher user is also created when launcher is not root to be allowed to create them,
+// launcher binary setting them up then gets capabilities it needs in this namespace until it executes sidecar
+func isolationSysProcAttr(isolation config.Isolation) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{}
+	if isolation.PrivateTmp {
+		attr.Cloneflags |= syscall.CLONE_NEWNS
+	}
+	if isolation.NoNetwork {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if os.Geteuid() != 0 {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Geteuid(), HostID: os.Geteuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getegid(), HostID: os.Getegid(), Size: 1}}
+		attr.GidMappingsEnableSetgroups = false
+		attr.AmbientCaps = []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_NET_ADMIN}
+	}
+	return attr
+}
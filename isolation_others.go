@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"syscall"
+)
+
+// isolationSysProcAttr is not supported on this platform
+func isolationSysProcAttr(isolation config.Isolation) *syscall.SysProcAttr {
+	return nil
+}
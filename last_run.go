@@ -0,0 +1,85 @@
+package sidecars
+
+import (
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lastRunOutputLines is number of last output lines of each process kept in last run report
+const lastRunOutputLines = 50
+
+// RunReport is written in last_run.json when launcher stops to help debugging crashes
+// which are no more in platform logs
+type RunReport struct {
+	StartedAt time.Time          `json:"started_at"`
+	StoppedAt time.Time          `json:"stopped_at"`
+	Processes []ProcessRunReport `json:"processes"`
+}
+
+type ProcessRunReport struct {
+	ProcessStatus
+	Runs       int      `json:"runs"`
+	LastOutput []string `json:"last_output"`
+}
+
+func LastRunFilePath(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, "last_run.json")
+}
+
+// writeLastRun write report of processes in last run file
+func (l Launcher) writeLastRun(startedAt time.Time) error {
+	report := RunReport{
+		StartedAt: startedAt,
+		StoppedAt: time.Now(),
+		Processes: make([]ProcessRunReport, 0),
+	}
+	for _, p := range l.processFactory.Processes() {
+		report.Processes = append(report.Processes, ProcessRunReport{
+			ProcessStatus: p.Status(),
+			Runs:          p.Runs(),
+			LastOutput:    lastLines(string(p.Output()), lastRunOutputLines),
+		})
+	}
+	lastRunFile := LastRunFilePath(l.sConfig.Dir)
+	err := os.MkdirAll(filepath.Dir(lastRunFile), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(lastRunFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// logTotalPeakMemory log sum of peak memory of all processes, peaks may not happen at the same time
+// but memory quota of app must cover it to be safe
+func (l Launcher) logTotalPeakMemory() {
+	var total uint64
+	for _, p := range l.processFactory.Processes() {
+		total += p.Status().PeakMemory
+	}
+	if total == 0 {
+		return
+	}
+	log.WithField("component", "Launcher").
+		Infof("Sum of peak memory usage of all processes: %.1f MB", float64(total)/bytesPerMB)
+}
+
+func lastLines(output string, n int) []string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return []string{}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
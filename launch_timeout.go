@@ -0,0 +1,62 @@
+package sidecars
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"syscall"
+	"time"
+)
+
+// watchLaunchTimeout stop launcher with an error when app has not been started before launch_timeout,
+// all processes are watched when there is no app
+func (l Launcher) watchLaunchTimeout(processes []*process, done chan struct{}) {
+	watched := make([]*process, 0)
+	for _, p := range processes {
+		if p.typeP == "cloud" {
+			watched = append(watched, p)
+		}
+	}
+	if len(watched) == 0 {
+		watched = processes
+	}
+	timeout := time.Duration(l.sConfig.LaunchTimeout) * time.Second
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(startAfterInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-l.processFactory.stopChan:
+			return
+		case <-ticker.C:
+			if allStarted(watched) {
+				return
+			}
+		case <-deadline:
+			l.launchTimedOut(watched, timeout)
+			return
+		}
+	}
+}
+
+// launchTimedOut stop all processes and make launcher exit with an error
+func (l Launcher) launchTimedOut(watched []*process, timeout time.Duration) {
+	errMess := fmt.Sprintf("Launch has not finished after %s, stopping everything", timeout)
+	if len(watched) == 1 {
+		errMess = fmt.Sprintf("%s %s has not been started after %s, stopping everything", watched[0].typeP, watched[0].name, timeout)
+	}
+	log.WithField("component", "Launcher").Error(errMess)
+	l.processFactory.ErrorChan() <- errors.New(errMess)
+	l.processFactory.SignalChan() <- syscall.SIGINT
+}
+
+func allStarted(processes []*process) bool {
+	for _, p := range processes {
+		if p.Runs() == 0 {
+			return false
+		}
+	}
+	return true
+}
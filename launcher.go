@@ -1,8 +1,9 @@
 package sidecars
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/olekukonko/tablewriter"
 	"github.com/orange-cloudfoundry/cloud-sidecars/config"
 	"github.com/orange-cloudfoundry/cloud-sidecars/starter"
 	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
@@ -10,10 +11,13 @@ import (
 	"gopkg.in/alessio/shellescape.v1"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -21,7 +25,14 @@ import (
 const (
 	ProxyAppPortEnvKey = "PROXY_APP_PORT"
 	AppPortEnvKey      = "SIDECAR_APP_PORT"
-	PathSidecarsWd     = ".sidecars"
+	AppDirEnvKey       = "SIDECAR_APP_DIR"
+	SidecarDirEnvKey   = "SIDECAR_DIR"
+	SidecarNameEnvKey  = "SIDECAR_NAME"
+	// SidecarSecretsDirEnvKey is given to sidecars having secret files, see SidecarSecretsDir
+	SidecarSecretsDirEnvKey = "SIDECAR_SECRETS_DIR"
+	PathSidecarsWd          = ".sidecars"
+	// DefaultLogBufferSize is size in KB of last output kept for each process
+	DefaultLogBufferSize = 64
 )
 
 type Launcher struct {
@@ -32,7 +43,30 @@ type Launcher struct {
 	stderr         io.Writer
 	appPort        int
 	processFactory *ProcessFactory
+	metrics        *StatsdClient
+	events         *EventEmitter
+	initMode       bool
 	indexer        *Indexer
+	locker         *Locker
+	launched       *launchState
+	unsafeExtract  bool
+	refresh        bool
+	setupTimings   *setupTimings
+	timingsFormat  string
+	pprofAddr      string
+	streamInstall  bool
+	downloads      *sharedDownloads
+	version        string
+	configLoader   ConfigLoader
+	watchedConfig  string
+	reloadMu       *sync.Mutex
+	onlyNames      []string
+	disabledNames  []string
+	loadedSidecars []*config.Sidecar
+	proxyEnvs      map[string]map[string]string
+	outputsEnv     map[string]string
+	extraPortsEnv  map[string]string
+	forwarders     map[string]*portForwarder
 }
 
 func NewLauncher(
@@ -52,6 +86,30 @@ func NewLauncher(
 	if appPort == 0 {
 		appPort = defaultAppPort
 	}
+	sConfig.Sidecars = platformSidecars(sConfig.Sidecars)
+	warnUnknownEnvKeys(sConfig)
+	processFactory := NewProcessFactory(stdout, stderr, cStarter, sConfig.Dir)
+	processFactory.SetPrefixOptions(PrefixOptions{
+		Timestamp: sConfig.LogTimestamp,
+		StreamTag: sConfig.LogStreamTag,
+	})
+	logBufferSize := sConfig.LogBufferSize
+	// config may not have been checked when launcher is used as a library
+	if logBufferSize <= 0 {
+		logBufferSize = DefaultLogBufferSize
+	}
+	processFactory.SetOutputBufferSize(logBufferSize * 1024)
+	processFactory.SetAppWaitFor(sConfig.AppWaitFor)
+	processFactory.SetAppCommand(sConfig.AppCommand)
+	var metrics *StatsdClient
+	if sConfig.Statsd != nil && sConfig.Statsd.Address != "" {
+		var err error
+		metrics, err = NewStatsdClient(*sConfig.Statsd)
+		if err != nil {
+			log.WithField("component", "statsd").Warnf("Metrics will not be sent: %s", err.Error())
+		}
+		processFactory.SetMetrics(metrics)
+	}
 	return &Launcher{
 		sConfig:        sConfig,
 		cStarter:       cStarter,
@@ -59,35 +117,170 @@ func NewLauncher(
 		stdout:         stdout,
 		stderr:         stderr,
 		appPort:        appPort,
-		processFactory: NewProcessFactory(stdout, stderr, cStarter, sConfig.Dir),
+		processFactory: processFactory,
 		indexer:        NewIndexer(IndexFilePath(sConfig.Dir)),
+		locker:         NewLocker(LockFilePath(sConfig.Dir)),
+		metrics:        metrics,
+		reloadMu:       &sync.Mutex{},
+		proxyEnvs:      make(map[string]map[string]string),
+		outputsEnv:     make(map[string]string),
+		extraPortsEnv:  make(map[string]string),
+		forwarders:     make(map[string]*portForwarder),
+	}
+}
+
+// Config give config used by launcher
+func (l Launcher) Config() config.Sidecars {
+	return l.sConfig
+}
+
+// SetInteractive forward stdin to app process, app is attached to a pseudo-terminal if tty is true
+func (l Launcher) SetInteractive(stdin io.Reader, tty bool) {
+	l.processFactory.SetAppStdin(stdin, tty)
+}
+
+// OnlySidecars keep only sidecars with given names or in given groups in sidecars to launch
+func (l *Launcher) OnlySidecars(names ...string) {
+	l.onlyNames = names
+	disabled := make([]string, 0)
+	for _, sidecar := range l.sConfig.Sidecars {
+		if !sidecarMatches(sidecar, names) {
+			disabled = append(disabled, sidecar.Name)
+		}
+	}
+	for _, name := range names {
+		if l.sConfig.SidecarByName(name) == nil && !l.sConfig.HasGroup(name) {
+			log.WithField("component", "Launcher").Warnf("Sidecar or group %s to launch does not exist", name)
+		}
+	}
+	l.DisableSidecars(disabled...)
+}
+
+// DisableSidecars remove sidecars with given names or in given groups from sidecars to launch
+func (l *Launcher) DisableSidecars(names ...string) {
+	if len(names) == 0 {
+		return
+	}
+	l.disabledNames = append(l.disabledNames, names...)
+	entry := log.WithField("component", "Launcher")
+	for _, name := range names {
+		if l.sConfig.SidecarByName(name) == nil && !l.sConfig.HasGroup(name) {
+			entry.Warnf("Sidecar or group %s to disable does not exist", name)
+		}
+	}
+	enabled := make([]*config.Sidecar, 0, len(l.sConfig.Sidecars))
+	for _, sidecar := range l.sConfig.Sidecars {
+		if sidecarMatches(sidecar, names) {
+			entry.Infof("Sidecar %s is disabled", sidecar.Name)
+			continue
+		}
+		enabled = append(enabled, sidecar)
 	}
+	l.sConfig.Sidecars = enabled
 }
 
-func (l Launcher) ShowSidecarsSha1() error {
-	table := tablewriter.NewWriter(l.stdout)
+// platformSidecars give sidecars which can run on current platform, other ones are skipped
+func platformSidecars(sidecars []*config.Sidecar) []*config.Sidecar {
+	supported := make([]*config.Sidecar, 0, len(sidecars))
+	for _, sidecar := range sidecars {
+		if !sidecar.SupportsPlatform() {
+			log.WithField("component", "Launcher").WithField("sidecar", sidecar.Name).Warnf(
+				"Skipping sidecar, it can only run on %s and current platform is %s",
+				strings.Join(sidecar.Platforms, ", "), config.CurrentPlatform(),
+			)
+			continue
+		}
+		supported = append(supported, sidecar)
+	}
+	return supported
+}
+
+// sidecarMatches check if sidecar name or group is in names
+func sidecarMatches(sidecar *config.Sidecar, names []string) bool {
+	return utils.InStrings(sidecar.Name, names) || (sidecar.Group != "" && utils.InStrings(sidecar.Group, names))
+}
+
+// ShowSidecarsSha1 print sha1 of artifacts for given sidecar names or all sidecars if no names given
+func (l Launcher) ShowSidecarsSha1(names ...string) error {
+	table := newTable(l.stdout)
 	table.SetHeader([]string{"Sidecar Name", "Sha1"})
 	for _, sidecar := range l.sConfig.Sidecars {
+		if len(names) > 0 && !utils.InStrings(sidecar.Name, names) {
+			continue
+		}
 		if sidecar.ArtifactURI == "" {
 			table.Append([]string{sidecar.Name, "-"})
 			continue
 		}
-		s, err := ZipperSess(sidecar.ArtifactURI, sidecar.ArtifactType)
+		sha1, err := l.artifactSha1(sidecar)
 		if err != nil {
-			return NewSidecarError(sidecar, err)
+			return err
+		}
+		table.Append([]string{sidecar.Name, sha1})
+	}
+	table.Render()
+	return nil
+}
+
+// PinSidecarsSha1 compute sha1 of artifacts for given sidecar names or all sidecars if no names given
+// and write them as artifact_sha1 in config file at confPath to enforce them on next setups
+func (l Launcher) PinSidecarsSha1(confPath string, names ...string) error {
+	pins := make([]*config.PinnedSha1, 0)
+	for _, sidecar := range l.sConfig.Sidecars {
+		if sidecar.ArtifactURI == "" || (len(names) > 0 && !utils.InStrings(sidecar.Name, names)) {
+			continue
 		}
-		sha1, err := s.Sha1()
+		sha1, err := l.artifactSha1(sidecar)
 		if err != nil {
-			return NewSidecarError(sidecar, err)
+			return err
 		}
-		table.Append([]string{sidecar.Name, sha1})
+		pins = append(pins, &config.PinnedSha1{Name: sidecar.Name, Sha1: sha1})
+	}
+	err := config.PinSidecarsSha1InFile(confPath, pins)
+	if err != nil {
+		return err
+	}
+	table := newTable(l.stdout)
+	table.SetHeader([]string{"Sidecar Name", "Sha1", "Status"})
+	for _, pin := range pins {
+		status := "pinned"
+		switch {
+		case pin.Skipped != "":
+			status = "skipped: " + pin.Skipped
+		case pin.Previous == pin.Sha1:
+			status = "unchanged"
+		case pin.Previous != "":
+			status = "changed from " + pin.Previous
+		}
+		table.Append([]string{pin.Name, pin.Sha1, status})
 	}
 	table.Render()
 	return nil
 }
 
+func (l Launcher) artifactSha1(sidecar *config.Sidecar) (string, error) {
+	s, err := ZipperSess(l.artifactSource(sidecar))
+	if err != nil {
+		return "", NewSidecarError(sidecar, err)
+	}
+	sha1, err := s.Sha1()
+	if err != nil {
+		return "", NewSidecarError(sidecar, err)
+	}
+	return sha1, nil
+}
+
 func (l Launcher) setupSidecarArtifact(sidecar *config.Sidecar) error {
 	entry := log.WithField("sidecar", sidecar.Name)
+	startExtract := time.Now()
+	if l.isLinkedArtifact(sidecar) {
+		err := l.linkLocalArtifact(sidecar)
+		if err != nil {
+			return NewSidecarError(sidecar, err)
+		}
+		l.setupTimings.record(sidecar.Name, SetupPhaseExtract, startExtract)
+		return l.afterInstallSidecar(sidecar, HttpValidators{})
+	}
 	entry.Debug("Unzipping artifact ...")
 	index, ok := l.indexer.Index(sidecar)
 	if !ok {
@@ -95,64 +288,159 @@ func (l Launcher) setupSidecarArtifact(sidecar *config.Sidecar) error {
 	}
 	zipFilePath := filepath.Join(l.sConfig.Dir, index.ZipFile)
 	uz := NewUnzip(zipFilePath, filepath.Dir(zipFilePath))
+	uz.StripComponents = sidecar.StripComponents
+	uz.Subpath = sidecar.ArtifactSubpath
+	uz.AllowUnsafe = l.unsafeExtract
 	err := uz.Extract()
 	if err != nil {
 		return NewSidecarError(sidecar, err)
 	}
+	l.setupTimings.record(sidecar.Name, SetupPhaseExtract, startExtract)
 	l.indexer.RemoveIndex(index)
 	err = l.indexer.Store()
 	if err != nil {
 		return err
 	}
 	entry.Debug("Finished unzipping artifact ...")
+	return l.afterInstallSidecar(sidecar, index.HttpValidators)
+}
 
-	if sidecar.AfterInstall == "" {
-		return nil
+// afterInstallSidecar run after install script and verify command of an installed sidecar and lock it
+func (l Launcher) afterInstallSidecar(sidecar *config.Sidecar, validators HttpValidators) error {
+	entry := log.WithField("sidecar", sidecar.Name)
+	if sidecar.AfterInstall == "" && sidecar.VerifyCommand == "" {
+		return l.lockSidecarArtifact(sidecar, validators)
 	}
 
-	entry.Debug("Run after install script ...")
-	env, err := OverrideEnv(utils.OsEnvToMap(), sidecar.Env)
+	env, err := l.installEnv(sidecar)
 	if err != nil {
 		return NewSidecarError(sidecar, err)
 	}
-	err = runScript(
-		sidecar.AfterInstall,
-		filepath.Dir(SidecarExecPath(l.sConfig.Dir, sidecar)),
-		utils.EnvMapToOsEnv(env),
-		l.stdout, l.stderr,
-	)
+	installWd := SidecarInstallDir(l.sConfig.Dir, sidecar)
+	// executable may be a glob pattern, install dir is kept when it does not match anything yet
+	if execPath, err := ResolveSidecarExecPath(l.sConfig.Dir, sidecar); err == nil {
+		installWd = filepath.Dir(execPath)
+	}
+	if sidecar.AfterInstall != "" {
+		entry.Debug("Run after install script ...")
+		script, err := TemplatingScript(env, sidecar.AfterInstall)
+		if err != nil {
+			return NewSidecarError(sidecar, err)
+		}
+		startScript := time.Now()
+		err = runScript(
+			script,
+			installWd,
+			utils.EnvMapToOsEnv(env),
+			l.stdout, l.stderr,
+			fmt.Sprintf("[after_install:%s]", sidecar.Name),
+			sidecar.ScriptTimeout(DefaultScriptTimeout),
+		)
+		if err != nil {
+			return NewSidecarError(sidecar, fmt.Errorf("After install script failed: %s", err.Error()))
+		}
+		l.setupTimings.record(sidecar.Name, SetupPhaseAfterInstall, startScript)
+		entry.Debug("Finished running after install script.")
+	}
+	if sidecar.VerifyCommand != "" {
+		entry.Infof("Verifying installation with '%s' ...", sidecar.VerifyCommand)
+		script, err := TemplatingScript(env, sidecar.VerifyCommand)
+		if err != nil {
+			return NewSidecarError(sidecar, err)
+		}
+		startVerify := time.Now()
+		err = runScript(
+			script,
+			installWd,
+			utils.EnvMapToOsEnv(env),
+			l.stdout, l.stderr,
+			fmt.Sprintf("[verify:%s]", sidecar.Name),
+			sidecar.ScriptTimeout(DefaultScriptTimeout),
+		)
+		if err != nil {
+			return NewSidecarError(sidecar, fmt.Errorf("Verify command '%s' failed: %s", sidecar.VerifyCommand, err.Error()))
+		}
+		l.setupTimings.record(sidecar.Name, SetupPhaseVerify, startVerify)
+		entry.Info("Finished verifying installation.")
+	}
+	return l.lockSidecarArtifact(sidecar, validators)
+}
+
+// installEnv give env of install scripts, it contains sidecar env and paths of app and sidecar
+func (l Launcher) installEnv(sidecar *config.Sidecar) (map[string]string, error) {
+	appDir, err := filepath.Abs(l.sConfig.Dir)
+	if err != nil {
+		return nil, err
+	}
+	env := utils.MergeEnv(utils.OsEnvToMap(), l.envKeyNames().forSidecar(sidecar).rename(map[string]string{
+		AppDirEnvKey:      appDir,
+		SidecarDirEnvKey:  SidecarInstallDir(appDir, sidecar),
+		SidecarNameEnvKey: sidecar.Name,
+	}))
+	return l.sidecarRefs().override(env, sidecar.Env)
+}
+
+func (l Launcher) lockSidecarArtifact(sidecar *config.Sidecar, validators HttpValidators) error {
+	checksum, err := DirChecksum(SidecarInstallDir(l.sConfig.Dir, sidecar))
 	if err != nil {
 		return NewSidecarError(sidecar, err)
 	}
-	entry.Debug("Finished running after install script.")
-	return nil
+	l.locker.UpdateOrCreateLock(sidecar, checksum, validators)
+	return l.locker.Store()
 }
 
 func (l Launcher) Setup() error {
 	entryG := log.WithField("component", "Launcher").WithField("command", "staging")
 	entryG.Infof("Setup sidecars ...")
+	l.setupTimings = newSetupTimings()
+	// http artifacts are installed while downloading, other ones are downloaded as zip and installed after
+	l.streamInstall = true
+	defer l.showSetupTimings()
 	appEnv := make(map[string]string)
 	err := os.MkdirAll(l.profileDir, 0755)
 	if err != nil {
 		return err
 	}
-	err = l.DownloadArtifacts()
+	var upToDate map[string]bool
+	if l.refresh {
+		upToDate, err = l.upToDateSidecars()
+		if err != nil {
+			return err
+		}
+	}
+	err = l.downloadArtifacts(upToDate)
+	if err != nil {
+		return err
+	}
+	err = l.setExtraPortsEnv(false)
 	if err != nil {
 		return err
 	}
+	keyNames := l.envKeyNames()
+	appEnv = utils.MergeEnv(appEnv, keyNames.rename(l.extraPortsEnv))
 	appPort := l.appPort
+	refs := l.sidecarRefs()
 	for id, sidecar := range l.sConfig.Sidecars {
 		entry := entryG.WithField("sidecar", sidecar.Name)
 		entry.Infof("Setup ...")
 
-		err := l.setupSidecarArtifact(sidecar)
+		if upToDate[sidecar.Name] {
+			entry.Info("Skipping install, sidecar has not changed since last setup.")
+		} else {
+			err := l.setupSidecarArtifact(sidecar)
+			if err != nil {
+				return err
+			}
+		}
+		err := l.applyManifest(sidecar)
 		if err != nil {
 			return err
 		}
 
-		appEnvUnTpl, err := TemplatingEnv(appEnv, sidecar.AppEnv)
+		startTemplating := time.Now()
+		appEnvUnTpl, err := refs.templateValues(appEnv, sidecar.AppEnv)
 		if err != nil {
-			return err
+			return NewSidecarError(sidecar, err)
 		}
 		appEnv = utils.MergeEnv(appEnv, appEnvUnTpl)
 		if sidecar.IsRproxy {
@@ -169,18 +457,27 @@ func (l Launcher) Setup() error {
 			}
 			entry.Infof("Finished writing profiled file '%s' .", fileName)
 		}
+		l.setupTimings.record(sidecar.Name, SetupPhaseTemplating, startTemplating)
 
 		entry.Infof("Finished setup.")
 	}
+	err = l.writeSetupState()
+	if err != nil {
+		return err
+	}
+	err = l.runRelease(config.ReleasePhaseSetup)
+	if err != nil {
+		return err
+	}
 	entryG.Infof("Finished setup sidecars.")
 	if l.cStarter == nil || l.sConfig.NoStarter {
 		return nil
 	}
 	if appPort != l.appPort {
-		appEnv = utils.MergeEnv(appEnv, l.cStarter.ProxyEnv(appPort))
-		appEnv = utils.MergeEnv(appEnv, map[string]string{
+		appEnv = utils.MergeEnv(appEnv, keyNames.rename(l.cStarter.ProxyEnv(appPort)))
+		appEnv = utils.MergeEnv(appEnv, keyNames.rename(map[string]string{
 			AppPortEnvKey: strconv.Itoa(l.appPort),
-		})
+		}))
 	}
 	entryG.WithField("starter", l.cStarter.Name()).Info("Adding starter.sh profile")
 	profileLaunch := ""
@@ -198,100 +495,432 @@ func (l Launcher) Setup() error {
 }
 
 func (l Launcher) DownloadArtifacts() error {
+	return l.downloadArtifacts(nil)
+}
+
+// downloadArtifacts download artifacts of sidecars except the ones given as up to date
+func (l Launcher) downloadArtifacts(upToDate map[string]bool) error {
 	entryG := log.WithField("component", "Launcher").WithField("command", "download_artifact")
 	entryG.Info("Start downloading artifacts from sidecars ...")
+	sources := make([]*config.Sidecar, 0)
+	for _, sidecar := range l.sConfig.Sidecars {
+		if sidecar.ArtifactURI == "" || upToDate[sidecar.Name] || l.isLinkedArtifact(sidecar) {
+			continue
+		}
+		source := *sidecar
+		source.ArtifactURI, source.ArtifactType = l.artifactSource(sidecar)
+		sources = append(sources, &source)
+	}
+	// sidecars using same artifact download it once
+	l.downloads = newSharedDownloads(l.sConfig.Dir, sources)
+	defer l.downloads.cleanup()
 	for _, sidecar := range l.sConfig.Sidecars {
 		if sidecar.ArtifactURI == "" {
 			continue
 		}
 		entry := entryG.WithField("sidecar", sidecar.Name)
-
-		shouldDownload, why := l.indexer.ShouldDownload(sidecar)
-		if !shouldDownload && why != "" {
-			return NewSidecarError(sidecar, fmt.Errorf(why))
-		}
-		if !shouldDownload {
-			entry.Info("Skipping downloading, already downloaded.")
+		if upToDate[sidecar.Name] {
+			entry.Info("Skipping downloading, sidecar has not changed since last setup.")
 			continue
 		}
-		dir := SidecarDir(l.sConfig.Dir, sidecar.Name)
-		os.RemoveAll(dir)
-		err := os.MkdirAll(dir, os.ModePerm)
-		if err != nil {
-			return NewSidecarError(sidecar, err)
-		}
-		zipFileName := sidecar.Name + ".zip"
-		zipFilePath := filepath.Join(dir, zipFileName)
-		err = DownloadSidecar(zipFilePath, sidecar)
-		if err != nil {
-			return NewSidecarError(sidecar, err)
-		}
-
-		err = l.indexer.UpdateOrCreateIndex(sidecar, filepath.Join(PathSidecarsWd, sidecar.Name, zipFileName))
-		if err != nil {
-			os.Remove(zipFilePath)
-			return NewSidecarError(sidecar, err)
-		}
-
-		err = l.indexer.Store()
+		err := l.downloadArtifact(sidecar)
 		if err != nil {
 			return err
 		}
 	}
+	err := l.cleanNonExistingSidecars()
+	if err != nil {
+		return err
+	}
+
+	entryG.Info("Finished downloading artifacts from sidecars.")
+	return nil
+}
+
+// cleanNonExistingSidecars remove install dirs and locks of sidecars which are not in config anymore
+func (l Launcher) cleanNonExistingSidecars() error {
+	entry := log.WithField("component", "Launcher").WithField("command", "download_artifact")
 	log.Debug("Cleaning non existing sidecars ...")
 	indexToRm := l.indexer.IndexToRemove(l.sConfig.Sidecars)
 	for _, index := range indexToRm {
-		os.RemoveAll(filepath.Dir(index.ZipFile))
+		err := removeInstallDir(l.sConfig.Dir, filepath.Join(l.sConfig.Dir, filepath.Dir(index.ZipFile)))
+		if err != nil {
+			entry.Warn(err.Error())
+		}
 		l.indexer.RemoveIndex(index)
 		l.indexer.Store()
 	}
+	for _, lock := range l.locker.Locks() {
+		if l.sConfig.SidecarByName(lock.Name) != nil {
+			continue
+		}
+		l.locker.RemoveLock(lock.Name)
+		err := l.locker.Store()
+		if err != nil {
+			return err
+		}
+	}
 	log.Debug("Finished cleaning non existing sidecars ...")
-
-	entryG.Info("Finished downloading artifacts from sidecars.")
 	return nil
 }
 
-func (l Launcher) Launch() error {
+// downloadArtifact download artifact of sidecar and index it if it has not been already downloaded or installed
+func (l Launcher) downloadArtifact(sidecar *config.Sidecar) error {
+	entry := log.WithField("component", "Launcher").
+		WithField("command", "download_artifact").
+		WithField("sidecar", sidecar.Name)
+	if l.isLinkedArtifact(sidecar) {
+		entry.Info("Skipping downloading, local artifact is symlinked during setup.")
+		return nil
+	}
+
+	shouldDownload, why := l.indexer.ShouldDownload(sidecar)
+	if !shouldDownload && why != "" {
+		return NewSidecarError(sidecar, errors.New(why))
+	}
+	if !shouldDownload {
+		entry.Info("Skipping downloading, already downloaded.")
+		return nil
+	}
+	notModified, err := l.installedNotModified(sidecar)
+	if err != nil {
+		entry.Warnf("Could not check if artifact has been modified: %s", err.Error())
+	}
+	if notModified {
+		entry.Info("Skipping downloading, artifact not modified since installation.")
+		return nil
+	}
+	dir := SidecarInstallDir(l.sConfig.Dir, sidecar)
+	err = removeInstallDir(l.sConfig.Dir, dir)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	err = createInstallDir(dir)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	err = l.checkArtifactSize(sidecar, dir)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	source := *sidecar
+	source.ArtifactURI, source.ArtifactType = l.artifactSource(sidecar)
+	if l.streamInstall && isHttpArtifact(&source) {
+		return l.streamInstallSidecar(sidecar, &source, dir)
+	}
+	zipFileName := sidecar.Name + ".zip"
+	zipFilePath := filepath.Join(dir, zipFileName)
+	metricTags := map[string]string{"sidecar": sidecar.Name}
+	validators := FetchHttpValidators(sidecar)
+	startDownload := time.Now()
+	source.MaxArtifactSize = int(l.maxArtifactSize(sidecar) / bytesPerMB)
+	shared := l.downloads.artifact(&source)
+	if sharedPath, ok := shared.reusable(); ok {
+		entry.Infof("Reusing artifact %s downloaded for another sidecar.", source.ArtifactURI)
+		err = linkOrCopyFile(sharedPath, zipFilePath)
+	} else {
+		err = DownloadSidecar(zipFilePath, &source)
+	}
+	if err != nil {
+		l.metrics.Incr(MetricDownloadFailed, metricTags)
+		return NewSidecarError(sidecar, err)
+	}
+	if shared != nil {
+		shared.path = zipFilePath
+	}
+	l.metrics.Timing(MetricDownloadDuration, time.Since(startDownload), metricTags)
+	l.setupTimings.record(sidecar.Name, SetupPhaseDownload, startDownload)
+
+	checksum, err := fileChecksum(zipFilePath)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	err = l.indexer.UpdateOrCreateIndex(sidecar, filepath.Join(sidecarInstallRelDir(sidecar), zipFileName), checksum, validators)
+	if err != nil {
+		os.Remove(zipFilePath)
+		return NewSidecarError(sidecar, err)
+	}
+
+	return l.indexer.Store()
+}
+
+// launchState keep what has been started by Start to be waited and cleaned by Wait
+type launchState struct {
+	done        chan struct{}
+	cleanups    []func()
+	cleanupOnce sync.Once
+	err         error
+}
+
+// cleanup run cleanup funcs in reverse order of registration
+func (s *launchState) cleanup() {
+	s.cleanupOnce.Do(func() {
+		for i := len(s.cleanups) - 1; i >= 0; i-- {
+			s.cleanups[i]()
+		}
+	})
+}
+
+// AllowUnsafeExtract let artifacts contain entries or symlinks escaping sidecar directory
+func (l *Launcher) AllowUnsafeExtract(allow bool) {
+	l.unsafeExtract = allow
+}
+
+// SetVersion set version of cloud-sidecars recorded in setup state, everything is set up again when it changes
+func (l *Launcher) SetVersion(version string) {
+	l.version = version
+}
+
+// SetRefresh make setup only set up again sidecars which have changed since last setup
+func (l *Launcher) SetRefresh(refresh bool) {
+	l.refresh = refresh
+}
+
+// Launch start all sidecars and app and wait for them to stop
+func (l *Launcher) Launch() error {
+	err := l.Start()
+	if err != nil {
+		return err
+	}
+	return l.Wait()
+}
+
+// Start all sidecars and app without waiting for them, use Wait to wait for them to stop
+func (l *Launcher) Start() error {
+	if l.launched != nil {
+		return fmt.Errorf("Launcher has already been started")
+	}
 	entry := log.WithField("component", "Launcher").
 		WithField("command", "launch")
 
+	state := &launchState{
+		done: make(chan struct{}),
+	}
 	wg := l.processFactory.WaitGroup()
-	processLen := len(l.sConfig.Sidecars)
-	if !l.sConfig.NoStarter {
-		processLen++
+	if l.sConfig.Loki != nil && l.sConfig.Loki.Url != "" {
+		lokiPusher := NewLokiPusher(*l.sConfig.Loki)
+		l.processFactory.SetLokiPusher(lokiPusher)
+		lokiPusher.Start()
+		state.cleanups = append(state.cleanups, lokiPusher.Stop)
+	}
+	if l.sConfig.Events != nil {
+		events, err := NewEventEmitter(*l.sConfig.Events)
+		if err != nil {
+			entry.Warnf("Lifecycle events will not be sent: %s", err.Error())
+		} else {
+			events.Start()
+			l.events = events
+			l.processFactory.SetEventEmitter(events)
+			state.cleanups = append(state.cleanups, events.Stop)
+		}
+	}
+	if l.pprofAddr != "" {
+		stopPprof, err := startPprofServer(l.pprofAddr)
+		if err != nil {
+			entry.Warnf("Profiles of launcher will not be served: %s", err.Error())
+		} else {
+			state.cleanups = append(state.cleanups, stopPprof)
+		}
+	}
+	// sidecars are copied before being modified by templating to find changes when reloading
+	loadedSidecars, err := copySidecars(l.sConfig.Sidecars)
+	if err != nil {
+		state.cleanup()
+		return err
+	}
+	l.loadedSidecars = loadedSidecars
+	state.cleanups = append(state.cleanups, func() {
+		for _, forwarder := range l.forwarders {
+			forwarder.Close()
+		}
+	})
+	if l.sConfig.SourceProfileD {
+		err := l.sourceProfileD()
+		if err != nil {
+			state.cleanup()
+			return err
+		}
+	}
+	if l.sConfig.VerifyAtLaunch != "" {
+		err := l.verifyAtLaunch()
+		if err != nil {
+			state.cleanup()
+			return err
+		}
+	}
+	if l.sConfig.CoreDump != nil {
+		err := l.setupCoreDumps()
+		if err != nil {
+			entry.Warnf("Core dumps of sidecars will not be collected: %s", err.Error())
+		}
+	}
+	// temporary dirs left by a previous run are removed, they are removed again once all processes are stopped
+	l.removeTmpDirs()
+	state.cleanups = append(state.cleanups, l.removeTmpDirs)
+	err = l.writeSecretFiles(l.sConfig.Sidecars)
+	if err != nil {
+		state.cleanup()
+		return err
+	}
+	state.cleanups = append(state.cleanups, l.shredSecretFiles)
+	err = l.runRelease(config.ReleasePhaseLaunch)
+	if err != nil {
+		state.cleanup()
+		return err
 	}
 	entry.Info("Creating all processes ...")
 	processLen, processes, err := l.CreateProcesses()
 	if err != nil {
+		state.cleanup()
 		return err
 	}
 	entry.Info("Finished creating all processes ...")
+	for _, p := range processes {
+		if l.forwarders[p.name] != nil {
+			p.rollingRestart = l.rollingRestartFunc(p.name)
+		}
+	}
 
 	wg.Add(processLen)
-	pProcesses := &processes
 
 	signalChan := l.processFactory.SignalChan()
-	errChan := l.processFactory.ErrorChan()
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL)
+	state.cleanups = append(state.cleanups, func() {
+		signal.Stop(signalChan)
+	})
+	l.initMode = isInit()
+	if l.initMode {
+		entry.Info("Launcher runs as pid 1, orphan processes are reaped and signals are forwarded to app.")
+		// pid 1 does not get default action of signals without handler, SIGQUIT must stop everything as it would do
+		signal.Notify(signalChan, syscall.SIGQUIT)
+	} else if l.sConfig.Subreaper {
+		err := setSubreaper()
+		if err != nil {
+			entry.Warnf("Launcher could not be registered as subreaper: %s", err.Error())
+		}
+	}
+	if l.initMode || l.sConfig.Subreaper {
+		go reapOrphans(state.done)
+	}
+
+	if !l.sConfig.NoControlApi {
+		control := newControlServer(l.sConfig, l.processFactory)
+		err = control.Start()
+		if err != nil {
+			entry.Warnf("Control api could not be started: %s", err.Error())
+		}
+		state.cleanups = append(state.cleanups, control.Stop)
+	}
+	if l.sConfig.GrpcControlAddr != "" {
+		grpcControl := newGrpcControlServer(l.sConfig, l.processFactory)
+		err = grpcControl.Start()
+		if err != nil {
+			entry.Warnf("Grpc control api could not be started: %s", err.Error())
+		}
+		state.cleanups = append(state.cleanups, grpcControl.Stop)
+	}
 
 	// manage graceful shutdown
-	go l.handlingSignal(pProcesses, processLen, signalChan)
+	go l.handlingSignal(signalChan)
+	state.cleanups = append(state.cleanups, l.handlingAuxSignals(state.done))
+	if l.configLoader != nil && l.watchedConfig != "" {
+		stopWatching, err := l.watchFiles(state.done)
+		if err != nil {
+			entry.Warnf("Config files will not be watched: %s", err.Error())
+		} else {
+			state.cleanups = append(state.cleanups, stopWatching)
+		}
+	}
+
+	if l.sConfig.ReadyFile != "" {
+		readyFile := l.readyFilePath()
+		os.Remove(readyFile)
+		go l.signalReadiness(readyFile, processLen)
+		state.cleanups = append(state.cleanups, func() {
+			os.Remove(readyFile)
+		})
+	}
 
 	for _, p := range processes {
 		go p.Start()
 	}
-	wg.Wait()
+	if l.sConfig.LaunchTimeout > 0 {
+		go l.watchLaunchTimeout(processes, state.done)
+	}
+	startedAt := time.Now()
+	go func() {
+		wg.Wait()
+		l.logTotalPeakMemory()
+		err := l.writeLastRun(startedAt)
+		if err != nil {
+			entry.Warnf("Last run report could not be written: %s", err.Error())
+		}
+		close(state.done)
+	}()
+	l.launched = state
+	return nil
+}
+
+// Wait for all sidecars and app started by Start to stop,
+// first error which has occurred on a process is returned
+func (l Launcher) Wait() error {
+	if l.launched == nil {
+		return fmt.Errorf("Launcher has not been started")
+	}
+	<-l.launched.done
+	l.launched.cleanup()
 	select {
-	case err = <-errChan:
-		return err
+	case err := <-l.processFactory.ErrorChan():
+		l.launched.err = err
 	default:
+	}
+	return l.exitCodeError(l.launched.err)
+}
+
+// Stop gracefully all sidecars and app started by Start,
+// processes are killed if they are still running when ctx is done
+func (l Launcher) Stop(ctx context.Context) error {
+	if l.launched == nil {
+		return fmt.Errorf("Launcher has not been started")
+	}
+	l.processFactory.SignalChan() <- syscall.SIGTERM
+	select {
+	case <-l.launched.done:
 		return nil
+	case <-ctx.Done():
+		for _, p := range l.processFactory.Processes() {
+			if p.IsRunning() {
+				p.Signal(syscall.SIGKILL)
+			}
+		}
+		return ctx.Err()
 	}
-	return nil
+}
+
+func (l Launcher) readyFilePath() string {
+	if filepath.IsAbs(l.sConfig.ReadyFile) {
+		return l.sConfig.ReadyFile
+	}
+	return filepath.Join(l.sConfig.Dir, l.sConfig.ReadyFile)
+}
+
+// signalReadiness write ready file when all processes has been started
+func (l Launcher) signalReadiness(readyFile string, processLen int) {
+	entry := log.WithField("component", "Launcher")
+	startedChan := l.processFactory.StartedChan()
+	for i := 0; i < processLen; i++ {
+		<-startedChan
+	}
+	err := ioutil.WriteFile(readyFile, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+	if err != nil {
+		entry.Warnf("Could not write ready file: %s", err.Error())
+		return
+	}
+	entry.Infof("All processes started, ready file written at %s", readyFile)
 }
 
 func (l Launcher) CreateProcesses() (processLen int, processes []*process, err error) {
-	processLen = len(l.sConfig.Sidecars)
+	processLen = instancesLen(l.sConfig.Sidecars)
 	if !l.sConfig.NoStarter {
 		processLen++
 	}
@@ -300,108 +929,255 @@ func (l Launcher) CreateProcesses() (processLen int, processes []*process, err e
 	appEnv := utils.OsEnvToMap()
 	i := 0
 	appPort := l.appPort
-	if os.Getenv(AppPortEnvKey) != "" {
-		appPort, err = strconv.Atoi(os.Getenv(AppPortEnvKey))
+	keyNames := l.envKeyNames()
+	if os.Getenv(keyNames.name(AppPortEnvKey)) != "" {
+		appPort, err = strconv.Atoi(os.Getenv(keyNames.name(AppPortEnvKey)))
 		if err != nil {
 			return processLen, processes, err
 		}
 	}
-	for _, sidecar := range l.sConfig.Sidecars {
-		env, err := OverrideEnv(utils.OsEnvToMap(), sidecar.Env)
+	hasStarter := l.cStarter != nil && !l.sConfig.NoStarter
+	// platform port can't be reallocated
+	if hasStarter || l.hasRproxy() {
+		_, err = l.ensurePortFree(appPort, l.upstreamListener(-1), false)
 		if err != nil {
-			return processLen, processes, NewSidecarError(sidecar, err)
+			return processLen, processes, err
+		}
+	}
+	for sidecarIndex, sidecar := range l.sConfig.Sidecars {
+		err := l.applyManifest(sidecar)
+		if err != nil {
+			return processLen, processes, err
 		}
 		if sidecar.IsRproxy {
-			if l.cStarter != nil && !l.sConfig.NoStarter {
-				env, err = OverrideEnv(env, l.cStarter.ProxyEnv(appPort))
+			// kept to give same ports to sidecar when it is restarted by a reload
+			proxyEnv := make(map[string]string)
+			listenPort := appPort
+			if sidecar.RollingRestart && !hasStarter {
+				log.WithField("sidecar", sidecar.Name).Warn("Rolling restart needs a starter to set sidecar port, sidecar will be restarted as usual")
+			}
+			if sidecar.RollingRestart && hasStarter {
+				// launcher listen on sidecar port and forward to port of current instance of sidecar
+				listenPort, err = utils.FreePort()
+				if err != nil {
+					return processLen, processes, NewSidecarError(sidecar, err)
+				}
+				forwarder, err := listenForwarder(appPort, listenPort)
 				if err != nil {
 					return processLen, processes, NewSidecarError(sidecar, err)
 				}
+				l.forwarders[sidecar.Name] = forwarder
+			}
+			if hasStarter {
+				proxyEnv = utils.MergeEnv(proxyEnv, l.cStarter.ProxyEnv(listenPort))
 			}
 			appPort++
-			env, err = OverrideEnv(env, map[string]string{
-				ProxyAppPortEnvKey: fmt.Sprintf("%d", appPort),
-			})
-			if err != nil {
-				return processLen, processes, NewSidecarError(sidecar, err)
+			upstream := l.upstreamListener(sidecarIndex)
+			// app port is not checked when app is not launched by us as it should already listen on it
+			if hasStarter || upstream != "app" {
+				appPort, err = l.ensurePortFree(appPort, upstream, true)
+				if err != nil {
+					return processLen, processes, NewSidecarError(sidecar, err)
+				}
 			}
+			if !hasStarter {
+				// port where proxy listens is only known when given by starter
+				listenPort = 0
+			}
+			proxyEnv = utils.MergeEnv(proxyEnv, l.proxyUpstreamEnv(sidecarIndex, listenPort, appPort))
+			l.proxyEnvs[sidecar.Name] = proxyEnv
 		}
+	}
+	err = l.setExtraPortsEnv(true)
+	if err != nil {
+		return processLen, processes, err
+	}
+	appEnv = utils.MergeEnv(appEnv, keyNames.rename(l.extraPortsEnv))
+	err = l.materializeOutputs()
+	if err != nil {
+		return processLen, processes, err
+	}
+	appEnv = utils.MergeEnv(appEnv, l.outputsEnv)
+	// envs are computed once all ports are known as sidecars can reference ports of each other
+	refs := l.sidecarRefs()
+	for _, sidecar := range l.sConfig.Sidecars {
 		entry := log.WithField("sidecar", sidecar.Name)
 		entry.Debug("Setup sidecar ...")
-		appEnvUnTpl, err := TemplatingEnv(appEnv, sidecar.AppEnv)
+		env, err := refs.env(sidecar)
 		if err != nil {
 			return processLen, processes, NewSidecarError(sidecar, err)
 		}
-		appEnv = utils.MergeEnv(appEnv, appEnvUnTpl)
-		processes[i], err = l.processFactory.FromSidecar(sidecar, env)
+		appEnvUnTpl, err := refs.templateValues(appEnv, sidecar.AppEnv)
 		if err != nil {
 			return processLen, processes, NewSidecarError(sidecar, err)
 		}
-		i++
+		appEnv = utils.MergeEnv(appEnv, appEnvUnTpl)
+		for _, instance := range sidecarInstances(sidecar) {
+			if instance != sidecar {
+				env, err = refs.env(instance)
+				if err != nil {
+					return processLen, processes, NewSidecarError(sidecar, err)
+				}
+			}
+			processes[i], err = l.processFactory.FromSidecar(instance, env)
+			if err != nil {
+				return processLen, processes, NewSidecarError(sidecar, err)
+			}
+			// sidecars are started one after the other with stagger interval to not start all at once
+			processes[i].startDelay += time.Duration(i*l.sConfig.StartStagger) * time.Second
+			i++
+		}
 
 		entry.Debug("Finished setup sidecar.")
 	}
 	if !l.sConfig.NoStarter {
 		entryS := log.WithField("starter", l.cStarter.Name())
 		if appPort != l.appPort {
-			appEnv = utils.MergeEnv(appEnv, l.cStarter.ProxyEnv(appPort))
+			appEnv = utils.MergeEnv(appEnv, keyNames.rename(l.cStarter.ProxyEnv(appPort)))
 		}
 		entryS.Debug("Setup cloud starter ...")
 		processes[i], err = l.processFactory.FromStarter(appEnv, l.profileDir)
 		if err != nil {
 			return processLen, processes, err
 		}
+		// sidecars started after app wait for app to accept connections on its port
+		appHost := loopbackHost()
+		if l.sConfig.AppHost != "" {
+			appHost = l.sConfig.AppHost
+		}
+		processes[i].listenAddr = net.JoinHostPort(appHost, strconv.Itoa(appPort))
 		entryS.Debug("Finished setup cloud starter ...")
 	}
+	l.orderProcesses(processes)
 	return processLen, processes, err
 }
 
-func (l Launcher) handlingSignal(pProcesses *[]*process, processLen int, signalChan chan os.Signal) {
-	sig := <-signalChan
-	// If signal has been set by other process at init we are waiting
-	// to reach number of process required before sending back signal
-	for !processesNotHaveLen(*pProcesses, processLen) {
-		time.Sleep(10 * time.Millisecond)
+// orderProcesses make sidecars of a group listed in group_order start after all sidecars of previous groups
+// and sidecars with start_order after_app start after app
+func (l Launcher) orderProcesses(processes []*process) {
+	for _, p := range processes {
+		if p != nil {
+			p.startAfter = l.startAfterProcesses(p, processes)
+		}
 	}
-	for _, process := range *pProcesses {
-		if process.cmd.Process == nil {
-			continue // process is not running (which probably create signal)
+}
+
+// startAfterProcesses give processes which must be started before process
+func (l Launcher) startAfterProcesses(p *process, processes []*process) []*process {
+	previous := l.previousGroupsProcesses(p.group, processes)
+	sidecar := sidecarOfProcess(l.sConfig.Sidecars, p.name)
+	if p.typeP != "sidecar" || sidecar == nil || sidecar.StartOrder != config.StartOrderAfterApp {
+		return previous
+	}
+	for _, other := range processes {
+		if other != nil && other.typeP == "cloud" {
+			return append(previous, other)
 		}
-		// resent signal for each process to make them detect
-		// when they receive a signal to not show error
-		signalChan <- sig
-		// if setpgid exist in sysproc, we need to send signal to negative pid (-pid)
-		// this will stop all sub process that one of our sidecars or app has started
-		// we override pid value to let us use process.Process.Signal
-		// instead of non os agnostic syscall funcs
-		if utils.HasPgidSysProcAttr(process.cmd.SysProcAttr) {
-			process.cmd.Process.Pid = -process.cmd.Process.Pid
+	}
+	log.WithField("sidecar", sidecar.Name).Warn("No app is launched, sidecar started after app is started directly")
+	return previous
+}
+
+// previousGroupsProcesses give processes of groups listed before group in group_order
+func (l Launcher) previousGroupsProcesses(group string, processes []*process) []*process {
+	previous := make([]*process, 0)
+	for i, g := range l.sConfig.GroupOrder {
+		if g != group || group == "" {
+			continue
+		}
+		for _, p := range processes {
+			if p != nil && p.group != "" && utils.InStrings(p.group, l.sConfig.GroupOrder[:i]) {
+				previous = append(previous, p)
+			}
+		}
+		break
+	}
+	return previous
+}
+
+func (l Launcher) hasRproxy() bool {
+	for _, sidecar := range l.sConfig.Sidecars {
+		if sidecar.IsRproxy {
+			return true
 		}
-		process.cmd.Process.Signal(sig)
 	}
-	// if processes still doesn't stop after 20 sec we force shutdown
-	time.Sleep(20 * time.Second)
-	for _, process := range *pProcesses {
-		signalChan <- syscall.SIGKILL
-		process.cmd.Process.Kill()
+	return false
+}
+
+func (l *Launcher) handlingSignal(signalChan chan os.Signal) {
+	sig := <-signalChan
+	l.processFactory.Stop()
+	stopEvent := Event{Event: EventStopping, Type: "launcher", Signal: sig.String()}
+	if s, ok := sig.(syscall.Signal); ok {
+		stopEvent.Signal = signalName(s)
 	}
+	l.events.Emit(stopEvent)
+	// wait for a running reload to finish to stop processes it has started
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	// processes are stopped stage after stage to let app stop before proxies and sidecars it relies on
+	l.stopProcesses(l.processFactory.Processes(), sig, signalChan)
 }
 
 func SidecarDir(baseDir, sidecarName string) string {
 	return filepath.Join(baseDir, PathSidecarsWd, sidecarName)
 }
 
-func IndexFilePath(baseDir string) string {
-	return filepath.Join(baseDir, PathSidecarsWd, "index.yml")
+// SidecarInstallDir give dir where artifact of sidecar is extracted, install_dir when set or .sidecars/<name>
+func SidecarInstallDir(baseDir string, sidecar *config.Sidecar) string {
+	return filepath.Join(baseDir, sidecarInstallRelDir(sidecar))
+}
+
+func sidecarInstallRelDir(sidecar *config.Sidecar) string {
+	if sidecar.InstallDir != "" {
+		return filepath.Clean(sidecar.InstallDir)
+	}
+	return filepath.Join(PathSidecarsWd, sidecar.Name)
 }
 
-func processesNotHaveLen(processes []*process, len int) bool {
-	var i int
-	var p *process
-	for i, p = range processes {
-		if p == nil {
-			break
+// installMarkerFile is written in install dirs created by launcher,
+// a non-empty install_dir without it may contain app files and is never removed
+const installMarkerFile = ".sidecar-install"
+
+// createInstallDir create install dir of a sidecar and mark it as created by launcher
+func createInstallDir(dir string) error {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, installMarkerFile), []byte{}, 0644)
+}
+
+// removeInstallDir remove install dir of a sidecar, dirs in .sidecars are owned by launcher,
+// other ones are only removed when empty or created by launcher
+func removeInstallDir(baseDir, dir string) error {
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Join(baseDir, PathSidecarsWd), dir)
+	ownedByLauncher := err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+	if info.IsDir() && !ownedByLauncher {
+		if _, err := os.Stat(filepath.Join(dir, installMarkerFile)); os.IsNotExist(err) {
+			files, err := ioutil.ReadDir(dir)
+			if err != nil {
+				return err
+			}
+			if len(files) > 0 {
+				return fmt.Errorf("Install dir %s is not empty and has not been created by launcher, remove it manually to install sidecar", dir)
+			}
 		}
 	}
-	return (i + 1) == len
+	return os.RemoveAll(dir)
+}
+
+func IndexFilePath(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, "index.yml")
+}
+
+func LockFilePath(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, "sidecars-lock.yml")
 }
@@ -0,0 +1,50 @@
+package sidecars
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveInstallDirKeepsDirNotCreatedByLauncher(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "sidecars-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+	appFile := filepath.Join(baseDir, "src", "main.go")
+	err = os.MkdirAll(filepath.Dir(appFile), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(appFile, []byte("package main"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = removeInstallDir(baseDir, filepath.Dir(appFile))
+	if err == nil {
+		t.Fatal("Expected removing a non-empty dir not created by launcher to fail")
+	}
+	if _, err := os.Stat(appFile); err != nil {
+		t.Fatalf("App file has been removed: %s", err.Error())
+	}
+
+	installDir := filepath.Join(baseDir, "datadog")
+	err = createInstallDir(installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(installDir, "agent"), []byte("agent"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = removeInstallDir(baseDir, installDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(installDir); !os.IsNotExist(err) {
+		t.Fatal("Expected install dir created by launcher to be removed")
+	}
+}
@@ -0,0 +1,85 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const localArtifactType = "local"
+
+// localArtifactDir give path of artifact when artifact_uri is a local directory,
+// it can be set as a file:// uri or as a path, relative paths are relative to sidecars dir
+func (l Launcher) localArtifactDir(sidecar *config.Sidecar) (string, bool) {
+	uri := sidecar.ArtifactURI
+	if uri == "" {
+		return "", false
+	}
+	isFileUri := strings.HasPrefix(uri, "file://")
+	if isFileUri {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", false
+		}
+		uri = u.Path
+	} else if strings.Contains(uri, "://") {
+		return "", false
+	}
+	if !filepath.IsAbs(uri) {
+		uri = filepath.Join(l.sConfig.Dir, uri)
+	}
+	stat, err := os.Stat(uri)
+	if err != nil || !stat.IsDir() {
+		return uri, isFileUri
+	}
+	return uri, true
+}
+
+// artifactSource give uri and type to use with zipper for downloading sidecar artifact
+func (l Launcher) artifactSource(sidecar *config.Sidecar) (uri, fileType string) {
+	if dir, ok := l.localArtifactDir(sidecar); ok {
+		return dir, localArtifactType
+	}
+	if uri, ok := l.mavenArtifactSource(sidecar.ArtifactURI); ok {
+		return uri, mavenArtifactType
+	}
+	return sidecar.ArtifactURI, sidecar.ArtifactType
+}
+
+// isLinkedArtifact check if sidecar directory must be a symlink to a local directory instead of a copy
+func (l Launcher) isLinkedArtifact(sidecar *config.Sidecar) bool {
+	_, isLocal := l.localArtifactDir(sidecar)
+	return isLocal && sidecar.ArtifactSymlink
+}
+
+// linkLocalArtifact replace sidecar directory by a symlink to local artifact directory
+func (l Launcher) linkLocalArtifact(sidecar *config.Sidecar) error {
+	entry := log.WithField("sidecar", sidecar.Name)
+	localDir, _ := l.localArtifactDir(sidecar)
+	target := filepath.Join(localDir, filepath.FromSlash(sidecar.ArtifactSubpath))
+	stat, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("Local artifact '%s' must be a directory to be symlinked", target)
+	}
+	dir := SidecarInstallDir(l.sConfig.Dir, sidecar)
+	if current, err := os.Readlink(dir); err == nil && current == target {
+		return nil
+	}
+	entry.Debugf("Linking local artifact %s ...", target)
+	err = removeInstallDir(l.sConfig.Dir, dir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(target, dir)
+}
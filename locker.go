@@ -0,0 +1,99 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type Lock struct {
+	Name     string `yaml:"name"`
+	Uri      string `yaml:"uri"`
+	Sha1     string `yaml:"sha1"`
+	Checksum string `yaml:"checksum"`
+
+	HttpValidators `yaml:",inline"`
+}
+
+type Locker struct {
+	lockFile string
+	locks    map[string]Lock
+}
+
+func NewLocker(lockFile string) *Locker {
+	locker := &Locker{
+		lockFile: lockFile,
+		locks:    make(map[string]Lock),
+	}
+	err := locker.loadLocks()
+	if err != nil {
+		panic(err)
+	}
+	return locker
+}
+
+func (l *Locker) loadLocks() error {
+	f, err := os.Open(l.lockFile)
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var locks []Lock
+	err = yaml.NewDecoder(f).Decode(&locks)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		l.locks[lock.Name] = lock
+	}
+	return nil
+}
+
+func (l Locker) Lock(sidecar *config.Sidecar) (Lock, bool) {
+	lock, ok := l.locks[sidecar.Name]
+	return lock, ok
+}
+
+func (l *Locker) UpdateOrCreateLock(sidecar *config.Sidecar, checksum string, validators HttpValidators) {
+	l.locks[sidecar.Name] = Lock{
+		Name:           sidecar.Name,
+		Uri:            sidecar.ArtifactURI,
+		Sha1:           sidecar.ArtifactSha1,
+		Checksum:       checksum,
+		HttpValidators: validators,
+	}
+}
+
+func (l *Locker) RemoveLock(name string) {
+	delete(l.locks, name)
+}
+
+func (l Locker) Locks() []Lock {
+	locks := make([]Lock, 0, len(l.locks))
+	for _, v := range l.locks {
+		locks = append(locks, v)
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Name < locks[j].Name
+	})
+	return locks
+}
+
+func (l Locker) Store() error {
+	// sidecars dir may not exist when all sidecars are installed in their own install dir
+	err := os.MkdirAll(filepath.Dir(l.lockFile), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(l.lockFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return yaml.NewEncoder(f).Encode(l.Locks())
+}
@@ -0,0 +1,253 @@
+package sidecars
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/cloudfoundry-community/gautocloud"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	lokiPushPath         = "/loki/api/v1/push"
+	lokiDefaultBatchSize = 500
+	lokiDefaultBatchWait = 1
+	lokiMaxPartialLine   = 64 * 1024
+)
+
+type lokiEntry struct {
+	labels map[string]string
+	time   time.Time
+	line   string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// LokiPusher send lines of processes output to a loki server by batch
+type LokiPusher struct {
+	conf       config.Loki
+	appName    string
+	httpClient *http.Client
+	entries    chan lokiEntry
+	stop       chan struct{}
+	done       chan struct{}
+	batchSize  int
+	batchWait  time.Duration
+}
+
+func NewLokiPusher(conf config.Loki) *LokiPusher {
+	appName := conf.AppName
+	if appName == "" {
+		appName = gautocloud.GetAppInfo().Name
+	}
+	batchSize := conf.BatchSize
+	if batchSize <= 0 {
+		batchSize = lokiDefaultBatchSize
+	}
+	batchWait := conf.BatchWait
+	if batchWait <= 0 {
+		batchWait = lokiDefaultBatchWait
+	}
+	return &LokiPusher{
+		conf:    conf,
+		appName: appName,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		entries:   make(chan lokiEntry, batchSize*10),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		batchSize: batchSize,
+		batchWait: time.Duration(batchWait) * time.Second,
+	}
+}
+
+// Writer give a writer which send each written line to loki with app, sidecar and stream labels
+func (p *LokiPusher) Writer(processName, stream string) io.Writer {
+	labels := map[string]string{
+		"app":     p.appName,
+		"sidecar": processName,
+		"stream":  stream,
+	}
+	for k, v := range p.conf.Labels {
+		labels[k] = v
+	}
+	return &lineWriter{
+		fn: func(line string) {
+			p.push(labels, line)
+		},
+	}
+}
+
+func (p *LokiPusher) push(labels map[string]string, line string) {
+	select {
+	case p.entries <- lokiEntry{labels: labels, time: time.Now(), line: line}:
+	default:
+		// loki is too slow, line is dropped instead of blocking processes output
+	}
+}
+
+func (p *LokiPusher) Start() {
+	go p.run()
+}
+
+// Stop send remaining lines and wait for them to be sent
+func (p *LokiPusher) Stop() {
+	close(p.stop)
+	<-p.done
+}
+
+func (p *LokiPusher) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.batchWait)
+	defer ticker.Stop()
+	batch := make([]lokiEntry, 0, p.batchSize)
+	for {
+		select {
+		case entry := <-p.entries:
+			batch = append(batch, entry)
+			if len(batch) < p.batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-p.stop:
+			p.flush(batch)
+			return
+		}
+		p.send(batch)
+		batch = batch[:0]
+	}
+}
+
+// flush send given batch and all lines still waiting
+func (p *LokiPusher) flush(batch []lokiEntry) {
+	for {
+		select {
+		case entry := <-p.entries:
+			batch = append(batch, entry)
+			if len(batch) >= p.batchSize {
+				p.send(batch)
+				batch = batch[:0]
+			}
+		default:
+			p.send(batch)
+			return
+		}
+	}
+}
+
+func (p *LokiPusher) send(batch []lokiEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	entry := log.WithField("component", "loki")
+	streams := make(map[string]*lokiStream)
+	keys := make([]string, 0)
+	for _, e := range batch {
+		key := lokiLabelsKey(e.labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: e.labels, Values: make([][2]string, 0)}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		stream.Values = append(stream.Values, [2]string{fmt.Sprintf("%d", e.time.UnixNano()), e.line})
+	}
+	body := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{
+		Streams: make([]*lokiStream, len(keys)),
+	}
+	for i, key := range keys {
+		body.Streams[i] = streams[key]
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		entry.Warnf("Could not encode lines for loki: %s", err.Error())
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(p.conf.Url, "/")+lokiPushPath, bytes.NewReader(b))
+	if err != nil {
+		entry.Warnf("Could not create request to loki: %s", err.Error())
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.conf.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", p.conf.TenantID)
+	}
+	if req.URL.User != nil {
+		password, _ := req.URL.User.Password()
+		req.SetBasicAuth(req.URL.User.Username(), password)
+		req.URL.User = nil
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		entry.Warnf("%d lines could not be sent to loki: %s", len(batch), err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		entry.Warnf("%d lines rejected by loki with status %d: %s", len(batch), resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+}
+
+func lokiLabelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// lineWriter call fn for each complete line written
+type lineWriter struct {
+	mu      sync.Mutex
+	partial []byte
+	fn      func(line string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	if len(w.partial) > lokiMaxPartialLine {
+		w.fn(string(w.partial))
+		w.partial = nil
+	}
+	return len(p), nil
+}
+
+// Flush call fn with last written line if it does not end with a new line
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		w.fn(string(w.partial))
+		w.partial = nil
+	}
+}
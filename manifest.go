@@ -0,0 +1,30 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"path/filepath"
+)
+
+// applyManifest merge sidecar.yml manifest found at root of installed artifact in sidecar config
+func (l Launcher) applyManifest(sidecar *config.Sidecar) error {
+	if sidecar.ArtifactURI == "" {
+		return nil
+	}
+	manifestPath := filepath.Join(SidecarInstallDir(l.sConfig.Dir, sidecar), config.SidecarManifestFile)
+	manifest, err := config.LoadSidecarManifest(manifestPath)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	if manifest == nil {
+		if sidecar.Executable == "" && sidecar.Command == nil {
+			return NewSidecarError(sidecar, fmt.Errorf("No executable or command set in config and no %s manifest found in artifact", config.SidecarManifestFile))
+		}
+		return nil
+	}
+	err = sidecar.MergeManifest(*manifest)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package sidecars
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+)
+
+const mavenArtifactType = "maven"
+
+// java archives are zip files which must be kept as is instead of being uncompressed
+var mavenKeptExtensions = []string{".jar", ".war", ".ear", ".aar"}
+
+func init() {
+	zipper.AddHandler(&MavenHandler{})
+}
+
+// MavenHandler is a zipper handler downloading artifacts given as mvn://group:artifact:version[:classifier][@extension]
+// from maven central, or given as url of a maven repository file with artifact type maven.
+// Java archives are kept as is and other archives are uncompressed as http handler would do.
+type MavenHandler struct{}
+
+func (h MavenHandler) Zip(src *zipper.Source) (zipper.ZipReadCloser, error) {
+	httpSrc, err := h.httpSource(src)
+	if err != nil {
+		return nil, err
+	}
+	if !zipper.HasExtFile(httpSrc.Path, mavenKeptExtensions...) {
+		return zipper.HttpHandler{}.Zip(httpSrc)
+	}
+	client := zipper.CtxHttpClient(httpSrc)
+	resp, err := client.Get(httpSrc.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not download maven artifact %s: %s", httpSrc.Path, resp.Status)
+	}
+	return singleFileZip(path.Base(httpSrc.Path), resp.Body)
+}
+
+func (h MavenHandler) Sha1(src *zipper.Source) (string, error) {
+	httpSrc, err := h.httpSource(src)
+	if err != nil {
+		return "", err
+	}
+	return zipper.HttpHandler{}.Sha1(httpSrc)
+}
+
+func (h MavenHandler) Detect(src *zipper.Source) bool {
+	return config.IsMavenUri(src.Path)
+}
+
+func (h MavenHandler) Name() string {
+	return mavenArtifactType
+}
+
+// httpSource give source of artifact url on maven repository
+func (h MavenHandler) httpSource(src *zipper.Source) (*zipper.Source, error) {
+	uri := src.Path
+	if config.IsMavenUri(uri) {
+		coords, err := config.ParseMavenUri(uri)
+		if err != nil {
+			return nil, err
+		}
+		uri = coords.Url(config.DefaultMavenRepository)
+	}
+	httpSrc := zipper.NewSource(uri)
+	client := zipper.CtxHttpClient(src)
+	if client == nil {
+		client = http.DefaultClient
+	}
+	zipper.SetCtxHttpClient(httpSrc, client)
+	return httpSrc, nil
+}
+
+// singleFileZip give a zip containing only content as file name
+func singleFileZip(name string, content io.Reader) (zipper.ZipReadCloser, error) {
+	zipFile, err := ioutil.TempFile("", "downloads-maven")
+	if err != nil {
+		return nil, err
+	}
+	cleanFunc := func() error {
+		return os.Remove(zipFile.Name())
+	}
+	zipWriter := zip.NewWriter(zipFile)
+	fh := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	fh.SetMode(0644)
+	w, err := zipWriter.CreateHeader(fh)
+	if err == nil {
+		_, err = io.Copy(w, content)
+	}
+	if err == nil {
+		err = zipWriter.Close()
+	}
+	zipFile.Close()
+	if err != nil {
+		cleanFunc()
+		return nil, err
+	}
+	file, err := os.Open(zipFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	fs, _ := file.Stat()
+	return zipper.NewZipFile(file, fs.Size(), cleanFunc), nil
+}
+
+// mavenArtifactSource give url of artifact in configured maven repository when uri is given as maven coordinates
+func (l Launcher) mavenArtifactSource(uri string) (string, bool) {
+	if !config.IsMavenUri(uri) {
+		return "", false
+	}
+	coords, err := config.ParseMavenUri(uri)
+	if err != nil {
+		return "", false
+	}
+	return coords.Url(l.sConfig.MavenRepository), true
+}
@@ -0,0 +1,77 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// materializeOutputs set env vars of outputs declared by sidecars, they are given to app and all sidecars,
+// ports chosen by launcher are kept when outputs are materialized again on reload
+func (l Launcher) materializeOutputs() error {
+	appDir, err := filepath.Abs(l.sConfig.Dir)
+	if err != nil {
+		return err
+	}
+	base := utils.OsEnvToMap()
+	outputsEnv := make(map[string]string)
+	owners := make(map[string]string)
+	for _, sidecar := range l.sConfig.Sidecars {
+		for _, output := range sidecar.Outputs {
+			key := output.EnvKey(sidecar.Name)
+			if owner, ok := owners[key]; ok {
+				return fmt.Errorf("Output env var %s is given by both sidecars %s and %s", key, owner, sidecar.Name)
+			}
+			owners[key] = sidecar.Name
+			value, err := outputValue(base, appDir, output, l.outputsEnv[key])
+			if err != nil {
+				return NewSidecarError(sidecar, fmt.Errorf("Invalid output %s: %s", output.Name, err.Error()))
+			}
+			outputsEnv[key] = value
+		}
+	}
+	for key := range l.outputsEnv {
+		delete(l.outputsEnv, key)
+	}
+	for key, value := range outputsEnv {
+		l.outputsEnv[key] = value
+	}
+	return nil
+}
+
+// outputValue give value of output, previous value is only used for a port chosen by launcher
+func outputValue(base map[string]string, appDir string, output *config.Output, previous string) (string, error) {
+	if output.Type == config.OutputTypePort && output.Value == "" {
+		if previous != "" {
+			return previous, nil
+		}
+		port, err := utils.FreePort()
+		if err != nil {
+			return "", err
+		}
+		return strconv.Itoa(port), nil
+	}
+	value, err := TemplatingFromEnv(base, output.Value)
+	if err != nil {
+		return "", err
+	}
+	if output.Type == config.OutputTypePort {
+		_, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("Port %s is not a number", value)
+		}
+		return value, nil
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(appDir, value)
+	}
+	// sidecar can create its socket or file without creating parent dirs
+	err = os.MkdirAll(filepath.Dir(value), 0755)
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
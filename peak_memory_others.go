@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package sidecars
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// peakMemory give peak resident set size in bytes of an exited process and of its waited children
+func peakMemory(state *os.ProcessState) uint64 {
+	if state == nil {
+		return 0
+	}
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage.Maxrss <= 0 {
+		return 0
+	}
+	// max rss is given in bytes on darwin and in kilobytes on other systems
+	if runtime.GOOS == "darwin" {
+		return uint64(rusage.Maxrss)
+	}
+	return uint64(rusage.Maxrss) * 1024
+}
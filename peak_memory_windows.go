@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package sidecars
+
+import (
+	"os"
+)
+
+// peakMemory is not available on windows
+func peakMemory(state *os.ProcessState) uint64 {
+	return 0
+}
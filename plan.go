@@ -0,0 +1,321 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PlanSchemaVersion is version of plan schema, it only changes when a field is removed or changes its meaning
+const PlanSchemaVersion = 1
+
+// appProcessName is name of process running app
+const appProcessName = "launcher"
+
+// planEnvMask replace env values in plan when they are not asked to be shown
+const planEnvMask = "*****"
+
+// Plan is what launcher would run with current config, without downloading or starting anything
+type Plan struct {
+	SchemaVersion int           `json:"schema_version"`
+	Dir           string        `json:"dir"`
+	Starter       string        `json:"starter"`
+	App           *PlanApp      `json:"app"`
+	Sidecars      []PlanSidecar `json:"sidecars"`
+	// Order is names of processes in start order, a process starts once all processes in its start_after are started
+	Order []string `json:"order"`
+}
+
+type PlanApp struct {
+	// Command is app command from config, when empty command is detected by starter at launch
+	Command string `json:"command"`
+	Port    int    `json:"port"`
+	// Env is env vars set on app by launcher and by app_env of sidecars
+	Env map[string]string `json:"env"`
+}
+
+type PlanSidecar struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Group      string            `json:"group"`
+	Executable string            `json:"executable"`
+	Command    *config.Command   `json:"command"`
+	Args       []string          `json:"args"`
+	WorkDir    string            `json:"work_dir"`
+	Artifact   *PlanArtifact     `json:"artifact"`
+	IsRproxy   bool              `json:"is_rproxy"`
+	Port       int               `json:"port,omitempty"`
+	AppPort    int               `json:"app_port,omitempty"`
+	Instances  []string          `json:"instances"`
+	Env        map[string]string `json:"env"`
+	StartAfter []string          `json:"start_after"`
+	StartDelay int               `json:"start_delay"`
+}
+
+type PlanArtifact struct {
+	URI string `json:"uri"`
+	// Type is type of artifact, given in config or else detected from uri
+	Type string `json:"type"`
+	// Source is uri where artifact is downloaded from once resolved (e.g.: maven coordinates as an url)
+	Source     string `json:"source"`
+	Sha1       string `json:"sha1"`
+	InstallDir string `json:"install_dir"`
+}
+
+// Plan give resolved model of what would be run by launcher: sidecars with their resolved artifacts, ports, env and start order.
+// Env only contains env vars set by config and launcher, env inherited from launcher env is left out.
+func (l Launcher) Plan() (*Plan, error) {
+	// manifests are merged in a copy of sidecars to not change config of launcher while planning
+	sidecars, err := copySidecars(l.sConfig.Sidecars)
+	if err != nil {
+		return nil, err
+	}
+	l.sConfig.Sidecars = sidecars
+	hasStarter := l.cStarter != nil && !l.sConfig.NoStarter
+	plan := &Plan{
+		SchemaVersion: PlanSchemaVersion,
+		Dir:           l.sConfig.Dir,
+		Sidecars:      make([]PlanSidecar, len(l.sConfig.Sidecars)),
+	}
+	if hasStarter {
+		plan.Starter = l.cStarter.Name()
+	}
+	refs := l.sidecarRefs()
+	refs.proxyEnvs = make(map[string]map[string]string)
+	appPort := l.appPort
+	appEnv := make(map[string]string)
+	for i, sidecar := range l.sConfig.Sidecars {
+		// manifest can only be read when artifact is already installed, sidecar is kept as configured otherwise
+		if _, err := os.Stat(SidecarInstallDir(l.sConfig.Dir, sidecar)); err == nil {
+			err = l.applyManifest(sidecar)
+			if err != nil {
+				return nil, err
+			}
+		}
+		planSidecar := PlanSidecar{
+			Name:       sidecar.Name,
+			Type:       sidecar.Type,
+			Group:      sidecar.Group,
+			Executable: sidecar.Executable,
+			Command:    sidecar.Command,
+			WorkDir:    sidecar.WorkDir,
+			IsRproxy:   sidecar.IsRproxy,
+			Instances:  make([]string, 0),
+			StartDelay: sidecar.StartDelay,
+		}
+		if sidecar.ArtifactURI != "" {
+			planSidecar.Artifact = l.planArtifact(sidecar)
+		}
+		if sidecar.Instances > 1 {
+			for _, instance := range sidecarInstances(sidecar) {
+				planSidecar.Instances = append(planSidecar.Instances, instance.Name)
+			}
+		}
+		if sidecar.IsRproxy {
+			proxyEnv := make(map[string]string)
+			if hasStarter {
+				proxyEnv = l.cStarter.ProxyEnv(appPort)
+			}
+			listenPort := 0
+			if hasStarter {
+				listenPort = appPort
+			}
+			planSidecar.Port = appPort
+			appPort++
+			planSidecar.AppPort = appPort
+			proxyEnv = utils.MergeEnv(proxyEnv, l.proxyUpstreamEnv(i, listenPort, appPort))
+			refs.proxyEnvs[sidecar.Name] = proxyEnv
+		}
+		plan.Sidecars[i] = planSidecar
+	}
+	for i, sidecar := range l.sConfig.Sidecars {
+		env, err := refs.env(sidecar)
+		if err != nil {
+			return nil, NewSidecarError(sidecar, err)
+		}
+		plan.Sidecars[i].Env = ownEnv(env, sidecar.Env, refs.keyNames.forSidecar(sidecar).rename(refs.proxyEnvs[sidecar.Name]))
+		plan.Sidecars[i].Args, err = TemplatingArgs(env, sidecar.Args...)
+		if err != nil {
+			return nil, NewSidecarError(sidecar, err)
+		}
+		appEnvUnTpl, err := refs.templateValues(utils.MergeEnv(refs.base, appEnv), sidecar.AppEnv)
+		if err != nil {
+			return nil, NewSidecarError(sidecar, err)
+		}
+		appEnv = utils.MergeEnv(appEnv, appEnvUnTpl)
+	}
+	if !l.sConfig.NoStarter {
+		if hasStarter && appPort != l.appPort {
+			appEnv = utils.MergeEnv(appEnv, refs.keyNames.rename(l.cStarter.ProxyEnv(appPort)))
+		}
+		plan.App = &PlanApp{
+			Command: l.sConfig.AppCommand,
+			Port:    appPort,
+			Env:     appEnv,
+		}
+	}
+	plan.Order = l.planOrder(plan)
+	return plan, nil
+}
+
+func (l Launcher) planArtifact(sidecar *config.Sidecar) *PlanArtifact {
+	source, fileType := l.artifactSource(sidecar)
+	if fileType == "" {
+		if h, err := zipper.FindHandler(source, ""); err == nil {
+			fileType = h.Name()
+		}
+	}
+	return &PlanArtifact{
+		URI:        sidecar.ArtifactURI,
+		Type:       fileType,
+		Source:     source,
+		Sha1:       sidecar.ArtifactSha1,
+		InstallDir: sidecarInstallRelDir(sidecar),
+	}
+}
+
+// ownEnv give values of env for keys set by config or by launcher
+func ownEnv(env map[string]string, sets ...map[string]string) map[string]string {
+	own := make(map[string]string)
+	for _, set := range sets {
+		for k := range set {
+			own[k] = env[k]
+		}
+	}
+	return own
+}
+
+// planOrder set processes to start before each sidecar and give processes in start order,
+// it follows group_order and start_order as launcher does
+func (l Launcher) planOrder(plan *Plan) []string {
+	depths := make(map[string]int)
+	if plan.App != nil {
+		depths[appProcessName] = 0
+	}
+	for i, sidecar := range l.sConfig.Sidecars {
+		startAfter := make([]string, 0)
+		for j, g := range l.sConfig.GroupOrder {
+			if g != sidecar.Group || sidecar.Group == "" {
+				continue
+			}
+			for _, other := range l.sConfig.Sidecars {
+				if other.Group != "" && utils.InStrings(other.Group, l.sConfig.GroupOrder[:j]) {
+					startAfter = append(startAfter, other.Name)
+				}
+			}
+			break
+		}
+		if sidecar.StartOrder == config.StartOrderAfterApp && plan.App != nil {
+			startAfter = append(startAfter, appProcessName)
+		}
+		plan.Sidecars[i].StartAfter = startAfter
+	}
+	var depth func(name string) int
+	depth = func(name string) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		d := 0
+		for _, sidecar := range plan.Sidecars {
+			if sidecar.Name != name {
+				continue
+			}
+			for _, previous := range sidecar.StartAfter {
+				if pd := depth(previous) + 1; pd > d {
+					d = pd
+				}
+			}
+		}
+		depths[name] = d
+		return d
+	}
+	order := make([]string, 0)
+	for _, sidecar := range plan.Sidecars {
+		depth(sidecar.Name)
+		order = append(order, sidecar.Name)
+	}
+	if plan.App != nil {
+		order = append(order, appProcessName)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return depths[order[i]] < depths[order[j]]
+	})
+	return order
+}
+
+// MaskEnv replace env values of plan by a mask, they can be secrets
+func (p *Plan) MaskEnv() {
+	mask := func(env map[string]string) {
+		for k := range env {
+			env[k] = planEnvMask
+		}
+	}
+	if p.App != nil {
+		mask(p.App.Env)
+	}
+	for _, sidecar := range p.Sidecars {
+		mask(sidecar.Env)
+	}
+}
+
+// ShowPlan print plan as json or as a table in start order, env values are masked in json unless showEnv is true
+func (l Launcher) ShowPlan(asJson, showEnv bool) error {
+	plan, err := l.Plan()
+	if err != nil {
+		return err
+	}
+	if !showEnv {
+		plan.MaskEnv()
+	}
+	if asJson {
+		enc := json.NewEncoder(l.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+	table := newTable(l.stdout)
+	table.SetHeader([]string{"Process", "Start After", "Ports", "Artifact", "Env"})
+	for _, name := range plan.Order {
+		if name == appProcessName && plan.App != nil {
+			table.Append([]string{name, "-", fmt.Sprintf("%d", plan.App.Port), "-", envKeys(plan.App.Env)})
+			continue
+		}
+		for _, sidecar := range plan.Sidecars {
+			if sidecar.Name != name {
+				continue
+			}
+			startAfter := "-"
+			if len(sidecar.StartAfter) > 0 {
+				startAfter = strings.Join(sidecar.StartAfter, ",")
+			}
+			ports := "-"
+			if sidecar.IsRproxy {
+				ports = fmt.Sprintf("%d -> %d", sidecar.Port, sidecar.AppPort)
+			}
+			artifact := "-"
+			if sidecar.Artifact != nil {
+				artifact = sidecar.Artifact.Source
+			}
+			table.Append([]string{name, startAfter, ports, artifact, envKeys(sidecar.Env)})
+		}
+	}
+	table.Render()
+	return nil
+}
+
+// envKeys give sorted keys of env, values are not shown as they can be secrets
+func envKeys(env map[string]string) string {
+	if len(env) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
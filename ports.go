@@ -0,0 +1,53 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"net"
+)
+
+const (
+	PortConflictFail       = config.PortConflictFail
+	PortConflictReallocate = config.PortConflictReallocate
+	PortConflictIgnore     = config.PortConflictIgnore
+)
+
+// ensurePortFree check that nothing already listen on port which will be used by listener,
+// when port is in use and reallocation is allowed a free port is given instead
+func (l Launcher) ensurePortFree(port int, listener string, canReallocate bool) (int, error) {
+	policy := l.sConfig.PortConflict
+	if policy == PortConflictIgnore || isPortFree(port) {
+		return port, nil
+	}
+	if policy == PortConflictReallocate && canReallocate {
+		newPort, err := utils.FreePort()
+		if err != nil {
+			return port, err
+		}
+		log.WithField("component", "Launcher").
+			Warnf("Port %d needed by %s is already in use, port %d is used instead", port, listener, newPort)
+		return newPort, nil
+	}
+	return port, fmt.Errorf("Port %d needed by %s is already in use", port, listener)
+}
+
+func isPortFree(port int) bool {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// upstreamListener give name of process which will listen on upstream port of rproxy sidecar at index i
+func (l Launcher) upstreamListener(i int) string {
+	for _, sidecar := range l.sConfig.Sidecars[i+1:] {
+		if sidecar.IsRproxy {
+			return fmt.Sprintf("sidecar %s", sidecar.Name)
+		}
+	}
+	return "app"
+}
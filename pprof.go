@@ -0,0 +1,48 @@
+package sidecars
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// PprofAddrEnvKey is env var giving address where profiles of launcher are served, same as launch --pprof-addr
+const PprofAddrEnvKey = "SIDECARS_PPROF_ADDR"
+
+// SetPprofAddr serve net/http/pprof profiles of launcher itself on addr (e.g.: 127.0.0.1:6060) while launched,
+// profiles are served without authentication and should only be listened on localhost
+func (l *Launcher) SetPprofAddr(addr string) {
+	l.pprofAddr = addr
+}
+
+// startPprofServer serve pprof handlers on addr and give func to stop serving
+func startPprofServer(addr string) (func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// handlers are not registered on default mux to not be exposed by another server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	server := &http.Server{Handler: mux}
+	entry := log.WithField("component", "pprof")
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			entry.Warnf("Pprof server stopped: %s", err.Error())
+		}
+	}()
+	entry.Infof("Serving profiles of launcher on http://%s/debug/pprof/", listener.Addr().String())
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
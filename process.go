@@ -1,31 +1,149 @@
 package sidecars
 
 import (
+	"errors"
 	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"os/exec"
 	"sync"
 	"syscall"
+	"time"
 )
 
+const (
+	ProcessStateCreated = "created"
+	ProcessStateWaiting = "waiting"
+	ProcessStateRunning = "running"
+	ProcessStateExited  = "exited"
+	ProcessStateFailed  = "failed"
+
+	// processRestartTimeout is time given to a process to stop when restarting before being killed
+	processRestartTimeout = 10 * time.Second
+	startAfterInterval    = 100 * time.Millisecond
+)
+
+type ProcessStatus struct {
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Group     string     `json:"group,omitempty"`
+	State     string     `json:"state"`
+	Pid       int        `json:"pid,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	ExitedAt  *time.Time `json:"exited_at,omitempty"`
+	ExitCode  *int       `json:"exit_code,omitempty"`
+	Signal    string     `json:"signal,omitempty"`
+	// PeakMemory is peak resident set size in bytes of last run of process
+	PeakMemory uint64 `json:"peak_memory_bytes,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
 type process struct {
-	cmd             *exec.Cmd
-	cmdHandler      CmdHandler
-	name            string
-	typeP           string
-	noInterrupt     bool
-	alwaysInterrupt bool
-	errChan         chan error
-	signalChan      chan os.Signal
-	wg              *sync.WaitGroup
+	cmd              *exec.Cmd
+	cmdHandler       CmdHandler
+	name             string
+	typeP            string
+	group            string
+	forwardSignals   []string
+	ignoreStopSignal bool
+	isRproxy         bool
+	noInterrupt      bool
+	alwaysInterrupt  bool
+	errChan          chan error
+	signalChan       chan os.Signal
+	wg               *sync.WaitGroup
+	output           *RingBuffer
+	metrics          *StatsdClient
+	events           *EventEmitter
+	startedChan      chan *process
+	waitFor          []*config.WaitFor
+	startAfter       []*process
+	listenAddr       string
+	startDelay       time.Duration
+	stopChan         chan struct{}
+	exited           chan struct{}
+	removeChan       chan struct{}
+	rebuild          func() (*exec.Cmd, CmdHandler, error)
+	drain            func() error
+	collectCoreDump  func(cmd *exec.Cmd, startedAt time.Time)
+	rollingRestart   func() error
+	mu               sync.Mutex
+	state            string
+	runs             int
+	restarting       bool
+	removed          bool
+	startedAt        time.Time
+	exitedAt         time.Time
+	exitErr          error
+	exitCode         int
+	exitSignal       string
+	peakMemory       uint64
+	failureCode      int
+	failedAt         time.Time
 }
 
 func (p *process) Start() {
 	entry := log.WithField(p.typeP, p.name)
 	entry.Infof("Starting %s %s ...", p.typeP, p.name)
 	defer p.wg.Done()
-	err := p.cmdHandler.Run()
+	defer close(p.exited)
+	stop := p.waitStopChan()
+	if p.startDelay > 0 {
+		p.setState(ProcessStateWaiting, nil)
+		entry.Infof("Delaying start of %s %s by %s ...", p.typeP, p.name, p.startDelay)
+		select {
+		case <-stop:
+			p.setState(ProcessStateExited, nil)
+			return
+		case <-time.After(p.startDelay):
+		}
+	}
+	if len(p.startAfter) > 0 {
+		p.setState(ProcessStateWaiting, nil)
+		err := waitForProcesses(p.startAfter, stop, entry)
+		if err == errWaitStopped {
+			p.setState(ProcessStateExited, nil)
+			return
+		}
+		if err != nil {
+			entry.Warnf("%s %s is not started: %s", p.typeP, p.name, err.Error())
+			p.setState(ProcessStateExited, err)
+			return
+		}
+	}
+	if len(p.waitFor) > 0 {
+		p.setState(ProcessStateWaiting, nil)
+		err := waitForDependencies(p.waitFor, stop, entry)
+		if err == errWaitStopped {
+			p.setState(ProcessStateExited, nil)
+			return
+		}
+		if err != nil {
+			p.setState(ProcessStateFailed, err)
+			p.metrics.Incr(MetricProbeFailed, p.metricTags())
+			p.events.emitProcess(EventProbeFailed, p, err)
+			p.handleError(entry, err)
+			return
+		}
+	}
+	err := p.run()
+	for p.shouldRestart() {
+		entry.Infof("Restarting %s %s ...", p.typeP, p.name)
+		p.metrics.Incr(MetricProcessRestarted, p.metricTags())
+		p.events.emitProcess(EventRestarting, p, nil)
+		err = p.rebuildCmd()
+		if err != nil {
+			p.setState(ProcessStateFailed, err)
+			break
+		}
+		err = p.run()
+	}
+	if p.isRemoved() {
+		entry.Infof("%s %s has been stopped.", p.typeP, p.name)
+		return
+	}
 	if err != nil {
 		// if this come from a signal, we do not considered this as an error
 		select {
@@ -33,15 +151,344 @@ func (p *process) Start() {
 			return
 		default:
 		}
-		errMess := fmt.Sprintf("Error occurred on %s %s: %s", p.typeP, p.name, err.Error())
-		entry.Error(errMess)
-		if !p.noInterrupt {
-			p.errChan <- fmt.Errorf(errMess)
-			p.signalChan <- syscall.SIGINT
+		p.handleError(entry, err)
+		return
+	}
+	// if process stopped we should stop all other processes
+	if p.alwaysInterrupt {
+		p.signalChan <- syscall.SIGINT
+	}
+}
+
+// run start process command and wait for it to exit
+func (p *process) run() error {
+	err := p.cmdHandler.Start()
+	if err == nil {
+		p.setState(ProcessStateRunning, nil)
+		p.metrics.Incr(MetricProcessStarted, p.metricTags())
+		p.events.emitProcess(EventStarted, p, nil)
+		if p.Runs() == 1 {
+			select {
+			case p.startedChan <- p:
+			default:
+			}
+		}
+		err = p.cmdHandler.Wait()
+		p.setExitStatus(err)
+		p.logPeakMemory()
+		if p.collectCoreDump != nil && coreDumped(err) {
+			// margin is kept as modification time of core dump can be less precise than start time
+			p.collectCoreDump(p.cmd, p.Status().StartedAt.Add(-time.Second))
+		}
+	}
+	if err != nil {
+		p.setState(ProcessStateFailed, err)
+	} else {
+		p.setState(ProcessStateExited, nil)
+	}
+	exitTags := p.metricTags()
+	exitTags["state"] = p.Status().State
+	p.metrics.Incr(MetricProcessExited, exitTags)
+	p.events.emitProcess(EventExited, p, err)
+	return err
+}
+
+// Restart stop process to start it again, process exit is not taken as an error
+// and process is killed if it is still running after processRestartTimeout
+func (p *process) Restart() error {
+	if p.rollingRestart != nil {
+		return p.rollingRestart()
+	}
+	if p.rebuild == nil {
+		return fmt.Errorf("%s %s cannot be restarted", p.typeP, p.name)
+	}
+	select {
+	case <-p.stopChan:
+		return fmt.Errorf("Launcher is stopping")
+	default:
+	}
+	p.mu.Lock()
+	if p.state != ProcessStateRunning || p.restarting || p.removed {
+		p.mu.Unlock()
+		return fmt.Errorf("%s %s is not running", p.typeP, p.name)
+	}
+	p.restarting = true
+	cmd := p.cmd
+	run := p.runs
+	p.mu.Unlock()
+	log.WithField(p.typeP, p.name).Infof("Stopping %s %s for restart ...", p.typeP, p.name)
+	err := signalProcessGroup(cmd, syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+	go func() {
+		time.Sleep(processRestartTimeout)
+		p.mu.Lock()
+		stillRunning := p.runs == run && p.state == ProcessStateRunning
+		p.mu.Unlock()
+		if stillRunning {
+			signalProcessGroup(cmd, syscall.SIGKILL)
+		}
+	}()
+	return nil
+}
+
+// shouldRestart tell if process has exited because of a restart
+func (p *process) shouldRestart() bool {
+	p.mu.Lock()
+	restarting := p.restarting && !p.removed
+	p.restarting = false
+	p.mu.Unlock()
+	if !restarting {
+		return false
+	}
+	select {
+	case <-p.stopChan:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *process) rebuildCmd() error {
+	cmd, cmdHandler, err := p.rebuild()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cmd = cmd
+	p.cmdHandler = cmdHandler
+	return nil
+}
+
+// Stop stop process for good without stopping others, its exit is not taken as an error
+// and process is killed if it is still running after timeout
+func (p *process) Stop(timeout time.Duration) {
+	p.mu.Lock()
+	if p.removed {
+		p.mu.Unlock()
+		<-p.exited
+		return
+	}
+	p.removed = true
+	p.mu.Unlock()
+	close(p.removeChan)
+	if p.IsRunning() {
+		p.events.emitProcess(EventStopping, p, nil)
+		if p.drain != nil {
+			err := p.drain()
+			if err != nil {
+				log.WithField(p.typeP, p.name).Warn(err.Error())
+			}
+		}
+		p.Signal(syscall.SIGTERM)
+	}
+	select {
+	case <-p.exited:
+		return
+	case <-time.After(timeout):
+	}
+	if p.IsRunning() {
+		p.Signal(syscall.SIGKILL)
+	}
+	<-p.exited
+}
+
+// pid give pid of last started command of process, 0 if it has not been started
+func (p *process) pid() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+func (p *process) isRemoved() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.removed
+}
+
+// waitStopChan give a channel closed when launcher is stopping or when process is removed
+func (p *process) waitStopChan() <-chan struct{} {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-p.stopChan:
+		case <-p.removeChan:
+		case <-p.exited:
+		}
+		close(stop)
+	}()
+	return stop
+}
+
+// Runs give number of times process has been started
+func (p *process) Runs() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runs
+}
+
+// IsRunning check if process command is running
+func (p *process) IsRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state == ProcessStateRunning && p.cmd.Process != nil
+}
+
+// Signal send sig to process and all processes it has started
+func (p *process) Signal(sig os.Signal) error {
+	cmd := p.runningCmd()
+	if cmd == nil {
+		return fmt.Errorf("%s %s is not running", p.typeP, p.name)
+	}
+	return signalProcessGroup(cmd, sig)
+}
+
+// forwardSignal send sig to process only, this let process forward it by itself to its children if needed
+func (p *process) forwardSignal(sig os.Signal) error {
+	cmd := p.runningCmd()
+	if cmd == nil {
+		return fmt.Errorf("%s %s is not running", p.typeP, p.name)
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// runningCmd give command of process if it is running, nil otherwise,
+// command and its os process are read under lock as restart replaces them
+func (p *process) runningCmd() *exec.Cmd {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != ProcessStateRunning || p.cmd == nil || p.cmd.Process == nil {
+		return nil
+	}
+	return p.cmd
+}
+
+// signalProcessGroup send sig to process and all processes in its group
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if utils.HasPgidSysProcAttr(cmd.SysProcAttr) && cmd.Process.Pid > 0 {
+		group, err := os.FindProcess(-cmd.Process.Pid)
+		if err == nil {
+			return group.Signal(sig)
 		}
 	}
+	return cmd.Process.Signal(sig)
+}
+
+func (p *process) handleError(entry *log.Entry, err error) {
+	errMess := fmt.Sprintf("Error occurred on %s %s: %s", p.typeP, p.name, err.Error())
+	entry.Error(errMess)
+	p.mu.Lock()
+	p.failureCode = exitCode(err)
+	p.failedAt = time.Now()
+	p.mu.Unlock()
+	if !p.noInterrupt {
+		p.errChan <- errors.New(errMess)
+		p.signalChan <- syscall.SIGINT
+		return
+	}
 	// if process stopped we should stop all other processes
 	if p.alwaysInterrupt {
 		p.signalChan <- syscall.SIGINT
 	}
 }
+
+func (p *process) setState(state string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = state
+	p.exitErr = err
+	if state == ProcessStateWaiting {
+		return
+	}
+	if state == ProcessStateRunning {
+		p.startedAt = time.Now()
+		p.exitedAt = time.Time{}
+		p.runs++
+		return
+	}
+	p.exitedAt = time.Now()
+}
+
+// logPeakMemory keep and log peak memory of exited process to help sizing memory quota of app
+func (p *process) logPeakMemory() {
+	peak := peakMemory(p.cmd.ProcessState)
+	if peak == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.peakMemory = peak
+	p.mu.Unlock()
+	log.WithField(p.typeP, p.name).Infof("Peak memory usage of %s %s: %.1f MB", p.typeP, p.name, float64(peak)/bytesPerMB)
+}
+
+// setExitStatus keep exit code and signal of last run of process
+func (p *process) setExitStatus(err error) {
+	code, sig := exitStatus(err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exitCode = code
+	p.exitSignal = sig
+}
+
+func (p *process) Status() ProcessStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := ProcessStatus{
+		Name:  p.name,
+		Type:  p.typeP,
+		Group: p.group,
+		State: p.state,
+	}
+	if status.State == "" {
+		status.State = ProcessStateCreated
+	}
+	if !p.startedAt.IsZero() {
+		startedAt := p.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !p.exitedAt.IsZero() {
+		exitedAt := p.exitedAt
+		status.ExitedAt = &exitedAt
+	}
+	if status.State != ProcessStateRunning && p.runs > 0 {
+		exitCode := p.exitCode
+		status.ExitCode = &exitCode
+		status.Signal = p.exitSignal
+		status.PeakMemory = p.peakMemory
+	}
+	if status.State == ProcessStateRunning && p.cmd.Process != nil {
+		status.Pid = p.cmd.Process.Pid
+	}
+	if p.exitErr != nil {
+		status.Error = p.exitErr.Error()
+	}
+	return status
+}
+
+// Failure give exit code of process and when it exited if it failed without being stopped by launcher,
+// code is 0 if process did not fail
+func (p *process) Failure() (int, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failureCode, p.failedAt
+}
+
+func (p *process) metricTags() map[string]string {
+	return map[string]string{
+		"process": p.name,
+		"type":    p.typeP,
+	}
+}
+
+// Output give last output lines written by process
+func (p *process) Output() []byte {
+	if p.output == nil {
+		return []byte{}
+	}
+	return p.output.Bytes()
+}
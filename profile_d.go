@@ -0,0 +1,57 @@
+package sidecars
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/starter"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const sourceProfileDScript = `
+set -e
+for env_file in .profile.d/*.sh; do
+  if [ -f "$env_file" ]; then
+    source "$env_file"
+  fi
+done
+env -0
+`
+
+// sourceProfileD source .profile.d/*.sh of app root, as cloud foundry launcher does,
+// and set resulting env in launcher env to let sidecars and app get env provided by buildpacks
+func (l Launcher) sourceProfileD() error {
+	entry := log.WithField("component", "Launcher")
+	if _, ok := l.cStarter.(starter.CloudFoundry); ok && !l.sConfig.NoStarter {
+		entry.Debug("Profile.d scripts are already sourced by cloud foundry launcher")
+		return nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("bash", "-c", sourceProfileDScript)
+	cmd.Dir = wd
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("Could not source .profile.d scripts: %s %s", err.Error(), strings.TrimSpace(stderr.String()))
+	}
+	changed := 0
+	for _, kv := range strings.Split(string(out), "\x00") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[0] == "_" || parts[0] == "SHLVL" || parts[0] == "PWD" {
+			continue
+		}
+		if current, ok := os.LookupEnv(parts[0]); ok && current == parts[1] {
+			continue
+		}
+		os.Setenv(parts[0], parts[1])
+		changed++
+	}
+	entry.Infof("Sourced .profile.d scripts, %d env vars have been set", changed)
+	return nil
+}
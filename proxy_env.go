@@ -0,0 +1,76 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"net"
+	"strconv"
+	"sync"
+)
+
+const (
+	ProxyAppHostEnvKey     = "PROXY_APP_HOST"
+	ProxyAppSchemeEnvKey   = "PROXY_APP_SCHEME"
+	ProxyAppUrlEnvKey      = "PROXY_APP_URL"
+	ProxyBindAddressEnvKey = "PROXY_BIND_ADDRESS"
+	ProxyListenAddrEnvKey  = "PROXY_LISTEN_ADDR"
+)
+
+// upstreamEnvKeys are env vars of reverse proxy sidecars describing where they forward requests
+var upstreamEnvKeys = []string{ProxyAppPortEnvKey, ProxyAppHostEnvKey, ProxyAppSchemeEnvKey, ProxyAppUrlEnvKey}
+
+var loopback struct {
+	once sync.Once
+	host string
+}
+
+// loopbackHost give 127.0.0.1 or ::1 on ipv6 only containers
+func loopbackHost() string {
+	loopback.once.Do(func() {
+		loopback.host = "127.0.0.1"
+		if ln, err := net.Listen("tcp4", "127.0.0.1:0"); err == nil {
+			ln.Close()
+			return
+		}
+		if ln, err := net.Listen("tcp6", "[::1]:0"); err == nil {
+			ln.Close()
+			loopback.host = "::1"
+		}
+	})
+	return loopback.host
+}
+
+// proxyUpstreamEnv give env vars of reverse proxy sidecar at index i of sidecars forwarding requests to appPort:
+// host and scheme of app are used when proxy forwards to app, other reverse proxy sidecars are reached with http on loopback.
+// Address to listen on is given when listenPort is known.
+func (l Launcher) proxyUpstreamEnv(i int, listenPort, appPort int) map[string]string {
+	host := loopbackHost()
+	scheme := "http"
+	if l.upstreamListener(i) == "app" {
+		if l.sConfig.AppHost != "" {
+			host = l.sConfig.AppHost
+		}
+		if l.sConfig.AppScheme != "" {
+			scheme = l.sConfig.AppScheme
+		}
+	}
+	sPort := strconv.Itoa(appPort)
+	env := map[string]string{
+		ProxyAppPortEnvKey:   sPort,
+		ProxyAppHostEnvKey:   host,
+		ProxyAppSchemeEnvKey: scheme,
+		ProxyAppUrlEnvKey:    scheme + "://" + net.JoinHostPort(host, sPort),
+	}
+	return utils.MergeEnv(env, l.proxyListenEnv(listenPort))
+}
+
+// proxyListenEnv give env vars giving address where a reverse proxy sidecar listening on listenPort must bind
+func (l Launcher) proxyListenEnv(listenPort int) map[string]string {
+	env := make(map[string]string)
+	if l.sConfig.ProxyBindAddress != "" {
+		env[ProxyBindAddressEnvKey] = l.sConfig.ProxyBindAddress
+	}
+	if listenPort > 0 {
+		env[ProxyListenAddrEnvKey] = net.JoinHostPort(l.sConfig.ProxyBindAddress, strconv.Itoa(listenPort))
+	}
+	return env
+}
@@ -0,0 +1,103 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReleaseMarker is written in release.json once release command has succeeded for a deploy
+type ReleaseMarker struct {
+	ReleaseId  string    `json:"release_id"`
+	ReleasedAt time.Time `json:"released_at"`
+}
+
+func ReleaseMarkerFilePath(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, "release.json")
+}
+
+// releaseId identify a deploy by release command and time of last setup,
+// without setup (e.g.: when running locally) release command is only run again when it changes
+func (l Launcher) releaseId() (string, error) {
+	state, err := loadSetupState(SetupStateFilePath(l.sConfig.Dir))
+	if err != nil {
+		return "", err
+	}
+	var setupAt time.Time
+	if state != nil {
+		setupAt = state.SetupAt
+	}
+	return hashJson([]interface{}{l.sConfig.Release.Command, setupAt})
+}
+
+// runRelease run release command when it must be run at phase and has not already succeeded for current deploy,
+// sidecars and app are not started when it fails
+func (l Launcher) runRelease(phase string) error {
+	release := l.sConfig.Release
+	if release == nil || !release.RunAt(phase) {
+		return nil
+	}
+	err := release.Check()
+	if err != nil {
+		return err
+	}
+	entry := log.WithField("component", "Launcher").WithField("command", "release")
+	instanceIndex := os.Getenv("CF_INSTANCE_INDEX")
+	if phase == config.ReleasePhaseLaunch && !release.AllInstances && instanceIndex != "" && instanceIndex != "0" {
+		entry.Info("Release command is only run by first app instance.")
+		return nil
+	}
+	id, err := l.releaseId()
+	if err != nil {
+		return err
+	}
+	markerFile := ReleaseMarkerFilePath(l.sConfig.Dir)
+	marker, err := loadReleaseMarker(markerFile)
+	if err != nil {
+		return err
+	}
+	if marker != nil && marker.ReleaseId == id {
+		entry.Infof("Release command has already been run at %s for this deploy.", marker.ReleasedAt.Format(time.RFC3339))
+		return nil
+	}
+	appDir, err := filepath.Abs(l.sConfig.Dir)
+	if err != nil {
+		return err
+	}
+	entry.Info("Running release command ...")
+	err = runScript(release.Command, appDir, os.Environ(), l.stdout, l.stderr, "[release]", release.TimeoutDuration())
+	if err != nil {
+		return fmt.Errorf("Release command failed: %s", err.Error())
+	}
+	entry.Info("Finished running release command.")
+	b, err := json.MarshalIndent(ReleaseMarker{ReleaseId: id, ReleasedAt: time.Now()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(markerFile), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(markerFile, b, 0644)
+}
+
+func loadReleaseMarker(markerFile string) (*ReleaseMarker, error) {
+	b, err := ioutil.ReadFile(markerFile)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var marker ReleaseMarker
+	err = json.Unmarshal(b, &marker)
+	if err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
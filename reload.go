@@ -0,0 +1,263 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/env"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+)
+
+// ConfigLoader give config to apply when launcher is reloaded
+type ConfigLoader func() (*config.Sidecars, error)
+
+// SetConfigLoader make launcher reload its config with loader when receiving SIGHUP
+func (l *Launcher) SetConfigLoader(loader ConfigLoader) {
+	l.configLoader = loader
+}
+
+// Reload load config again and apply sidecars changes on running processes:
+// removed sidecars are stopped, added ones are started and updated ones are restarted.
+// Reverse proxy chain and other config than sidecars can't be changed without restarting launcher.
+func (l *Launcher) Reload() error {
+	if l.configLoader == nil {
+		return fmt.Errorf("No config loader has been set, config cannot be reloaded")
+	}
+	if l.launched == nil {
+		return fmt.Errorf("Launcher has not been started")
+	}
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+	select {
+	case <-l.processFactory.stopChan:
+		return fmt.Errorf("Launcher is stopping")
+	default:
+	}
+	entry := log.WithField("component", "Launcher").WithField("command", "reload")
+	entry.Info("Reloading config ...")
+	newConf, err := l.configLoader()
+	if err != nil {
+		return err
+	}
+	newSidecars := filterSidecars(newConf.Sidecars, l.onlyNames, l.disabledNames)
+	if !sameRproxyChain(l.loadedSidecars, newSidecars) {
+		return fmt.Errorf("Reverse proxy sidecars have changed, launcher must be restarted to apply it")
+	}
+	oldConf, reloadedConf := l.sConfig, *newConf
+	oldConf.Sidecars, reloadedConf.Sidecars = nil, nil
+	if !reflect.DeepEqual(oldConf, reloadedConf) {
+		entry.Warn("Only sidecars changes are applied on reload, launcher must be restarted to apply other changes")
+	}
+	loadedSidecars, err := copySidecars(newSidecars)
+	if err != nil {
+		return err
+	}
+	removed, added, updated := diffSidecars(l.loadedSidecars, loadedSidecars)
+	if len(removed)+len(added)+len(updated) == 0 {
+		entry.Info("No sidecars changes found.")
+		return nil
+	}
+	for _, sidecar := range append(added, updated...) {
+		oldSidecar := sidecarByName(l.loadedSidecars, sidecar.Name)
+		if (oldSidecar == nil && len(sidecar.AppEnv) > 0) ||
+			(oldSidecar != nil && !reflect.DeepEqual(oldSidecar.AppEnv, sidecar.AppEnv)) {
+			entry.Warnf("App env of sidecar %s will be applied on next launcher restart", sidecar.Name)
+		}
+	}
+
+	toStart := append(added, updated...)
+	// sidecars to start are installed while old processes are still running,
+	// launcher state is only switched to new config when they are ready
+	oldSidecars := l.sConfig.Sidecars
+	oldOutputsEnv := env.Merge(l.outputsEnv)
+	envs, err := l.prepareReload(newSidecars, removed, toStart)
+	if err != nil {
+		l.sConfig.Sidecars = oldSidecars
+		for key := range l.outputsEnv {
+			delete(l.outputsEnv, key)
+		}
+		for key, value := range oldOutputsEnv {
+			l.outputsEnv[key] = value
+		}
+		return err
+	}
+	if !reflect.DeepEqual(oldOutputsEnv, l.outputsEnv) {
+		entry.Warn("Outputs of sidecars have changed, they will be given to app on next launcher restart")
+	}
+
+	for _, sidecar := range append(removed, updated...) {
+		if l.forwarders[sidecar.Name] != nil && sidecarByName(updated, sidecar.Name) != nil {
+			continue
+		}
+		for _, p := range l.sidecarProcesses(sidecar) {
+			entry.Infof("Stopping sidecar %s ...", p.name)
+			p.Stop(l.stopStageTimeout())
+			l.processFactory.RemoveProcess(p)
+			entry.Infof("Finished stopping sidecar %s.", p.name)
+		}
+	}
+	l.loadedSidecars = loadedSidecars
+	err = l.cleanNonExistingSidecars()
+	if err != nil {
+		entry.Warn(err.Error())
+	}
+
+	wg := l.processFactory.WaitGroup()
+	for _, sidecar := range newSidecars {
+		if sidecarByName(toStart, sidecar.Name) == nil {
+			continue
+		}
+		if old := l.processFactory.ProcessByName(sidecar.Name); old != nil && l.forwarders[sidecar.Name] != nil {
+			err := l.rollingRestart(old, sidecar)
+			if err != nil {
+				return NewSidecarError(sidecar, err)
+			}
+			continue
+		}
+		for _, instance := range sidecarInstances(sidecar) {
+			p, err := l.processFactory.FromSidecar(instance, envs[instance.Name])
+			if err != nil {
+				return NewSidecarError(sidecar, err)
+			}
+			p.startAfter = l.startAfterProcesses(p, l.processFactory.Processes())
+			wg.Add(1)
+			go p.Start()
+		}
+	}
+	entry.Infof("Finished reloading config: %d sidecars removed, %d added and %d updated.", len(removed), len(added), len(updated))
+	return nil
+}
+
+// prepareReload set new sidecars in config and install sidecars to start without stopping running processes,
+// env of each instance to start is given, except for reverse proxies restarted with rolling restart
+func (l *Launcher) prepareReload(newSidecars, removed, toStart []*config.Sidecar) (map[string]map[string]string, error) {
+	l.sConfig.Sidecars = newSidecars
+	err := l.materializeOutputs()
+	if err != nil {
+		return nil, err
+	}
+	// unchanged sidecars are still running from their install dir which must not be touched,
+	// removed ones are kept installed until they are stopped
+	upToDate := make(map[string]bool)
+	for _, sidecar := range newSidecars {
+		if sidecarByName(toStart, sidecar.Name) == nil {
+			upToDate[sidecar.Name] = true
+		}
+	}
+	for _, sidecar := range removed {
+		upToDate[sidecar.Name] = true
+	}
+	l.sConfig.Sidecars = append(append([]*config.Sidecar{}, newSidecars...), removed...)
+	err = l.downloadArtifacts(upToDate)
+	l.sConfig.Sidecars = newSidecars
+	if err != nil {
+		return nil, err
+	}
+	envs := make(map[string]map[string]string)
+	for _, sidecar := range newSidecars {
+		if sidecarByName(toStart, sidecar.Name) == nil {
+			continue
+		}
+		err := l.setupSidecarArtifact(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		err = l.applyManifest(sidecar)
+		if err != nil {
+			return nil, err
+		}
+		err = l.writeSecretFiles([]*config.Sidecar{sidecar})
+		if err != nil {
+			return nil, err
+		}
+		if l.forwarders[sidecar.Name] != nil && l.processFactory.ProcessByName(sidecar.Name) != nil {
+			continue
+		}
+		for _, instance := range sidecarInstances(sidecar) {
+			env, err := l.sidecarEnv(instance)
+			if err != nil {
+				return nil, NewSidecarError(sidecar, err)
+			}
+			envs[instance.Name] = env
+		}
+	}
+	return envs, nil
+}
+
+// sidecarEnv give env of a sidecar started after launch
+func (l Launcher) sidecarEnv(sidecar *config.Sidecar) (map[string]string, error) {
+	return l.sidecarRefs().env(sidecar)
+}
+
+// filterSidecars give sidecars matching only names (if any) and not matching disabled names
+func filterSidecars(sidecars []*config.Sidecar, only, disabled []string) []*config.Sidecar {
+	filtered := make([]*config.Sidecar, 0, len(sidecars))
+	for _, sidecar := range sidecars {
+		if len(only) > 0 && !sidecarMatches(sidecar, only) {
+			continue
+		}
+		if sidecarMatches(sidecar, disabled) || !sidecar.SupportsPlatform() {
+			continue
+		}
+		filtered = append(filtered, sidecar)
+	}
+	return filtered
+}
+
+// diffSidecars give sidecars removed from old ones, added in new ones and new sidecars which differ from old ones
+func diffSidecars(old, new []*config.Sidecar) (removed, added, updated []*config.Sidecar) {
+	for _, sidecar := range old {
+		if sidecarByName(new, sidecar.Name) == nil {
+			removed = append(removed, sidecar)
+		}
+	}
+	for _, sidecar := range new {
+		oldSidecar := sidecarByName(old, sidecar.Name)
+		if oldSidecar == nil {
+			added = append(added, sidecar)
+			continue
+		}
+		if !reflect.DeepEqual(oldSidecar, sidecar) {
+			updated = append(updated, sidecar)
+		}
+	}
+	return removed, added, updated
+}
+
+// sameRproxyChain check that reverse proxy sidecars are the same, in same order and with same rolling restart mode
+func sameRproxyChain(old, new []*config.Sidecar) bool {
+	rproxyNames := func(sidecars []*config.Sidecar) []string {
+		names := make([]string, 0)
+		for _, sidecar := range sidecars {
+			if sidecar.IsRproxy {
+				names = append(names, fmt.Sprintf("%s:%t", sidecar.Name, sidecar.RollingRestart))
+			}
+		}
+		return names
+	}
+	return reflect.DeepEqual(rproxyNames(old), rproxyNames(new))
+}
+
+func sidecarByName(sidecars []*config.Sidecar, name string) *config.Sidecar {
+	for _, sidecar := range sidecars {
+		if sidecar.Name == name {
+			return sidecar
+		}
+	}
+	return nil
+}
+
+// copySidecars give a deep copy of sidecars, sidecars given to processes are modified by templating
+func copySidecars(sidecars []*config.Sidecar) ([]*config.Sidecar, error) {
+	b, err := json.Marshal(sidecars)
+	if err != nil {
+		return nil, err
+	}
+	copied := make([]*config.Sidecar, 0, len(sidecars))
+	err = json.Unmarshal(b, &copied)
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,98 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReloadKeepsInstallDirOfUnchangedSidecar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	unchanged := &config.Sidecar{
+		Name:        "unchanged",
+		Executable:  "sidecar",
+		ArtifactURI: filepath.Join(dir, "unchanged.zip"),
+	}
+	removed := &config.Sidecar{Name: "removed", Executable: "sleep"}
+	sConfig := config.Sidecars{Dir: dir, Sidecars: []*config.Sidecar{unchanged, removed}}
+
+	installedFile := filepath.Join(SidecarInstallDir(dir, unchanged), "sidecar")
+	err = os.MkdirAll(filepath.Dir(installedFile), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(installedFile, []byte("installed"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLauncher(sConfig, nil, filepath.Join(dir, ".profile.d"), ioutil.Discard, ioutil.Discard, 8080)
+	l.loadedSidecars, err = copySidecars(sConfig.Sidecars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.launched = &launchState{done: make(chan struct{})}
+	l.SetConfigLoader(func() (*config.Sidecars, error) {
+		reloaded := sConfig
+		reloaded.Sidecars = []*config.Sidecar{unchanged}
+		return &reloaded, nil
+	})
+
+	err = l.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(installedFile)
+	if err != nil {
+		t.Fatalf("Install dir of unchanged sidecar has been wiped: %s", err.Error())
+	}
+	if string(b) != "installed" {
+		t.Fatalf("Expected installed file to be kept, got %q", string(b))
+	}
+}
+
+func TestReloadKeepsOldProcessesWhenSetupFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sidecar := &config.Sidecar{Name: "updated", Executable: "sleep"}
+	sConfig := config.Sidecars{Dir: dir, Sidecars: []*config.Sidecar{sidecar}}
+
+	l := NewLauncher(sConfig, nil, filepath.Join(dir, ".profile.d"), ioutil.Discard, ioutil.Discard, 8080)
+	l.loadedSidecars, err = copySidecars(sConfig.Sidecars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.launched = &launchState{done: make(chan struct{})}
+	running := runningProcess(t, "updated", "sidecar")
+	defer running.Signal(os.Kill)
+	l.processFactory.addProcess(running)
+	l.SetConfigLoader(func() (*config.Sidecars, error) {
+		reloaded := sConfig
+		reloaded.Sidecars = []*config.Sidecar{{
+			Name:        "updated",
+			Executable:  "sidecar",
+			ArtifactURI: filepath.Join(dir, "missing.zip"),
+		}}
+		return &reloaded, nil
+	})
+
+	err = l.Reload()
+	if err == nil {
+		t.Fatal("Expected reload to fail when artifact cannot be downloaded")
+	}
+	if !running.IsRunning() || l.processFactory.ProcessByName("updated") != running {
+		t.Fatal("Expected old process to be kept running")
+	}
+	if l.sConfig.Sidecars[0].ArtifactURI != "" || l.loadedSidecars[0].ArtifactURI != "" {
+		t.Fatal("Expected launcher to keep old config")
+	}
+}
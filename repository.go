@@ -0,0 +1,473 @@
+package sidecars
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// LatestVersionPlaceholder is replaced in path of repository artifacts by latest version found in repository
+	LatestVersionPlaceholder = "{latest}"
+
+	ArtifactoryApiKeyEnvKey = "ARTIFACTORY_API_KEY"
+	// NexusApiKeyEnvKey is a nexus user token in the form <name>:<passcode>
+	NexusApiKeyEnvKey = "NEXUS_API_KEY"
+
+	repositoryArtifactory = "artifactory"
+	repositoryNexus       = "nexus"
+)
+
+func init() {
+	zipper.AddHandler(NewArtifactoryHandler())
+	zipper.AddHandler(NewNexusHandler())
+}
+
+// RepositoryHandler is a zipper handler downloading artifacts from artifactory (artifactory://<host>/artifactory/<repo>/<path>)
+// or nexus (nexus://<host>/repository/<repo>/<path>) repositories, use artifactory+http:// or nexus+http:// for plain http.
+// Path can contain {latest} to use latest version found in repository and
+// downloaded artifact is verified with checksum given by repository.
+type RepositoryHandler struct {
+	kind string
+}
+
+func NewArtifactoryHandler() *RepositoryHandler {
+	return &RepositoryHandler{kind: repositoryArtifactory}
+}
+
+func NewNexusHandler() *RepositoryHandler {
+	return &RepositoryHandler{kind: repositoryNexus}
+}
+
+func (h RepositoryHandler) Zip(src *zipper.Source) (zipper.ZipReadCloser, error) {
+	repo, err := h.repository(src)
+	if err != nil {
+		return nil, err
+	}
+	asset, err := repo.resolve()
+	if err != nil {
+		return nil, err
+	}
+	tmpDir, err := ioutil.TempDir("", "repository-artifact")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	filePath := filepath.Join(tmpDir, path.Base(asset.path))
+	err = repo.download(asset, filePath)
+	if err != nil {
+		return nil, err
+	}
+	// local handler uncompress archives as http handler would do
+	return zipper.LocalHandler{}.Zip(zipper.NewSource(filePath))
+}
+
+func (h RepositoryHandler) Sha1(src *zipper.Source) (string, error) {
+	repo, err := h.repository(src)
+	if err != nil {
+		return "", err
+	}
+	asset, err := repo.resolve()
+	if err != nil {
+		return "", err
+	}
+	// sha1 of http handler is sha1 of file content which is already known by repository
+	if asset.sha1 != "" {
+		return asset.sha1, nil
+	}
+	httpSrc := zipper.NewSource(asset.downloadUrl)
+	zipper.SetCtxHttpClient(httpSrc, repo.client)
+	return zipper.HttpHandler{}.Sha1(httpSrc)
+}
+
+func (h RepositoryHandler) Detect(src *zipper.Source) bool {
+	return strings.HasPrefix(src.Path, h.kind+"://") || strings.HasPrefix(src.Path, h.kind+"+http://")
+}
+
+func (h RepositoryHandler) Name() string {
+	return h.kind
+}
+
+func (h RepositoryHandler) repository(src *zipper.Source) (*repository, error) {
+	repo, err := parseRepositoryUri(h.kind, src.Path)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport
+	if client := zipper.CtxHttpClient(src); client != nil && client.Transport != nil {
+		transport = client.Transport
+	}
+	repo.client = &http.Client{
+		Transport: &repositoryTransport{base: transport, host: repo.host, auth: repo.auth},
+	}
+	return repo, nil
+}
+
+type repository struct {
+	kind    string
+	host    string
+	baseUrl string
+	name    string
+	path    string
+	auth    func(req *http.Request)
+	client  *http.Client
+}
+
+type repositoryAsset struct {
+	path        string
+	downloadUrl string
+	sha1        string
+	sha256      string
+}
+
+// parseRepositoryUri split uri in repository base url, repository name and artifact path,
+// for artifactory a first path element named artifactory is considered as base path of artifactory
+func parseRepositoryUri(kind, uri string) (*repository, error) {
+	protocol := "https"
+	rest := strings.TrimPrefix(uri, kind+"://")
+	if strings.HasPrefix(uri, kind+"+http://") {
+		protocol = "http"
+		rest = strings.TrimPrefix(uri, kind+"+http://")
+	}
+	u, err := url.Parse(protocol + "://" + rest)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s uri '%s': %s", kind, uri, err.Error())
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	basePath := ""
+	switch kind {
+	case repositoryArtifactory:
+		if len(segments) > 0 && segments[0] == "artifactory" {
+			basePath = "/artifactory"
+			segments = segments[1:]
+		}
+	case repositoryNexus:
+		i := 0
+		for i < len(segments) && segments[i] != "repository" {
+			i++
+		}
+		if i == len(segments) {
+			return nil, fmt.Errorf("Invalid nexus uri '%s', it must be in the form nexus://<host>/repository/<repo>/<path>", uri)
+		}
+		basePath = strings.Join(append([]string{""}, segments[:i]...), "/")
+		segments = segments[i+1:]
+	}
+	if len(segments) < 2 || segments[0] == "" || segments[len(segments)-1] == "" {
+		return nil, fmt.Errorf("Invalid %s uri '%s', repository and path of artifact must be given", kind, uri)
+	}
+	repo := &repository{
+		kind:    kind,
+		host:    u.Host,
+		baseUrl: fmt.Sprintf("%s://%s%s", protocol, u.Host, basePath),
+		name:    segments[0],
+		path:    strings.Join(segments[1:], "/"),
+		auth:    repositoryAuth(kind, u.User),
+	}
+	return repo, nil
+}
+
+// repositoryAuth give function authenticating requests with credentials in uri or else with api key from env
+func repositoryAuth(kind string, user *url.Userinfo) func(req *http.Request) {
+	if user != nil && user.Username() != "" {
+		password, _ := user.Password()
+		return func(req *http.Request) {
+			req.SetBasicAuth(user.Username(), password)
+		}
+	}
+	if apiKey := os.Getenv(ArtifactoryApiKeyEnvKey); kind == repositoryArtifactory && apiKey != "" {
+		return func(req *http.Request) {
+			req.Header.Set("X-JFrog-Art-Api", apiKey)
+		}
+	}
+	if apiKey := os.Getenv(NexusApiKeyEnvKey); kind == repositoryNexus && apiKey != "" {
+		parts := strings.SplitN(apiKey, ":", 2)
+		return func(req *http.Request) {
+			req.SetBasicAuth(parts[0], parts[len(parts)-1])
+		}
+	}
+	return func(req *http.Request) {}
+}
+
+// resolve replace {latest} in artifact path by latest version found in repository and give artifact metadata
+func (r *repository) resolve() (repositoryAsset, error) {
+	artifactPath, err := r.resolveLatest(r.path)
+	if err != nil {
+		return repositoryAsset{}, err
+	}
+	asset, err := r.asset(artifactPath)
+	if err != nil {
+		return repositoryAsset{}, err
+	}
+	if asset.downloadUrl == "" {
+		asset.downloadUrl = fmt.Sprintf("%s/%s/%s", r.baseUrl, r.name, artifactPath)
+		if r.kind == repositoryNexus {
+			asset.downloadUrl = fmt.Sprintf("%s/repository/%s/%s", r.baseUrl, r.name, artifactPath)
+		}
+	}
+	return asset, nil
+}
+
+func (r *repository) resolveLatest(artifactPath string) (string, error) {
+	segments := strings.Split(artifactPath, "/")
+	for i, segment := range segments {
+		if !strings.Contains(segment, LatestVersionPlaceholder) {
+			continue
+		}
+		pattern := regexp.MustCompile("^" + strings.Replace(
+			regexp.QuoteMeta(segment), regexp.QuoteMeta(LatestVersionPlaceholder), "(.+)", -1,
+		) + "$")
+		names, err := r.children(strings.Join(segments[:i], "/"))
+		if err != nil {
+			return "", err
+		}
+		latest := ""
+		for _, name := range names {
+			match := pattern.FindStringSubmatch(name)
+			if match == nil || !allEqual(match[1:]) {
+				continue
+			}
+			if latest == "" || utils.IsNewerVersion(match[1], latest) {
+				latest = match[1]
+			}
+		}
+		if latest == "" {
+			return "", fmt.Errorf("No version found in %s repository %s for %s", r.kind, r.name, artifactPath)
+		}
+		return strings.Replace(artifactPath, LatestVersionPlaceholder, latest, -1), nil
+	}
+	return artifactPath, nil
+}
+
+// children give names of entries in folder of repository
+func (r *repository) children(folder string) ([]string, error) {
+	if r.kind == repositoryArtifactory {
+		var info struct {
+			Children []struct {
+				Uri string `json:"uri"`
+			} `json:"children"`
+		}
+		err := r.getJson(fmt.Sprintf("%s/api/storage/%s/%s", r.baseUrl, r.name, folder), &info)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(info.Children))
+		for i, child := range info.Children {
+			names[i] = strings.TrimPrefix(child.Uri, "/")
+		}
+		return names, nil
+	}
+	prefix := ""
+	if folder != "" {
+		prefix = folder + "/"
+	}
+	items, err := r.nexusAssets(prefix + "*")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, item := range items {
+		name := strings.SplitN(strings.TrimPrefix(item.path, prefix), "/", 2)[0]
+		if strings.HasPrefix(item.path, prefix) && !utils.InStrings(name, names) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// asset give metadata of artifact at path in repository
+func (r *repository) asset(artifactPath string) (repositoryAsset, error) {
+	if r.kind == repositoryArtifactory {
+		var info struct {
+			DownloadUri string `json:"downloadUri"`
+			Checksums   struct {
+				Sha1   string `json:"sha1"`
+				Sha256 string `json:"sha256"`
+			} `json:"checksums"`
+		}
+		err := r.getJson(fmt.Sprintf("%s/api/storage/%s/%s", r.baseUrl, r.name, artifactPath), &info)
+		if err != nil {
+			return repositoryAsset{}, err
+		}
+		return repositoryAsset{
+			path:        artifactPath,
+			downloadUrl: info.DownloadUri,
+			sha1:        info.Checksums.Sha1,
+			sha256:      info.Checksums.Sha256,
+		}, nil
+	}
+	items, err := r.nexusAssets(artifactPath)
+	if err != nil {
+		return repositoryAsset{}, err
+	}
+	for _, item := range items {
+		if item.path == artifactPath {
+			return item, nil
+		}
+	}
+	return repositoryAsset{}, fmt.Errorf("Artifact %s not found in nexus repository %s", artifactPath, r.name)
+}
+
+// nexusAssets search assets of repository matching name on all pages of results
+func (r *repository) nexusAssets(name string) ([]repositoryAsset, error) {
+	assets := make([]repositoryAsset, 0)
+	token := ""
+	for {
+		params := url.Values{}
+		params.Set("repository", r.name)
+		params.Set("name", name)
+		if token != "" {
+			params.Set("continuationToken", token)
+		}
+		var page struct {
+			Items []struct {
+				Path        string `json:"path"`
+				DownloadUrl string `json:"downloadUrl"`
+				Checksum    struct {
+					Sha1   string `json:"sha1"`
+					Sha256 string `json:"sha256"`
+				} `json:"checksum"`
+			} `json:"items"`
+			ContinuationToken string `json:"continuationToken"`
+		}
+		err := r.getJson(r.baseUrl+"/service/rest/v1/search/assets?"+params.Encode(), &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			assets = append(assets, repositoryAsset{
+				path:        strings.TrimPrefix(item.Path, "/"),
+				downloadUrl: item.DownloadUrl,
+				sha1:        item.Checksum.Sha1,
+				sha256:      item.Checksum.Sha256,
+			})
+		}
+		if page.ContinuationToken == "" {
+			return assets, nil
+		}
+		token = page.ContinuationToken
+	}
+}
+
+func (r *repository) getJson(apiUrl string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Could not query %s repository %s on %s: %s", r.kind, r.name, apiUrl, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("Invalid response of %s repository on %s: %s", r.kind, apiUrl, err.Error())
+	}
+	return nil
+}
+
+// download write artifact in filePath and verify its checksum with the one given by repository
+func (r *repository) download(asset repositoryAsset, filePath string) error {
+	req, err := http.NewRequest(http.MethodGet, asset.downloadUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Could not download %s from %s repository %s: %s", asset.path, r.kind, r.name, resp.Status)
+	}
+	var h hash.Hash
+	expected := ""
+	switch {
+	case asset.sha256 != "":
+		h, expected = sha256.New(), asset.sha256
+	case asset.sha1 != "":
+		h, expected = sha1.New(), asset.sha1
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	if h != nil {
+		w = io.MultiWriter(f, h)
+	}
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	if h != nil && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), expected) {
+		return fmt.Errorf("Checksum of %s mismatch with checksum '%s' given by %s repository", asset.path, expected, r.kind)
+	}
+	return markExecutable(filePath)
+}
+
+// markExecutable set executable permission on file if it is an executable as http handler would do
+func markExecutable(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	isExec := zipper.IsExecutable(f)
+	f.Close()
+	if !isExec {
+		return nil
+	}
+	return os.Chmod(filePath, 0755)
+}
+
+// repositoryTransport authenticate requests sent to repository host, redirections to other hosts are not authenticated
+type repositoryTransport struct {
+	base http.RoundTripper
+	host string
+	auth func(req *http.Request)
+}
+
+func (t *repositoryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.host {
+		return t.base.RoundTrip(req)
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	t.auth(r)
+	return t.base.RoundTrip(r)
+}
+
+func allEqual(values []string) bool {
+	for _, v := range values {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
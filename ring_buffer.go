@@ -0,0 +1,93 @@
+package sidecars
+
+import (
+	"bytes"
+	"sync"
+)
+
+// RingBuffer is a writer which only keep last written bytes up to its size
+type RingBuffer struct {
+	mu          sync.Mutex
+	buf         []byte
+	size        int
+	dropped     bool
+	subscribers map[chan []byte]bool
+}
+
+// NewRingBuffer give a ring buffer keeping last size bytes, nothing is kept when size is not positive
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 0 {
+		size = 0
+	}
+	return &RingBuffer{
+		buf:  make([]byte, 0, size),
+		size: size,
+	}
+}
+
+func (b *RingBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	if n >= b.size {
+		b.buf = append(b.buf[:0], p[n-b.size:]...)
+		b.dropped = true
+		b.publish(p)
+		return n, nil
+	}
+	if overflow := len(b.buf) + n - b.size; overflow > 0 {
+		b.buf = append(b.buf[:0], b.buf[overflow:]...)
+		b.dropped = true
+	}
+	b.buf = append(b.buf, p...)
+	b.publish(p)
+	return n, nil
+}
+
+// Bytes give a copy of kept bytes, first line is removed if it has been partially dropped
+func (b *RingBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bytes()
+}
+
+// Subscribe give kept bytes and a channel receiving bytes written after,
+// written bytes are dropped for a subscriber which does not read fast enough.
+// Returned func must be called to unsubscribe.
+func (b *RingBuffer) Subscribe() ([]byte, <-chan []byte, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan []byte]bool)
+	}
+	sub := make(chan []byte, 100)
+	b.subscribers[sub] = true
+	return b.bytes(), sub, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, sub)
+	}
+}
+
+func (b *RingBuffer) publish(p []byte) {
+	if len(b.subscribers) == 0 {
+		return
+	}
+	written := append([]byte{}, p...)
+	for sub := range b.subscribers {
+		select {
+		case sub <- written:
+		default:
+		}
+	}
+}
+
+func (b *RingBuffer) bytes() []byte {
+	buf := b.buf
+	if b.dropped {
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			buf = buf[i+1:]
+		}
+	}
+	return append([]byte{}, buf...)
+}
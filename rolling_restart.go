@@ -0,0 +1,103 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// rollingRestartFunc give function to restart a reverse proxy sidecar without closing its listening port
+func (l *Launcher) rollingRestartFunc(name string) func() error {
+	return func() error {
+		l.reloadMu.Lock()
+		defer l.reloadMu.Unlock()
+		old := l.processFactory.ProcessByName(name)
+		sidecar := sidecarByName(l.loadedSidecars, name)
+		if old == nil || sidecar == nil {
+			return fmt.Errorf("Sidecar %s not found", name)
+		}
+		// sidecar is copied as it is modified by templating when creating process
+		sidecars, err := copySidecars([]*config.Sidecar{sidecar})
+		if err != nil {
+			return err
+		}
+		return l.rollingRestart(old, sidecars[0])
+	}
+}
+
+// rollingRestart start a new instance of a reverse proxy sidecar on a new port, switch traffic to it
+// and then drain and stop old instance
+func (l *Launcher) rollingRestart(old *process, sidecar *config.Sidecar) error {
+	select {
+	case <-l.processFactory.stopChan:
+		return fmt.Errorf("Launcher is stopping")
+	default:
+	}
+	if !old.IsRunning() {
+		return fmt.Errorf("%s %s is not running", old.typeP, old.name)
+	}
+	forwarder := l.forwarders[sidecar.Name]
+	entry := log.WithField("sidecar", sidecar.Name)
+	port, err := utils.FreePort()
+	if err != nil {
+		return err
+	}
+	env, err := l.sidecarEnv(sidecar)
+	if err != nil {
+		return err
+	}
+	proxyEnv := l.cStarter.ProxyEnv(port)
+	for _, key := range upstreamEnvKeys {
+		proxyEnv[key] = l.proxyEnvs[sidecar.Name][key]
+	}
+	proxyEnv = utils.MergeEnv(proxyEnv, l.proxyListenEnv(port))
+	env, err = OverrideEnv(env, l.envKeyNames().forSidecar(sidecar).rename(proxyEnv))
+	if err != nil {
+		return err
+	}
+	entry.Infof("Starting new instance of sidecar %s on port %d ...", sidecar.Name, port)
+	p, err := l.processFactory.FromSidecar(sidecar, env)
+	if err != nil {
+		return err
+	}
+	p.rollingRestart = l.rollingRestartFunc(sidecar.Name)
+	l.processFactory.WaitGroup().Add(1)
+	go p.Start()
+
+	err = waitForListening(p, port, defaultWaitForTimeout*time.Second)
+	if err != nil {
+		p.Stop(l.stopStageTimeout())
+		l.processFactory.RemoveProcess(p)
+		return fmt.Errorf("New instance of sidecar %s has not been started, old one is kept: %s", sidecar.Name, err.Error())
+	}
+	forwarder.SetTarget(port)
+	l.proxyEnvs[sidecar.Name] = proxyEnv
+	l.metrics.Incr(MetricProcessRestarted, old.metricTags())
+	entry.Infof("Traffic switched to new instance of sidecar %s, stopping old one ...", sidecar.Name)
+	old.Stop(l.stopStageTimeout())
+	l.processFactory.RemoveProcess(old)
+	entry.Infof("Finished rolling restart of sidecar %s.", sidecar.Name)
+	return nil
+}
+
+// waitForListening wait for process to accept connections on port
+func waitForListening(p *process, port int, timeout time.Duration) error {
+	dep := &config.WaitFor{Tcp: fmt.Sprintf("127.0.0.1:%d", port)}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := checkDependency(dep)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("port %d is still not listening after %s", port, timeout)
+		}
+		select {
+		case <-p.exited:
+			return fmt.Errorf("process has exited")
+		case <-time.After(waitForInterval):
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Package rproxy is the reverse proxy embedded in cloud-sidecars.
+//
+// Launcher runs it as a sidecar process by executing its own binary with Command as first argument,
+// config is given in ConfigEnvKey env var. Programs embedding launcher must call RunFromEnv
+// when they are executed with Command as first argument to use sidecars of type builtin-rproxy
+// (and static.RunFromEnv or forward.RunFromEnv for sidecars of type builtin-static or builtin-forward).
+package rproxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// Command is first argument given to launcher binary to run builtin reverse proxy
+	Command = "builtin-rproxy"
+	// ConfigEnvKey is env var giving config of builtin reverse proxy as json
+	ConfigEnvKey = "SIDECARS_BUILTIN_RPROXY"
+
+	shutdownTimeout = 10 * time.Second
+	defaultRealm    = "cloud-sidecars"
+)
+
+// RunFromEnv serve reverse proxy on port given by PORT env var and forward requests to app
+// on port given by PROXY_APP_PORT env var until SIGTERM or SIGINT is received,
+// PROXY_LISTEN_ADDR and PROXY_APP_URL are used instead when set to bind on a given address or reach app on another host or with https
+func RunFromEnv() error {
+	conf := config.BuiltinRproxy{}
+	if v := os.Getenv(ConfigEnvKey); v != "" {
+		err := json.Unmarshal([]byte(v), &conf)
+		if err != nil {
+			return fmt.Errorf("Invalid builtin reverse proxy config: %s", err.Error())
+		}
+	}
+	port := os.Getenv("PORT")
+	appPort := os.Getenv("PROXY_APP_PORT")
+	if port == "" || appPort == "" {
+		return fmt.Errorf("PORT and PROXY_APP_PORT env vars must be set to run builtin reverse proxy")
+	}
+	upstreamUrl := os.Getenv("PROXY_APP_URL")
+	if upstreamUrl == "" {
+		upstreamUrl = "http://127.0.0.1:" + appPort
+	}
+	upstream, err := url.Parse(upstreamUrl)
+	if err != nil {
+		return err
+	}
+	addr := os.Getenv("PROXY_LISTEN_ADDR")
+	if addr == "" {
+		addr = ":" + port
+	}
+	server := &http.Server{
+		Addr:    addr,
+		Handler: NewHandler(conf, upstream),
+	}
+	entry := log.WithField("component", "rproxy")
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigs
+		entry.Info("Stopping builtin reverse proxy ...")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	entry.Infof("Builtin reverse proxy listening on %s and forwarding to %s", addr, upstream.String())
+	if conf.TLS != nil {
+		err = server.ListenAndServeTLS(conf.TLS.CertFile, conf.TLS.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// NewHandler give handler forwarding requests to upstream with headers and basic auth of config
+func NewHandler(conf config.BuiltinRproxy, upstream *url.URL) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(upstream)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		if req.TLS != nil {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		} else if req.Header.Get("X-Forwarded-Proto") == "" {
+			req.Header.Set("X-Forwarded-Proto", "http")
+		}
+		for k, v := range conf.Headers {
+			req.Header.Set(k, v)
+		}
+	}
+	if len(conf.ResponseHeaders) > 0 {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			for k, v := range conf.ResponseHeaders {
+				resp.Header.Set(k, v)
+			}
+			return nil
+		}
+	}
+	if conf.BasicAuth == nil {
+		return proxy
+	}
+	return basicAuth(*conf.BasicAuth, proxy)
+}
+
+func basicAuth(auth config.BasicAuth, next http.Handler) http.Handler {
+	realm := auth.Realm
+	if realm == "" {
+		realm = defaultRealm
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		// both are compared to not leak which one is wrong with timing
+		userOk := subtle.ConstantTimeCompare([]byte(username), []byte(auth.Username)) == 1
+		passOk := subtle.ConstantTimeCompare([]byte(password), []byte(auth.Password)) == 1
+		if !ok || !userOk || !passOk {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		// credentials are not given to app
+		req.Header.Del("Authorization")
+		next.ServeHTTP(w, req)
+	})
+}
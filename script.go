@@ -1,19 +1,80 @@
 package sidecars
 
 import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
 	"io"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"time"
 )
 
-func runScript(script, wd string, env []string, stdout, stderr io.Writer) error {
+const (
+	// DefaultScriptTimeout is time in seconds given to install scripts before being killed
+	DefaultScriptTimeout = 600
+	// scriptStderrExcerptSize is size in bytes of last stderr output given in script errors
+	scriptStderrExcerptSize = 1024
+)
+
+// runScript run script through bash, each line of output is written with prefix.
+// Script and all its sub processes are killed when timeout is reached (0 means no timeout)
+// and last lines written on stderr are given in returned error.
+func runScript(script, wd string, env []string, stdout, stderr io.Writer, prefix string, timeout time.Duration) error {
 	if runtime.GOOS == "windows" {
 		return nil
 	}
 	cmd := exec.Command("bash", "-c", script)
 	cmd.Dir = wd
 	cmd.Env = env
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-	return cmd.Run()
+	cmd.SysProcAttr = utils.PgidSysProcAttr(nil)
+	stdoutWriter := prefixLineWriter(stdout, prefix)
+	stderrWriter := prefixLineWriter(stderr, prefix)
+	stderrExcerpt := NewRingBuffer(scriptStderrExcerptSize)
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = io.MultiWriter(stderrWriter, stderrExcerpt)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
+	}
+	timedOut := false
+	select {
+	case err = <-done:
+	case <-timeoutChan:
+		timedOut = true
+		signalProcessGroup(cmd, os.Kill)
+		err = <-done
+	}
+	stdoutWriter.Flush()
+	stderrWriter.Flush()
+	if timedOut {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err == nil {
+		return nil
+	}
+	excerpt := strings.TrimSpace(string(stderrExcerpt.Bytes()))
+	if excerpt == "" {
+		return err
+	}
+	return fmt.Errorf("%s, last lines on stderr:\n%s", err.Error(), excerpt)
+}
+
+func prefixLineWriter(w io.Writer, prefix string) *lineWriter {
+	return &lineWriter{
+		fn: func(line string) {
+			scannerOutput(w, prefix, line)
+		},
+	}
 }
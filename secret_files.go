@@ -0,0 +1,275 @@
+package sidecars
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	CredhubApiEnvKey     = "CREDHUB_API"
+	VaultAddrEnvKey      = "VAULT_ADDR"
+	VaultTokenEnvKey     = "VAULT_TOKEN"
+	VaultNamespaceEnvKey = "VAULT_NAMESPACE"
+	VaultCaCertEnvKey    = "VAULT_CACERT"
+
+	// pathSecretFiles is dir in sidecars directory containing secret files of each sidecar
+	pathSecretFiles = "secrets"
+
+	// defaultCredhubApi is CredHub of Cloud Foundry reached with instance identity of app container
+	defaultCredhubApi = "https://credhub.service.cf.internal:8844"
+	secretsTimeout    = 30 * time.Second
+)
+
+// writeSecretFiles write secret files of sidecars with 0600 mode, they are written at launch
+// to not keep secrets in staged app and as CredHub can only be reached with instance identity of running app
+func (l Launcher) writeSecretFiles(sidecars []*config.Sidecar) error {
+	for _, sidecar := range sidecars {
+		for _, secretFile := range sidecar.SecretFiles {
+			entry := log.WithField("sidecar", sidecar.Name)
+			entry.Debugf("Writing secret file %s ...", secretFile.Path)
+			value, err := secretValue(secretFile)
+			if err != nil {
+				return NewSidecarError(sidecar, fmt.Errorf("Secret of file %s could not be retrieved: %s", secretFile.Path, err.Error()))
+			}
+			path := l.secretFilePath(sidecar, secretFile)
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err != nil {
+				return NewSidecarError(sidecar, err)
+			}
+			err = writeSecretFile(path, []byte(value))
+			if err != nil {
+				return NewSidecarError(sidecar, err)
+			}
+			entry.Debugf("Finished writing secret file %s.", secretFile.Path)
+		}
+	}
+	return nil
+}
+
+// writeSecretFile write value in a temp file created with 0600 mode which replaces path,
+// an existing file keeps its mode when written and could expose secret
+func writeSecretFile(path string, value []byte) error {
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), ".secret-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(value)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// shredSecretFiles overwrite and remove secret files of sidecars marked to be shredded
+func (l Launcher) shredSecretFiles() {
+	for _, sidecar := range l.sConfig.Sidecars {
+		for _, secretFile := range sidecar.SecretFiles {
+			if !secretFile.Shred {
+				continue
+			}
+			err := shredFile(l.secretFilePath(sidecar, secretFile))
+			if err != nil && !os.IsNotExist(err) {
+				log.WithField("sidecar", sidecar.Name).Warnf("Secret file %s could not be shredded: %s", secretFile.Path, err.Error())
+			}
+		}
+	}
+}
+
+// SidecarSecretsDir give dir of relative secret files of sidecar, it is outside of sidecar dir
+// to not change checksum of installed artifact verified at launch
+func SidecarSecretsDir(baseDir string, sidecar *config.Sidecar) string {
+	return filepath.Join(baseDir, PathSidecarsWd, pathSecretFiles, sidecar.Name)
+}
+
+func (l Launcher) secretFilePath(sidecar *config.Sidecar, secretFile *config.SecretFile) string {
+	if filepath.IsAbs(secretFile.Path) {
+		return secretFile.Path
+	}
+	return filepath.Join(SidecarSecretsDir(l.sConfig.Dir, sidecar), secretFile.Path)
+}
+
+// shredFile overwrite content of file with zeros before removing it
+func shredFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	_, err = f.Write(make([]byte, info.Size()))
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// secretValue give secret of secret file from its source
+func secretValue(secretFile *config.SecretFile) (string, error) {
+	switch {
+	case secretFile.Env != "":
+		value, ok := os.LookupEnv(secretFile.Env)
+		if !ok {
+			return "", fmt.Errorf("Env var %s is not set", secretFile.Env)
+		}
+		return value, nil
+	case secretFile.Credhub != "":
+		return credhubSecret(secretFile.Credhub, secretFile.Key)
+	case secretFile.Vault != "":
+		return vaultSecret(secretFile.Vault, secretFile.Key)
+	}
+	return secretFile.Value, nil
+}
+
+// credhubSecret give current value of a CredHub credential, key picks a field of a credential which is not a string
+// (e.g.: private_key of a certificate), CredHub is reached with instance identity given by CF_INSTANCE_CERT and CF_INSTANCE_KEY
+func credhubSecret(name, key string) (string, error) {
+	api := os.Getenv(CredhubApiEnvKey)
+	if api == "" {
+		api = defaultCredhubApi
+	}
+	certFile, keyFile := os.Getenv("CF_INSTANCE_CERT"), os.Getenv("CF_INSTANCE_KEY")
+	if certFile == "" || keyFile == "" {
+		return "", fmt.Errorf("CF_INSTANCE_CERT and CF_INSTANCE_KEY env vars must be set to reach CredHub")
+	}
+	tlsConfig, err := secretsTlsConfig("")
+	if err != nil {
+		return "", err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return "", err
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(api, "/")+"/api/v1/data?current=true&name="+url.QueryEscape(name), nil)
+	if err != nil {
+		return "", err
+	}
+	resp := struct {
+		Data []struct {
+			Value interface{} `json:"value"`
+		} `json:"data"`
+	}{}
+	err = getSecretJson(tlsConfig, req, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("CredHub credential %s not found", name)
+	}
+	return secretField(resp.Data[0].Value, key)
+}
+
+// vaultSecret give field key of a Vault secret, secrets of kv v1 and v2 engines are supported,
+// Vault is reached as vault cli does with VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE and VAULT_CACERT env vars
+func vaultSecret(path, key string) (string, error) {
+	addr := os.Getenv(VaultAddrEnvKey)
+	if addr == "" {
+		return "", fmt.Errorf("%s env var must be set to reach Vault", VaultAddrEnvKey)
+	}
+	tlsConfig, err := secretsTlsConfig(os.Getenv(VaultCaCertEnvKey))
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv(VaultTokenEnvKey))
+	if namespace := os.Getenv(VaultNamespaceEnvKey); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	err = getSecretJson(tlsConfig, req, &resp)
+	if err != nil {
+		return "", err
+	}
+	data := resp.Data
+	// kv v2 engine nests secret in data with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok && data["metadata"] != nil {
+		data = nested
+	}
+	return secretField(data, key)
+}
+
+// secretField give a secret as a string, key picks a field of a secret which is an object,
+// values which are not strings are given as json
+func secretField(secret interface{}, key string) (string, error) {
+	if key != "" {
+		fields, ok := secret.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("Secret has no field %s", key)
+		}
+		secret, ok = fields[key]
+		if !ok {
+			return "", fmt.Errorf("Secret has no field %s", key)
+		}
+	}
+	if s, ok := secret.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// secretsTlsConfig give tls config trusting system CAs and CA in caFile when given
+func secretsTlsConfig(caFile string) (*tls.Config, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("No certificate found in %s", caFile)
+		}
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+func getSecretJson(tlsConfig *tls.Config, req *http.Request, v interface{}) error {
+	client := &http.Client{
+		Timeout:   secretsTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %s", req.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,187 @@
+package sidecars
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SetupState is written in setup_state.json after a successful setup,
+// it is used by setup --refresh to only set up again sidecars which have changed
+type SetupState struct {
+	Version    string                       `json:"version"`
+	ConfigHash string                       `json:"config_hash"`
+	SetupAt    time.Time                    `json:"setup_at"`
+	Sidecars   map[string]SidecarSetupState `json:"sidecars"`
+}
+
+type SidecarSetupState struct {
+	ConfigHash string `json:"config_hash"`
+	Checksum   string `json:"checksum,omitempty"`
+}
+
+func SetupStateFilePath(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, "setup_state.json")
+}
+
+// upToDateSidecars give sidecars which have not changed since last setup,
+// sidecars which have changed are removed to be downloaded and installed again
+func (l Launcher) upToDateSidecars() (map[string]bool, error) {
+	entry := log.WithField("component", "Launcher").WithField("command", "staging")
+	state, err := loadSetupState(SetupStateFilePath(l.sConfig.Dir))
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		entry.Info("No previous setup found, everything is set up.")
+		return nil, nil
+	}
+	if state.Version != l.version {
+		entry.Infof("Previous setup has been made by version '%s', everything is set up again.", state.Version)
+		return nil, nil
+	}
+	upToDate := make(map[string]bool)
+	for _, sidecar := range l.sConfig.Sidecars {
+		why, err := l.sidecarChange(sidecar, state.Sidecars[sidecar.Name])
+		if err != nil {
+			return nil, NewSidecarError(sidecar, err)
+		}
+		if why == "" {
+			upToDate[sidecar.Name] = true
+			continue
+		}
+		entry.WithField("sidecar", sidecar.Name).Infof("Sidecar will be set up again: %s.", why)
+		err = l.resetSidecarSetup(sidecar)
+		if err != nil {
+			return nil, NewSidecarError(sidecar, err)
+		}
+	}
+	return upToDate, nil
+}
+
+// sidecarChange give why sidecar must be set up again, empty if it has not changed
+func (l Launcher) sidecarChange(sidecar *config.Sidecar, previous SidecarSetupState) (string, error) {
+	if previous.ConfigHash == "" {
+		return "not set up by previous setup", nil
+	}
+	configHash, err := sidecarConfigHash(sidecar)
+	if err != nil {
+		return "", err
+	}
+	if configHash != previous.ConfigHash {
+		return "config has changed", nil
+	}
+	if sidecar.ArtifactURI == "" {
+		return "", nil
+	}
+	result, err := l.verifySidecar(sidecar)
+	if err != nil {
+		return "", err
+	}
+	if result.Target != "directory" || result.Status != VerifyStatusOk || result.Current != previous.Checksum {
+		return "installed files have changed", nil
+	}
+	return "", nil
+}
+
+// resetSidecarSetup remove installed files, index and lock of sidecar
+func (l Launcher) resetSidecarSetup(sidecar *config.Sidecar) error {
+	if index, ok := l.indexer.Index(sidecar); ok {
+		l.indexer.RemoveIndex(index)
+		err := l.indexer.Store()
+		if err != nil {
+			return err
+		}
+	}
+	l.locker.RemoveLock(sidecar.Name)
+	err := l.locker.Store()
+	if err != nil {
+		return err
+	}
+	return removeInstallDir(l.sConfig.Dir, SidecarInstallDir(l.sConfig.Dir, sidecar))
+}
+
+// writeSetupState record config and installed files of sidecars after a successful setup
+func (l Launcher) writeSetupState() error {
+	configHash, err := hashJson(l.sConfig.Sidecars)
+	if err != nil {
+		return err
+	}
+	state := SetupState{
+		Version:    l.version,
+		ConfigHash: configHash,
+		SetupAt:    time.Now(),
+		Sidecars:   make(map[string]SidecarSetupState),
+	}
+	for _, sidecar := range l.sConfig.Sidecars {
+		sidecarHash, err := sidecarConfigHash(sidecar)
+		if err != nil {
+			return NewSidecarError(sidecar, err)
+		}
+		lock, _ := l.locker.Lock(sidecar)
+		state.Sidecars[sidecar.Name] = SidecarSetupState{
+			ConfigHash: sidecarHash,
+			Checksum:   lock.Checksum,
+		}
+	}
+	stateFile := SetupStateFilePath(l.sConfig.Dir)
+	err = os.MkdirAll(filepath.Dir(stateFile), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(stateFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
+
+func loadSetupState(stateFile string) (*SetupState, error) {
+	f, err := os.Open(stateFile)
+	if err != nil && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var state SetupState
+	err = json.NewDecoder(f).Decode(&state)
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// sidecarConfigHash hash config used to install sidecar, changing how sidecar runs does not need a new setup
+func sidecarConfigHash(sidecar *config.Sidecar) (string, error) {
+	return hashJson([]interface{}{
+		sidecar.Executable,
+		sidecar.ArtifactURI,
+		sidecar.ArtifactType,
+		sidecar.ArtifactSha1,
+		sidecar.ArtifactSymlink,
+		sidecar.ArtifactSubpath,
+		sidecar.StripComponents,
+		sidecar.AfterInstall,
+		sidecar.VerifyCommand,
+		sidecar.Env,
+	})
+}
+
+func hashJson(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
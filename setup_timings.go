@@ -0,0 +1,136 @@
+package sidecars
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	SetupPhaseDownload     = "download"
+	SetupPhaseExtract      = "extract"
+	SetupPhaseAfterInstall = "after_install"
+	SetupPhaseVerify       = "verify"
+	SetupPhaseTemplating   = "templating"
+
+	SetupTimingsTable = "table"
+	SetupTimingsJson  = "json"
+	SetupTimingsNone  = "none"
+)
+
+var setupPhases = []string{
+	SetupPhaseDownload,
+	SetupPhaseExtract,
+	SetupPhaseAfterInstall,
+	SetupPhaseVerify,
+	SetupPhaseTemplating,
+}
+
+// setupTimings record duration of each setup phase of sidecars, a nil setupTimings records nothing
+type setupTimings struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	names     []string
+	durations map[string]map[string]time.Duration
+}
+
+type SetupTimingsReport struct {
+	// TotalMs is duration of whole setup in milliseconds
+	TotalMs  int64                `json:"total_ms"`
+	Sidecars []SidecarSetupTiming `json:"sidecars"`
+}
+
+type SidecarSetupTiming struct {
+	Name string `json:"name"`
+	// PhasesMs is duration in milliseconds of each phase which has been run (download, extract, after_install, verify, templating)
+	PhasesMs map[string]int64 `json:"phases_ms"`
+	TotalMs  int64            `json:"total_ms"`
+}
+
+func newSetupTimings() *setupTimings {
+	return &setupTimings{
+		startedAt: time.Now(),
+		durations: make(map[string]map[string]time.Duration),
+	}
+}
+
+// record add time elapsed since start to phase of sidecar
+func (t *setupTimings) record(sidecarName, phase string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.durations[sidecarName]; !ok {
+		t.names = append(t.names, sidecarName)
+		t.durations[sidecarName] = make(map[string]time.Duration)
+	}
+	t.durations[sidecarName][phase] += time.Since(start)
+}
+
+func (t *setupTimings) report() SetupTimingsReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := SetupTimingsReport{
+		TotalMs:  time.Since(t.startedAt).Milliseconds(),
+		Sidecars: make([]SidecarSetupTiming, len(t.names)),
+	}
+	for i, name := range t.names {
+		timing := SidecarSetupTiming{
+			Name:     name,
+			PhasesMs: make(map[string]int64),
+		}
+		for phase, d := range t.durations[name] {
+			timing.PhasesMs[phase] = d.Milliseconds()
+			timing.TotalMs += d.Milliseconds()
+		}
+		report.Sidecars[i] = timing
+	}
+	return report
+}
+
+// SetSetupTimings set how timing breakdown is shown at end of setup: table (default), json or none
+func (l *Launcher) SetSetupTimings(format string) {
+	l.timingsFormat = format
+}
+
+// showSetupTimings print time spent in each setup phase by sidecar to find which one slows staging
+func (l Launcher) showSetupTimings() error {
+	if l.setupTimings == nil || l.timingsFormat == SetupTimingsNone {
+		return nil
+	}
+	report := l.setupTimings.report()
+	if l.timingsFormat == SetupTimingsJson {
+		// written on one line to be easily found among setup logs
+		return json.NewEncoder(l.stdout).Encode(report)
+	}
+	header := []string{"Sidecar"}
+	for _, phase := range setupPhases {
+		header = append(header, phase)
+	}
+	table := newTable(l.stdout)
+	table.SetHeader(append(header, "Total"))
+	for _, timing := range report.Sidecars {
+		row := []string{timing.Name}
+		for _, phase := range setupPhases {
+			ms, ok := timing.PhasesMs[phase]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, formatMs(ms))
+		}
+		table.Append(append(row, formatMs(timing.TotalMs)))
+	}
+	footer := make([]string, len(setupPhases)+2)
+	footer[0] = "Setup"
+	footer[len(footer)-1] = formatMs(report.TotalMs)
+	table.Append(footer)
+	table.Render()
+	return nil
+}
+
+func formatMs(ms int64) string {
+	return fmt.Sprint(time.Duration(ms) * time.Millisecond)
+}
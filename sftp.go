@@ -0,0 +1,213 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	sftpScheme = "sftp://"
+
+	// SftpPrivateKeyEnvKey is a private key in PEM format, SftpPrivateKeyFileEnvKey can be used to give its path instead
+	SftpPrivateKeyEnvKey           = "SFTP_PRIVATE_KEY"
+	SftpPrivateKeyFileEnvKey       = "SFTP_PRIVATE_KEY_FILE"
+	SftpPrivateKeyPassphraseEnvKey = "SFTP_PRIVATE_KEY_PASSPHRASE"
+	// SftpKnownHostsEnvKey are lines in known_hosts format used to verify host key instead of ~/.ssh/known_hosts
+	SftpKnownHostsEnvKey = "SFTP_KNOWN_HOSTS"
+	// SftpInsecureEnvKey set to true disable host key verification
+	SftpInsecureEnvKey = "SFTP_INSECURE_IGNORE_HOST_KEY"
+
+	sftpDialTimeout = 30 * time.Second
+)
+
+func init() {
+	zipper.AddHandler(&SftpHandler{})
+}
+
+// SftpHandler is a zipper handler downloading artifacts with sftp from sftp://user@host[:port]/path (sftp://user@host/~/path for a path
+// relative to user home), it is authenticated with a private key given in env or with ssh agent.
+// Archives are uncompressed as http handler would do.
+type SftpHandler struct{}
+
+func (h SftpHandler) Zip(src *zipper.Source) (zipper.ZipReadCloser, error) {
+	tmpDir, err := ioutil.TempDir("", "sftp-artifact")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	u, err := url.Parse(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	filePath := filepath.Join(tmpDir, path.Base(u.Path))
+	f, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	err = sftpDownload(src.Path, f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = markExecutable(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return zipper.LocalHandler{}.Zip(zipper.NewSource(filePath))
+}
+
+func (h SftpHandler) Sha1(src *zipper.Source) (string, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(sftpDownload(src.Path, pw))
+	}()
+	defer pr.Close()
+	return zipper.GetSha1FromReader(pr)
+}
+
+func (h SftpHandler) Detect(src *zipper.Source) bool {
+	return strings.HasPrefix(src.Path, sftpScheme)
+}
+
+func (h SftpHandler) Name() string {
+	return "sftp"
+}
+
+// sftpDownload write content of remote file given by uri in w
+func sftpDownload(uri string, w io.Writer) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	remotePath := u.Path
+	if strings.HasPrefix(remotePath, "/~/") {
+		remotePath = strings.TrimPrefix(remotePath, "/~/")
+	}
+	if remotePath == "" || strings.HasSuffix(remotePath, "/") {
+		return fmt.Errorf("Invalid sftp uri '%s', it must be in the form sftp://user@host[:port]/path/to/file", uri)
+	}
+	sshConfig, closeAgent, err := sftpSshConfig(u)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+	conn, err := ssh.Dial("tcp", host, sshConfig)
+	// ssh agent is only used to authenticate
+	closeAgent()
+	if err != nil {
+		return fmt.Errorf("Could not connect to sftp server %s: %s", host, err.Error())
+	}
+	defer conn.Close()
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("Sftp is not available on server: %s", err.Error())
+	}
+	defer client.Close()
+	f, err := client.Open(remotePath)
+	if err != nil {
+		return fmt.Errorf("Sftp could not open %s: %s", remotePath, err.Error())
+	}
+	defer f.Close()
+	_, err = f.WriteTo(w)
+	if err != nil {
+		return fmt.Errorf("Sftp could not read %s: %s", remotePath, err.Error())
+	}
+	return nil
+}
+
+// sftpSshConfig give ssh config with user of uri, auth methods and host key verification from env,
+// closeAgent must be called once connection is authenticated to close connection to ssh agent if any
+func sftpSshConfig(u *url.URL) (sshConfig *ssh.ClientConfig, closeAgent func(), err error) {
+	user := u.User.Username()
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	authMethods := make([]ssh.AuthMethod, 0)
+	key := []byte(os.Getenv(SftpPrivateKeyEnvKey))
+	if keyFile := os.Getenv(SftpPrivateKeyFileEnvKey); len(key) == 0 && keyFile != "" {
+		var err error
+		key, err = ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not read sftp private key: %s", err.Error())
+		}
+	}
+	if len(key) > 0 {
+		var signer ssh.Signer
+		var err error
+		if passphrase := os.Getenv(SftpPrivateKeyPassphraseEnvKey); passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid sftp private key: %s", err.Error())
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+	closeAgent = func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if agentConn, err := net.Dial("unix", sock); err == nil {
+			authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers))
+			closeAgent = func() {
+				agentConn.Close()
+			}
+		}
+	}
+	if len(authMethods) == 0 {
+		return nil, nil, fmt.Errorf("No private key found for sftp, set %s, %s or use an ssh agent", SftpPrivateKeyEnvKey, SftpPrivateKeyFileEnvKey)
+	}
+	hostKeyCallback, err := sftpHostKeyCallback()
+	if err != nil {
+		closeAgent()
+		return nil, nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sftpDialTimeout,
+	}, closeAgent, nil
+}
+
+func sftpHostKeyCallback() (ssh.HostKeyCallback, error) {
+	if os.Getenv(SftpInsecureEnvKey) == "true" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	knownHostsFile := ""
+	if knownHosts := os.Getenv(SftpKnownHostsEnvKey); knownHosts != "" {
+		f, err := ioutil.TempFile("", "known_hosts")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(knownHosts + "\n")
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		knownHostsFile = f.Name()
+	} else if home, err := os.UserHomeDir(); err == nil {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load known hosts to verify sftp server, set %s or %s=true: %s", SftpKnownHostsEnvKey, SftpInsecureEnvKey, err.Error())
+	}
+	return callback, nil
+}
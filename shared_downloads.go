@@ -0,0 +1,97 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// sharedDownloadsDir is dir in sidecars dir where artifacts used by several sidecars are kept while downloading artifacts
+const sharedDownloadsDir = ".downloads"
+
+// sharedDownloads let sidecars using same artifact download it once, artifacts are downloaded one after the other
+// so next sidecars reuse file of the first one, a nil sharedDownloads shares nothing
+type sharedDownloads struct {
+	dir       string
+	artifacts map[string]*sharedArtifact
+}
+
+// sharedArtifact is a downloaded artifact, path is empty until it has been downloaded successfully
+type sharedArtifact struct {
+	path       string
+	validators HttpValidators
+}
+
+// newSharedDownloads give shared downloads for artifacts used by more than one sidecar, sources are artifacts as resolved for download
+func newSharedDownloads(baseDir string, sources []*config.Sidecar) *sharedDownloads {
+	counts := make(map[string]int)
+	for _, source := range sources {
+		counts[sharedArtifactKey(source)]++
+	}
+	d := &sharedDownloads{
+		dir:       filepath.Join(baseDir, PathSidecarsWd, sharedDownloadsDir),
+		artifacts: make(map[string]*sharedArtifact),
+	}
+	for key, count := range counts {
+		if count > 1 {
+			d.artifacts[key] = &sharedArtifact{}
+		}
+	}
+	return d
+}
+
+// sharedArtifactKey identify an artifact, expected sha1 is part of it to check it for each sidecar
+func sharedArtifactKey(source *config.Sidecar) string {
+	return source.ArtifactType + "|" + source.ArtifactURI + "|" + source.ArtifactSha1
+}
+
+// artifact give shared artifact of source, nil is given when artifact is not shared
+func (d *sharedDownloads) artifact(source *config.Sidecar) *sharedArtifact {
+	if d == nil {
+		return nil
+	}
+	return d.artifacts[sharedArtifactKey(source)]
+}
+
+// reusable give path of artifact when it has already been downloaded for another sidecar
+func (a *sharedArtifact) reusable() (string, bool) {
+	if a == nil || a.path == "" {
+		return "", false
+	}
+	return a.path, true
+}
+
+// spoolFile create file where a shared artifact is written while being downloaded, nil is given when artifact is not shared
+func (d *sharedDownloads) spoolFile(a *sharedArtifact) (*os.File, error) {
+	if d == nil || a == nil {
+		return nil, nil
+	}
+	err := os.MkdirAll(d.dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.TempFile(d.dir, "artifact-")
+}
+
+// cleanup remove files kept for sharing
+func (d *sharedDownloads) cleanup() {
+	if d == nil {
+		return
+	}
+	os.RemoveAll(d.dir)
+}
+
+// linkOrCopyFile make dst a hard link of src or a copy of it when it can't be linked
+func linkOrCopyFile(src, dst string) error {
+	os.Remove(dst)
+	if os.Link(src, dst) == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in, 0644)
+}
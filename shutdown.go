@@ -0,0 +1,161 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultStopTimeout is time in seconds given to all processes to exit before being killed when launcher stops,
+	// it fits in grace period given by cloud foundry
+	DefaultStopTimeout = 10
+	// DefaultStopStageTimeout is time in seconds given to a process stopped alone (e.g.: on reload) before being killed
+	DefaultStopStageTimeout = 10
+)
+
+// stopStages give processes in order they must be stopped:
+// app first, then reverse proxies from the nearest of app to the one receiving platform traffic,
+// then other sidecars by groups in reverse order of group_order and finally sidecars started without order
+func (l Launcher) stopStages(processes []*process) [][]*process {
+	stages := make([][]*process, 0)
+	for _, p := range processes {
+		if p.typeP == "cloud" {
+			stages = append(stages, []*process{p})
+		}
+	}
+	// first rproxy in config listen on platform port and forward to next one, the last one forward to app
+	for i := len(processes) - 1; i >= 0; i-- {
+		if processes[i].isRproxy {
+			stages = append(stages, []*process{processes[i]})
+		}
+	}
+	isBackground := func(p *process) bool {
+		return p.typeP != "cloud" && !p.isRproxy
+	}
+	for i := len(l.sConfig.GroupOrder) - 1; i >= 0; i-- {
+		stage := make([]*process, 0)
+		for _, p := range processes {
+			if isBackground(p) && p.group != "" && p.group == l.sConfig.GroupOrder[i] {
+				stage = append(stage, p)
+			}
+		}
+		if len(stage) > 0 {
+			stages = append(stages, stage)
+		}
+	}
+	stage := make([]*process, 0)
+	for _, p := range processes {
+		if isBackground(p) && (p.group == "" || !utils.InStrings(p.group, l.sConfig.GroupOrder)) {
+			stage = append(stage, p)
+		}
+	}
+	if len(stage) > 0 {
+		stages = append(stages, stage)
+	}
+	return stages
+}
+
+// stopProcesses drain reverse proxies while app can still answer in-flight requests they forward,
+// then stop processes stage after stage
+func (l Launcher) stopProcesses(processes []*process, sig os.Signal, signalChan chan os.Signal) {
+	deadline := time.Now().Add(l.stopTimeout())
+	drainProcesses(processes)
+	stages := l.stopStages(processes)
+	for i, stage := range stages {
+		l.stopStage(stage, sig, signalChan, l.stageTimeout(deadline, len(stages)-i))
+	}
+}
+
+// stopStage send sig to running processes of stage and wait for them to exit,
+// processes still running after timeout are killed
+// (processes ignoring stop signal forwarding are only waited before being killed)
+func (l Launcher) stopStage(stage []*process, sig os.Signal, signalChan chan os.Signal, timeout time.Duration) {
+	entry := log.WithField("component", "Launcher")
+	names := make([]string, len(stage))
+	for i, p := range stage {
+		names[i] = p.name
+		if !p.IsRunning() {
+			continue // process is not running (which probably create signal)
+		}
+		// resent signal for each process to make them detect
+		// when they receive a signal to not show error
+		signalChan <- sig
+		p.events.emitProcess(EventStopping, p, nil)
+		if p.ignoreStopSignal {
+			// process is stopped by someone else, we only wait for it
+			continue
+		}
+		p.Signal(sig)
+	}
+	entry.Infof("Waiting for %s to stop ...", strings.Join(names, ", "))
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for _, p := range stage {
+		select {
+		case <-p.exited:
+			continue
+		case <-timer.C:
+		}
+		// if processes still doesn't stop after timeout we force shutdown
+		for _, p := range stage {
+			if !p.IsRunning() {
+				continue
+			}
+			entry.Warnf("%s %s did not stop in %s, killing it", p.typeP, p.name, timeout)
+			signalChan <- syscall.SIGKILL
+			p.Signal(syscall.SIGKILL)
+		}
+		return
+	}
+}
+
+func (l Launcher) stopTimeout() time.Duration {
+	if l.sConfig.StopTimeout == 0 {
+		return DefaultStopTimeout * time.Second
+	}
+	return time.Duration(l.sConfig.StopTimeout) * time.Second
+}
+
+// stageTimeout give time left to a stop stage: time left before deadline is shared by remaining stages,
+// time not used by a stage is given to next ones, stop_stage_timeout caps it when set
+func (l Launcher) stageTimeout(deadline time.Time, remainingStages int) time.Duration {
+	timeout := time.Until(deadline) / time.Duration(remainingStages)
+	if timeout < 0 {
+		timeout = 0
+	}
+	if l.sConfig.StopStageTimeout > 0 && timeout > time.Duration(l.sConfig.StopStageTimeout)*time.Second {
+		timeout = time.Duration(l.sConfig.StopStageTimeout) * time.Second
+	}
+	return timeout
+}
+
+func (l Launcher) stopStageTimeout() time.Duration {
+	if l.sConfig.StopStageTimeout == 0 {
+		return DefaultStopStageTimeout * time.Second
+	}
+	return time.Duration(l.sConfig.StopStageTimeout) * time.Second
+}
+
+// drainProcesses run drain of running processes in parallel and wait for them
+func drainProcesses(processes []*process) {
+	wg := &sync.WaitGroup{}
+	for _, p := range processes {
+		if p.drain == nil || !p.IsRunning() {
+			continue
+		}
+		wg.Add(1)
+		go func(p *process) {
+			defer wg.Done()
+			err := p.drain()
+			if err != nil {
+				log.WithField(p.typeP, p.name).Warn(err.Error())
+			}
+		}(p)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,83 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// runningProcess start a long running command and give a process in running state for it
+func runningProcess(t *testing.T, name, typeP string) *process {
+	cmd := exec.Command("sleep", "30")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &process{
+		cmd:    cmd,
+		name:   name,
+		typeP:  typeP,
+		exited: make(chan struct{}),
+		state:  ProcessStateRunning,
+	}
+	go func() {
+		err := cmd.Wait()
+		p.setState(ProcessStateExited, err)
+		close(p.exited)
+	}()
+	return p
+}
+
+func TestStopProcessesDrainsRproxiesBeforeStoppingApp(t *testing.T) {
+	app := runningProcess(t, "app", "cloud")
+	proxy := runningProcess(t, "proxy", "sidecar")
+	proxy.isRproxy = true
+	mu := sync.Mutex{}
+	steps := make([]string, 0)
+	proxy.drain = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if app.IsRunning() {
+			steps = append(steps, "drain proxy")
+		} else {
+			steps = append(steps, "drain proxy after app stop")
+		}
+		return nil
+	}
+	l := Launcher{sConfig: config.Sidecars{StopTimeout: 5}}
+	signalChan := make(chan os.Signal, 10)
+
+	l.stopProcesses([]*process{proxy, app}, syscall.SIGTERM, signalChan)
+
+	if len(steps) != 1 || steps[0] != "drain proxy" {
+		t.Fatalf("Expected proxy to be drained once before app stop, got %v", steps)
+	}
+	for _, p := range []*process{app, proxy} {
+		select {
+		case <-p.exited:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %s to be stopped", p.name)
+		}
+	}
+}
+
+func TestStageTimeoutSharesStopTimeout(t *testing.T) {
+	l := Launcher{sConfig: config.Sidecars{StopTimeout: 12}}
+	deadline := time.Now().Add(l.stopTimeout())
+
+	timeout := l.stageTimeout(deadline, 3)
+	if timeout > 4*time.Second || timeout < 3*time.Second {
+		t.Fatalf("Expected stop timeout to be shared by stages, got %s", timeout)
+	}
+	if timeout := l.stageTimeout(time.Now().Add(-time.Second), 1); timeout != 0 {
+		t.Fatalf("Expected no time left after deadline, got %s", timeout)
+	}
+	l.sConfig.StopStageTimeout = 2
+	if timeout := l.stageTimeout(deadline, 1); timeout != 2*time.Second {
+		t.Fatalf("Expected stage timeout to be capped by stop_stage_timeout, got %s", timeout)
+	}
+}
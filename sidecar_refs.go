@@ -0,0 +1,159 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/env"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// sidecarRefFunc is template function giving a value of another sidecar,
+// e.g.: {{ sidecar "proxy" "port" }} or {{ sidecar "agent" "AGENT_URL" }}
+const sidecarRefFunc = "sidecar"
+
+// sidecarRefs resolve references to other sidecars in env and app env templates,
+// envs of referenced sidecars are computed first whatever the order of sidecars in config
+type sidecarRefs struct {
+	sidecars  []*config.Sidecar
+	base      map[string]string
+	proxyEnvs map[string]map[string]string
+	dir       string
+	envs      map[string]map[string]string
+	resolving []string
+	keyNames  envKeyNames
+}
+
+func (l Launcher) sidecarRefs() *sidecarRefs {
+	keyNames := l.envKeyNames()
+	return &sidecarRefs{
+		sidecars:  l.sConfig.Sidecars,
+		base:      utils.MergeEnv(utils.MergeEnv(utils.OsEnvToMap(), keyNames.rename(l.extraPortsEnv)), l.outputsEnv),
+		keyNames:  keyNames,
+		proxyEnvs: l.proxyEnvs,
+		dir:       l.sConfig.Dir,
+		envs:      make(map[string]map[string]string),
+	}
+}
+
+// env give env of sidecar process, ports given by launcher to reverse proxy sidecars take precedence over sidecar env
+func (r *sidecarRefs) env(sidecar *config.Sidecar) (map[string]string, error) {
+	if env, ok := r.envs[sidecar.Name]; ok {
+		return env, nil
+	}
+	for i, name := range r.resolving {
+		if name == sidecar.Name {
+			cycle := append(r.resolving[i:], sidecar.Name)
+			return nil, fmt.Errorf("Sidecars references are in a cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	r.resolving = append(r.resolving, sidecar.Name)
+	defer func() {
+		r.resolving = r.resolving[:len(r.resolving)-1]
+	}()
+	base := r.base
+	if sidecar.Instances > 1 {
+		// instance env vars can be used in templates of sidecar env
+		base = utils.MergeEnv(base, instanceEnv(sidecar))
+	}
+	if len(sidecar.SecretFiles) > 0 {
+		secretsDir, err := filepath.Abs(SidecarSecretsDir(r.dir, sidecar))
+		if err != nil {
+			return nil, err
+		}
+		base = utils.MergeEnv(base, r.keyNames.forSidecar(sidecar).rename(map[string]string{
+			SidecarSecretsDirEnvKey: secretsDir,
+		}))
+	}
+	env, err := r.override(base, sidecar.Env)
+	if err != nil {
+		return nil, err
+	}
+	if sidecar.IsRproxy {
+		env = utils.MergeEnv(env, r.keyNames.forSidecar(sidecar).rename(r.proxyEnvs[sidecar.Name]))
+	}
+	r.envs[sidecar.Name] = env
+	return env, nil
+}
+
+// override give base env overridden by values templated with base env and references to sidecars
+func (r *sidecarRefs) override(base, values map[string]string) (map[string]string, error) {
+	templated, err := r.templateValues(base, values)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MergeEnv(base, templated), nil
+}
+
+// templateValues give values templated with env and references to sidecars
+func (r *sidecarRefs) templateValues(base, values map[string]string) (map[string]string, error) {
+	err := r.resolveReferences(base, values)
+	if err != nil {
+		return nil, err
+	}
+	return env.TemplateValuesFuncs(base, values, template.FuncMap{
+		sidecarRefFunc: r.value,
+	})
+}
+
+// resolveReferences compute envs of sidecars referenced in values,
+// templates can't be rendered while rendering another one, references are found by a first rendering
+func (r *sidecarRefs) resolveReferences(base, values map[string]string) error {
+	names := make([]string, 0)
+	record := func(name string, key string) string {
+		names = append(names, name)
+		return ""
+	}
+	for _, v := range values {
+		if !strings.Contains(v, sidecarRefFunc) {
+			continue
+		}
+		// errors are given by real rendering
+		env.TemplateFuncs(base, v, template.FuncMap{sidecarRefFunc: record})
+	}
+	for _, name := range names {
+		sidecar := sidecarByName(r.sidecars, name)
+		if sidecar == nil {
+			return fmt.Errorf("Referenced sidecar %s does not exist", name)
+		}
+		_, err := r.env(sidecar)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value give value of key for a sidecar already resolved,
+// key is name, group, dir, port, app_port, an output or an env var of sidecar
+func (r *sidecarRefs) value(name string, key string) (string, error) {
+	env, ok := r.envs[name]
+	if !ok {
+		return "", fmt.Errorf("Referenced sidecar %s could not be resolved", name)
+	}
+	sidecar := sidecarByName(r.sidecars, name)
+	switch key {
+	case "name":
+		return sidecar.Name, nil
+	case "group":
+		return sidecar.Group, nil
+	case "dir":
+		return filepath.Abs(SidecarInstallDir(r.dir, sidecar))
+	case "port":
+		key = "PORT"
+	case "app_port":
+		key = r.keyNames.forSidecar(sidecar).name(ProxyAppPortEnvKey)
+	}
+	for _, output := range sidecar.Outputs {
+		if output.Name == key {
+			return r.base[output.EnvKey(name)], nil
+		}
+	}
+	v, ok := env[key]
+	if !ok {
+		return "", fmt.Errorf("Sidecar %s has no value %s", name, key)
+	}
+	return v, nil
+}
@@ -0,0 +1,64 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handlingAuxSignals forward SIGHUP, SIGUSR1 and SIGUSR2 to sidecars which have them in forward_signals
+// and reload config on SIGHUP when a config loader is set, until launcher is stopped
+func (l *Launcher) handlingAuxSignals(done chan struct{}) func() {
+	sigChan := make(chan os.Signal, 1)
+	signals := make([]os.Signal, 0, len(auxSignals))
+	for _, sig := range auxSignals {
+		signals = append(signals, sig)
+	}
+	signal.Notify(sigChan, signals...)
+	go func() {
+		for {
+			var sig os.Signal
+			select {
+			case sig = <-sigChan:
+			case <-done:
+				return
+			}
+			l.forwardSignal(sig)
+			if sig != syscall.SIGHUP || l.configLoader == nil {
+				continue
+			}
+			err := l.Reload()
+			if err != nil {
+				log.WithField("component", "Launcher").Errorf("Config has not been reloaded: %s", err.Error())
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(sigChan)
+	}
+}
+
+// forwardSignal send sig to running sidecars which have it in forward_signals,
+// app also receives it when launcher runs as pid 1 as it would without launcher
+func (l Launcher) forwardSignal(sig os.Signal) {
+	name := ""
+	for n, s := range auxSignals {
+		if s == sig {
+			name = n
+		}
+	}
+	for _, p := range l.processFactory.Processes() {
+		forward := utils.InStrings(name, p.forwardSignals) || (l.initMode && p.typeP == "cloud")
+		if !forward || !p.IsRunning() {
+			continue
+		}
+		entry := log.WithField(p.typeP, p.name)
+		entry.Infof("Forwarding %s to %s %s", name, p.typeP, p.name)
+		err := p.forwardSignal(sig)
+		if err != nil {
+			entry.Warnf("Could not forward %s: %s", name, err.Error())
+		}
+	}
+}
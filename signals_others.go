@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package sidecars
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+	"syscall"
+)
+
+// auxSignals are signals received by launcher which can be forwarded to sidecars
+var auxSignals = map[string]os.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// signalName give name of signal as SIGTERM
+func signalName(sig syscall.Signal) string {
+	return unix.SignalName(sig)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package sidecars
+
+import (
+	"os"
+	"syscall"
+)
+
+// auxSignals are signals received by launcher which can be forwarded to sidecars
+var auxSignals = map[string]os.Signal{
+	"SIGHUP": syscall.SIGHUP,
+}
+
+// signalName give name of signal
+func signalName(sig syscall.Signal) string {
+	return sig.String()
+}
@@ -3,9 +3,7 @@ package starter
 import (
 	"fmt"
 	"github.com/cloudfoundry-community/gautocloud"
-	"gopkg.in/yaml.v2"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,12 +14,18 @@ const (
 )
 
 type BuildpackIO struct {
+	// Command to start app, by default command is web process of Procfile
+	Command string
 }
 
 func (s BuildpackIO) StartCmd(env []string, _ string, stdOut, stdErr io.Writer) (*exec.Cmd, error) {
 	lPath := s.launcherPath()
 	wd, _ := os.Getwd()
-	cmd := exec.Command(lPath, s.getUserStartCommand())
+	startCommand := s.Command
+	if startCommand == "" {
+		startCommand = procfileCommand()
+	}
+	cmd := exec.Command(lPath, startCommand)
 	cmd.Env = env
 	cmd.Dir = filepath.Dir(wd)
 	cmd.Stdout = stdOut
@@ -29,23 +33,13 @@ func (s BuildpackIO) StartCmd(env []string, _ string, stdOut, stdErr io.Writer)
 	return cmd, nil
 }
 
-func (s BuildpackIO) Name() string {
-	return "buildpacksio"
+func (s BuildpackIO) WithCommand(command string) Starter {
+	s.Command = command
+	return s
 }
 
-func (BuildpackIO) getUserStartCommand() string {
-	b, err := ioutil.ReadFile(procFile)
-	if err != nil {
-		return ""
-	}
-	startCommandS := struct {
-		StartCommand string `yaml:"start"`
-	}{}
-	err = yaml.Unmarshal(b, &startCommandS)
-	if err != nil {
-		return ""
-	}
-	return startCommandS.StartCommand
+func (s BuildpackIO) Name() string {
+	return "buildpacksio"
 }
 
 func (s BuildpackIO) Detect() bool {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/cloudfoundry-community/gautocloud"
 	"github.com/cloudfoundry-community/gautocloud/cloudenv"
+	"gopkg.in/alessio/shellescape.v1"
 	"gopkg.in/yaml.v2"
 	"io"
 	"io/ioutil"
@@ -11,20 +12,36 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 const (
-	cfLauncherName string = "launcher"
-	procFile       string = "Procfile"
+	cfLauncherName  string = "launcher"
+	procFile        string = "Procfile"
+	stagingInfoFile string = "staging_info.yml"
 )
 
 type CloudFoundry struct {
+	// Command to start app, by default command is the one detected at staging or found in Procfile
+	Command string
 }
 
 func (s CloudFoundry) StartCmd(env []string, _ string, stdOut, stdErr io.Writer) (*exec.Cmd, error) {
 	lPath := s.launcherPath()
 	wd, _ := os.Getwd()
-	cmd := exec.Command(lPath, wd, s.getUserStartCommand(), "")
+	startCommand := s.Command
+	if startCommand == "" {
+		startCommand = s.getStagingStartCommand(wd)
+	}
+	if startCommand == "" {
+		startCommand = procfileCommand()
+	}
+	// cloud foundry launcher source profile.d again before start command, which export app port found at setup,
+	// port given by sidecars launcher (e.g.: reallocated on conflict) is exported again after it
+	if port, ok := envValue(env, "PORT"); ok {
+		startCommand = fmt.Sprintf("export PORT=%[1]s VCAP_APP_PORT=%[1]s; %s", shellescape.Quote(port), startCommand)
+	}
+	cmd := exec.Command(lPath, wd, startCommand, "")
 	cmd.Env = env
 	cmd.Dir = filepath.Dir(wd)
 	cmd.Stdout = stdOut
@@ -32,6 +49,11 @@ func (s CloudFoundry) StartCmd(env []string, _ string, stdOut, stdErr io.Writer)
 	return cmd, nil
 }
 
+func (s CloudFoundry) WithCommand(command string) Starter {
+	s.Command = command
+	return s
+}
+
 func (s CloudFoundry) Name() string {
 	return cloudenv.CfCloudEnv{}.Name()
 }
@@ -40,19 +62,21 @@ func (s CloudFoundry) Detect() bool {
 	return s.Name() == gautocloud.CurrentCloudEnv().Name()
 }
 
-func (CloudFoundry) getUserStartCommand() string {
-	b, err := ioutil.ReadFile(procFile)
+// getStagingStartCommand give start command detected by buildpacks at staging,
+// droplet is extracted in parent of app dir with staging_info.yml beside app dir
+func (CloudFoundry) getStagingStartCommand(wd string) string {
+	b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(wd), stagingInfoFile))
 	if err != nil {
 		return ""
 	}
-	startCommandS := struct {
-		StartCommand string `yaml:"start"`
+	stagingInfo := struct {
+		StartCommand string `yaml:"start_command"`
 	}{}
-	err = yaml.Unmarshal(b, &startCommandS)
+	err = yaml.Unmarshal(b, &stagingInfo)
 	if err != nil {
 		return ""
 	}
-	return startCommandS.StartCommand
+	return stagingInfo.StartCommand
 }
 
 func (CloudFoundry) launcherPath() string {
@@ -82,3 +106,13 @@ func (s CloudFoundry) ProxyEnv(appPort int) map[string]string {
 		"VCAP_APP_PORT": sPort,
 	}
 }
+
+// envValue give value of key in env given as list of key=value
+func envValue(env []string, key string) (string, bool) {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return strings.TrimPrefix(kv, key+"="), true
+		}
+	}
+	return "", false
+}
@@ -4,14 +4,23 @@ import (
 	"fmt"
 	"github.com/cloudfoundry-community/gautocloud"
 	"github.com/cloudfoundry-community/gautocloud/cloudenv"
-	"gopkg.in/yaml.v2"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"github.com/subosito/gotenv"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"sync"
 )
 
+const dotEnvFile = ".env"
+
+var localPort struct {
+	once sync.Once
+	port int
+}
+
 const launcher = `
 set -e
 cd "$1"
@@ -34,11 +43,23 @@ bash -c "$@"
 `
 
 type Local struct {
+	// Command to start app, by default command is web process of Procfile
+	Command string
 }
 
 func (s Local) StartCmd(env []string, profileDir string, stdOut, stdErr io.Writer) (*exec.Cmd, error) {
 	wd, _ := os.Getwd()
-	cmd := exec.Command("bash", "-c", launcher, os.Args[0], wd, profileDir, s.getUserStartCommand())
+	startCommand := s.Command
+	if startCommand == "" {
+		startCommand = procfileCommand()
+	}
+	if startCommand == "" {
+		return nil, fmt.Errorf("No command found to start app, set it in %s or with app_command in config", procFile)
+	}
+	if !hasEnvKey(env, "PORT") {
+		env = append(env, fmt.Sprintf("PORT=%d", s.AppPort()))
+	}
+	cmd := exec.Command("bash", "-c", launcher, os.Args[0], wd, profileDir, startCommand)
 	cmd.Env = env
 	cmd.Dir = wd
 	cmd.Stdout = stdOut
@@ -46,19 +67,9 @@ func (s Local) StartCmd(env []string, profileDir string, stdOut, stdErr io.Write
 	return cmd, nil
 }
 
-func (Local) getUserStartCommand() string {
-	b, err := ioutil.ReadFile(procFile)
-	if err != nil {
-		return ""
-	}
-	startCommandS := struct {
-		StartCommand string `yaml:"start"`
-	}{}
-	err = yaml.Unmarshal(b, &startCommandS)
-	if err != nil {
-		return ""
-	}
-	return startCommandS.StartCommand
+func (s Local) WithCommand(command string) Starter {
+	s.Command = command
+	return s
 }
 
 func (Local) Name() string {
@@ -78,10 +89,32 @@ func (s Local) ProxyEnv(appPort int) map[string]string {
 	}
 }
 
+// AppPort give port from PORT env var or pick a free port if not set
 func (Local) AppPort() int {
 	port, err := strconv.Atoi(os.Getenv("PORT"))
-	if err != nil {
-		return 8080
+	if err == nil {
+		return port
+	}
+	localPort.once.Do(func() {
+		port, err := utils.FreePort()
+		if err != nil {
+			port = 8080
+		}
+		localPort.port = port
+	})
+	return localPort.port
+}
+
+// LoadDotEnv load env vars from .env file in given dir if exists, already set env vars are not overridden
+func (Local) LoadDotEnv(dir string) error {
+	path := filepath.Join(dir, dotEnvFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
 	}
-	return port
+	return gotenv.Load(path)
+}
+
+func hasEnvKey(env []string, key string) bool {
+	_, ok := envValue(env, key)
+	return ok
 }
@@ -1,7 +1,9 @@
 package starter
 
 import (
+	"gopkg.in/yaml.v2"
 	"io"
+	"io/ioutil"
 	"os/exec"
 )
 
@@ -13,6 +15,12 @@ type Starter interface {
 	Detect() bool
 }
 
+// CommandStarter is a starter which can start app with another command than detected one (e.g.: app_command)
+type CommandStarter interface {
+	// WithCommand give a starter which starts app with given command instead of detected one
+	WithCommand(command string) Starter
+}
+
 func Retrieve() []Starter {
 	return []Starter{
 		BuildpackIO{},
@@ -20,3 +28,24 @@ func Retrieve() []Starter {
 		Local{},
 	}
 }
+
+// procfileCommand give app command from Procfile in current dir as buildpacks do,
+// web process is used and start is kept for procfiles written for previous versions
+func procfileCommand() string {
+	b, err := ioutil.ReadFile(procFile)
+	if err != nil {
+		return ""
+	}
+	processes := struct {
+		Web   string `yaml:"web"`
+		Start string `yaml:"start"`
+	}{}
+	err = yaml.Unmarshal(b, &processes)
+	if err != nil {
+		return ""
+	}
+	if processes.Web != "" {
+		return processes.Web
+	}
+	return processes.Start
+}
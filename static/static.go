@@ -0,0 +1,94 @@
+// Package static is the file server embedded in cloud-sidecars.
+//
+// Like package rproxy, launcher runs it as a sidecar process by executing its own binary with Command as first argument
+// and config given in ConfigEnvKey env var.
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// Command is first argument given to launcher binary to run builtin file server
+	Command = "builtin-static"
+	// ConfigEnvKey is env var giving config of builtin file server as json
+	ConfigEnvKey = "SIDECARS_BUILTIN_STATIC"
+
+	shutdownTimeout = 10 * time.Second
+)
+
+// RunFromEnv serve directory given in config until SIGTERM or SIGINT is received
+func RunFromEnv() error {
+	conf := config.BuiltinStatic{}
+	err := json.Unmarshal([]byte(os.Getenv(ConfigEnvKey)), &conf)
+	if err != nil {
+		return fmt.Errorf("Invalid builtin static config: %s", err.Error())
+	}
+	err = conf.Check()
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(conf.Dir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("Directory to serve is not available: %s", err.Error())
+	}
+	server := &http.Server{
+		Addr:    net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)),
+		Handler: NewHandler(dir, conf),
+	}
+	entry := log.WithField("component", "static")
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigs
+		entry.Info("Stopping builtin file server ...")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	entry.Infof("Builtin file server serving %s on %s", dir, server.Addr)
+	err = server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// NewHandler give handler serving files of dir
+func NewHandler(dir string, conf config.BuiltinStatic) http.Handler {
+	fs := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// path is cleaned as http.Dir does to find file which would be served
+		name := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+		info, err := os.Stat(name)
+		if err != nil && conf.Fallback != "" {
+			http.ServeFile(w, req, filepath.Join(dir, conf.Fallback))
+			return
+		}
+		if err == nil && info.IsDir() && conf.NoListing {
+			if _, err := os.Stat(filepath.Join(name, "index.html")); err != nil {
+				http.NotFound(w, req)
+				return
+			}
+		}
+		fs.ServeHTTP(w, req)
+	})
+}
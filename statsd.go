@@ -0,0 +1,83 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	MetricProcessStarted   = "process.started"
+	MetricProcessExited    = "process.exited"
+	MetricProcessRestarted = "process.restarted"
+	MetricProbeFailed      = "probe.failed"
+	MetricDownloadDuration = "artifact.download.duration"
+	MetricDownloadFailed   = "artifact.download.failed"
+)
+
+// StatsdClient send launcher metrics to a statsd or dogstatsd server,
+// a nil client can be used and does nothing
+type StatsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   []string
+	noTags bool
+}
+
+func NewStatsdClient(conf config.Statsd) (*StatsdClient, error) {
+	conn, err := net.Dial("udp", conf.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsdClient{
+		conn:   conn,
+		prefix: conf.Prefix,
+		tags:   statsdTags(conf.Tags),
+		noTags: conf.NoTags,
+	}, nil
+}
+
+// Incr increment a counter
+func (c *StatsdClient) Incr(name string, tags map[string]string) {
+	c.send(name, "1|c", tags)
+}
+
+// Timing send a duration in milliseconds
+func (c *StatsdClient) Timing(name string, d time.Duration, tags map[string]string) {
+	c.send(name, fmt.Sprintf("%d|ms", d.Nanoseconds()/int64(time.Millisecond)), tags)
+}
+
+func (c *StatsdClient) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *StatsdClient) send(name, value string, tags map[string]string) {
+	if c == nil {
+		return
+	}
+	metric := fmt.Sprintf("%s%s:%s", c.prefix, name, value)
+	allTags := append(append([]string{}, c.tags...), statsdTags(tags)...)
+	if !c.noTags && len(allTags) > 0 {
+		metric += "|#" + strings.Join(allTags, ",")
+	}
+	_, err := c.conn.Write([]byte(metric))
+	if err != nil {
+		log.WithField("component", "statsd").Debugf("Could not send metric %s: %s", name, err.Error())
+	}
+}
+
+func statsdTags(tags map[string]string) []string {
+	result := make([]string, 0, len(tags))
+	for k, v := range tags {
+		result = append(result, k+":"+v)
+	}
+	sort.Strings(result)
+	return result
+}
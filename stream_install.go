@@ -0,0 +1,223 @@
+package sidecars
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"github.com/ArthurHlt/zipper"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const streamBufferSize = 64 * 1024
+
+// streamInstallSidecar install http artifact of sidecar while downloading it and run its after install script,
+// it replaces download as zip and extraction done for other artifacts
+func (l Launcher) streamInstallSidecar(sidecar *config.Sidecar, source *config.Sidecar, dir string) error {
+	metricTags := map[string]string{"sidecar": sidecar.Name}
+	startDownload := time.Now()
+	validators, err := l.streamInstallArtifact(sidecar, source, dir)
+	if err != nil {
+		l.metrics.Incr(MetricDownloadFailed, metricTags)
+		return NewSidecarError(sidecar, err)
+	}
+	l.metrics.Timing(MetricDownloadDuration, time.Since(startDownload), metricTags)
+	// extraction is done while downloading, its time is part of download
+	l.setupTimings.record(sidecar.Name, SetupPhaseDownload, startDownload)
+	// a previous download which has not been installed must not be extracted over this installation
+	if index, ok := l.indexer.Index(sidecar); ok {
+		os.Remove(filepath.Join(l.sConfig.Dir, index.ZipFile))
+		l.indexer.RemoveIndex(index)
+		err = l.indexer.Store()
+		if err != nil {
+			return err
+		}
+	}
+	return l.afterInstallSidecar(sidecar, validators)
+}
+
+// streamInstallArtifact install an http artifact with a single request: tar archives (compressed or not) and single files
+// are extracted while downloading and zip archives, which can't be read sequentially, are written once in sidecar dir before extraction.
+// Sha1 of artifact is computed on the fly, sidecar dir is removed when it mismatches.
+func (l Launcher) streamInstallArtifact(sidecar *config.Sidecar, source *config.Sidecar, dir string) (HttpValidators, error) {
+	entry := log.WithField("component", "Downloader").WithField("sidecar", sidecar.Name)
+	uz := Unzip{
+		Dest:            dir,
+		StripComponents: sidecar.StripComponents,
+		Subpath:         sidecar.ArtifactSubpath,
+		AllowUnsafe:     l.unsafeExtract,
+	}
+	shared := l.downloads.artifact(source)
+	if sharedPath, ok := shared.reusable(); ok {
+		entry.Infof("Extracting artifact %s downloaded for another sidecar ...", source.ArtifactURI)
+		err := extractSharedArtifact(sharedPath, uz, artifactFileName(source.ArtifactURI), sidecar.Name+".zip", l.maxArtifactSize(sidecar))
+		if err != nil {
+			os.RemoveAll(dir)
+			return HttpValidators{}, err
+		}
+		return shared.validators, nil
+	}
+	entry.Infof("Downloading and extracting from %s ...", source.ArtifactURI)
+	client := &http.Client{}
+	if maxSize := l.maxArtifactSize(sidecar); maxSize > 0 {
+		client.Transport = sizeLimitTransport{base: http.DefaultTransport, maxSize: maxSize}
+	}
+	resp, err := streamGet(client, source.ArtifactURI)
+	if err != nil {
+		return HttpValidators{}, err
+	}
+	defer resp.Body.Close()
+	hash := sha1.New()
+	var w io.Writer = hash
+	// artifact used by other sidecars is also written as is to be extracted for them without downloading it again
+	spool, err := l.downloads.spoolFile(shared)
+	if err != nil {
+		return HttpValidators{}, err
+	}
+	if spool != nil {
+		defer spool.Close()
+		w = io.MultiWriter(hash, spool)
+	}
+	br := bufio.NewReaderSize(io.TeeReader(resp.Body, w), streamBufferSize)
+	err = streamExtract(br, uz, artifactFileName(source.ArtifactURI), sidecar.Name+".zip", l.maxArtifactSize(sidecar))
+	if err == nil {
+		// rest of body (e.g.: tar padding) is read to get sha1 of whole artifact
+		_, err = io.Copy(ioutil.Discard, br)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return HttpValidators{}, err
+	}
+	if sha1Sum := fmt.Sprintf("%x", hash.Sum(nil)); source.ArtifactSha1 != "" && sha1Sum != source.ArtifactSha1 {
+		os.RemoveAll(dir)
+		return HttpValidators{}, fmt.Errorf("Sha1 '%s' mismatch with current sha1 '%s'.", source.ArtifactSha1, sha1Sum)
+	}
+	entry.Infof("Finished downloading and extracting from %s .", source.ArtifactURI)
+	validators := HttpValidators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if spool != nil && spool.Sync() == nil {
+		shared.path = spool.Name()
+		shared.validators = validators
+	}
+	return validators, nil
+}
+
+// extractSharedArtifact extract an artifact already downloaded and checked for another sidecar
+func extractSharedArtifact(artifactPath string, uz Unzip, fileName, zipName string, maxSize int64) error {
+	f, err := os.Open(artifactPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return streamExtract(bufio.NewReaderSize(f, streamBufferSize), uz, fileName, zipName, maxSize)
+}
+
+func streamGet(client *http.Client, uri string) (*http.Response, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	user := u.User
+	u.User = nil
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && user.Username() != "" {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error occured when downloading file: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// artifactFileName give file name of artifact from its url
+func artifactFileName(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return path.Base(uri)
+	}
+	return path.Base(u.Path)
+}
+
+// streamExtract extract content read from br in dest of uz, content is detected as zipper does from magic bytes or file name,
+// decompressed content bigger than maxSize bytes is rejected (0 means no limit)
+func streamExtract(br *bufio.Reader, uz Unzip, fileName, zipName string, maxSize int64) error {
+	magic, _ := br.Peek(4)
+	if zipper.HasExtFile(fileName, zipper.ZIP_FILE_EXT...) || isZipMagic(magic) {
+		// zip archive directory is at its end, it must be written before extracting
+		uz.Src = filepath.Join(uz.Dest, zipName)
+		err := os.MkdirAll(uz.Dest, 0755)
+		if err != nil {
+			return err
+		}
+		err = writeFile(uz.Src, br, 0644)
+		if err != nil {
+			os.Remove(uz.Src)
+			return err
+		}
+		return uz.Extract()
+	}
+	content, err := decompressReader(br)
+	if err != nil {
+		return err
+	}
+	if content == nil {
+		return streamSingleFile(br, uz, fileName)
+	}
+	cbr := bufio.NewReaderSize(limitDecompressed(content, maxSize), streamBufferSize)
+	if isTarContent(cbr) {
+		return uz.ExtractTar(cbr)
+	}
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, compressedExt := range compressedFileExts {
+		if ext == compressedExt {
+			fileName = strings.TrimSuffix(fileName, path.Ext(fileName))
+			break
+		}
+	}
+	return streamSingleFile(cbr, uz, fileName)
+}
+
+// streamSingleFile write content in dest as fileName, it is made executable if it is a binary or a script
+func streamSingleFile(br *bufio.Reader, uz Unzip, fileName string) error {
+	name := uz.targetName(fileName)
+	if name == "" {
+		return nil
+	}
+	err := os.MkdirAll(uz.Dest, 0755)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	head, _ := br.Peek(4)
+	if zipper.IsExecutable(bytes.NewReader(head)) {
+		perm = 0755
+	}
+	return writeFile(filepath.Join(uz.Dest, filepath.FromSlash(name)), br, perm)
+}
+
+func isZipMagic(magic []byte) bool {
+	return len(magic) == 4 && magic[0] == 'P' && magic[1] == 'K' &&
+		(magic[2] == 0x03 || magic[2] == 0x05 || magic[2] == 0x07) &&
+		(magic[3] == 0x04 || magic[3] == 0x06 || magic[3] == 0x08)
+}
@@ -0,0 +1,27 @@
+package sidecars
+
+import (
+	"github.com/olekukonko/tablewriter"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"io"
+)
+
+// newTable create a table writing in w, borders are removed when w is not a terminal
+// to keep output readable in logs and easy to parse
+func newTable(w io.Writer) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	if utils.IsTerminal(w) {
+		return table
+	}
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetBorder(false)
+	table.SetHeaderLine(false)
+	table.SetColumnSeparator("")
+	table.SetCenterSeparator("")
+	table.SetRowSeparator("")
+	table.SetTablePadding("  ")
+	table.SetNoWhiteSpace(true)
+	return table
+}
@@ -1,49 +1,31 @@
 package sidecars
 
 import (
-	"github.com/gliderlabs/sigil"
-	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	"github.com/orange-cloudfoundry/cloud-sidecars/env"
 )
 
-func init() {
-	sigil.PosixPreprocess = true
-}
-
+// OverrideEnv give old env overridden by new templated values, see env.Override
 func OverrideEnv(old, new map[string]string) (map[string]string, error) {
-	newUnTpl, err := TemplatingEnv(old, new)
-	if err != nil {
-		return map[string]string{}, err
-	}
-	return utils.MergeEnv(old, newUnTpl), nil
+	return env.Override(old, new)
 }
 
+// TemplatingEnv give new values templated with old env, see env.TemplateValues
 func TemplatingEnv(old, new map[string]string) (map[string]string, error) {
-	for k, v := range new {
-		newV, err := TemplatingFromEnv(old, v)
-		if err != nil {
-			return new, err
-		}
-		new[k] = newV
-	}
-	return new, nil
+	return env.TemplateValues(old, new)
+}
+
+// TemplatingArgs give args templated with env, see env.TemplateArgs
+func TemplatingArgs(envVars map[string]string, args ...string) ([]string, error) {
+	return env.TemplateArgs(envVars, args...)
 }
 
-func TemplatingArgs(env map[string]string, args ...string) ([]string, error) {
-	var err error
-	for i, arg := range args {
-		args[i], err = TemplatingFromEnv(env, arg)
-		if err != nil {
-			return args, err
-		}
-	}
-	return args, nil
+// TemplatingFromEnv render a template with env, see env.Template
+func TemplatingFromEnv(envVars map[string]string, s string) (string, error) {
+	return env.Template(envVars, s)
 }
 
-func TemplatingFromEnv(env map[string]string, s string) (string, error) {
-	// sigil allow $ENV_VAR in templating
-	buf, err := sigil.Execute([]byte(s), utils.MapCast(env), "env-tpl")
-	if err != nil {
-		return "", err
-	}
-	return buf.String(), nil
+// TemplatingScript render templates of a shell script with env,
+// unlike TemplatingFromEnv $VAR are left as is to be expanded by shell, see env.TemplateScript
+func TemplatingScript(envVars map[string]string, script string) (string, error) {
+	return env.TemplateScript(envVars, script)
 }
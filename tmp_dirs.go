@@ -0,0 +1,53 @@
+package sidecars
+
+import (
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	TmpDirEnvKey = "TMPDIR"
+	pathTmpDirs  = "tmp"
+)
+
+// SidecarsTmpDir give dir containing temporary dir of each sidecar process
+func SidecarsTmpDir(baseDir string) string {
+	return filepath.Join(baseDir, PathSidecarsWd, pathTmpDirs)
+}
+
+// tmpDirEnv give env of sidecar process with TMPDIR set to its own temporary dir .sidecars/tmp/<name>
+// to not share temporary files with other sidecars, TMPDIR set in env of sidecar is kept
+func (f *ProcessFactory) tmpDirEnv(sidecar *config.Sidecar, env map[string]string) (map[string]string, error) {
+	for key := range sidecar.Env {
+		if strings.EqualFold(key, TmpDirEnvKey) {
+			return env, nil
+		}
+	}
+	dir, err := filepath.Abs(filepath.Join(SidecarsTmpDir(f.wd), sidecar.Name))
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	tmpEnv := map[string]string{TmpDirEnvKey: dir}
+	if runtime.GOOS == "windows" {
+		tmpEnv["TMP"] = dir
+		tmpEnv["TEMP"] = dir
+	}
+	return utils.MergeEnv(env, tmpEnv), nil
+}
+
+// removeTmpDirs remove temporary dirs of sidecar processes
+func (l Launcher) removeTmpDirs() {
+	err := os.RemoveAll(SidecarsTmpDir(l.sConfig.Dir))
+	if err != nil {
+		log.WithField("component", "Launcher").Warnf("Temporary dirs of sidecars could not be removed: %s", err.Error())
+	}
+}
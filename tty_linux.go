@@ -0,0 +1,160 @@
+//go:build linux
+// +build linux
+
+package sidecars
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// ttyCmdHandler run a command attached to a new pseudo-terminal
+// which is connected to given stdin and stdout
+type ttyCmdHandler struct {
+	cmd        *exec.Cmd
+	stdin      io.Reader
+	stdout     io.Writer
+	master     *os.File
+	outputDone chan struct{}
+	restore    func()
+}
+
+func newTtyCmdHandler(cmd *exec.Cmd, stdin io.Reader, stdout io.Writer) (CmdHandler, error) {
+	return &ttyCmdHandler{
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     stdout,
+		outputDone: make(chan struct{}),
+		restore:    func() {},
+	}, nil
+}
+
+func (h *ttyCmdHandler) Run() error {
+	err := h.Start()
+	if err != nil {
+		return err
+	}
+	return h.Wait()
+}
+
+func (h *ttyCmdHandler) Start() error {
+	master, slave, err := openPty()
+	if err != nil {
+		return err
+	}
+	defer slave.Close()
+	h.master = master
+
+	h.cmd.Stdin = slave
+	h.cmd.Stdout = slave
+	h.cmd.Stderr = slave
+	if h.cmd.SysProcAttr == nil {
+		h.cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	// process become leader of a new session with pty as controlling terminal,
+	// setpgid is not allowed for a session leader
+	h.cmd.SysProcAttr.Setpgid = false
+	h.cmd.SysProcAttr.Setsid = true
+	h.cmd.SysProcAttr.Setctty = true
+	h.cmd.SysProcAttr.Ctty = 0
+
+	if f, ok := h.stdin.(*os.File); ok {
+		h.setupTerminal(int(f.Fd()))
+	}
+
+	err = h.cmd.Start()
+	if err != nil {
+		h.restore()
+		master.Close()
+		return err
+	}
+	go func() {
+		io.Copy(master, h.stdin)
+		// forward end of input as an EOT character like a terminal would do
+		master.Write([]byte{4})
+	}()
+	go func() {
+		io.Copy(h.stdout, master)
+		close(h.outputDone)
+	}()
+	return nil
+}
+
+func (h *ttyCmdHandler) Wait() error {
+	err := h.cmd.Wait()
+	<-h.outputDone
+	h.restore()
+	h.master.Close()
+	return err
+}
+
+// setupTerminal put launcher terminal in raw mode and follow its size
+// it does nothing if fd is not a terminal
+func (h *ttyCmdHandler) setupTerminal(fd int) {
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return
+	}
+	raw := *termios
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	// output processing is kept for sidecars and launcher logs which are written on same terminal
+	err = unix.IoctlSetTermios(fd, unix.TCSETS, &raw)
+	if err != nil {
+		return
+	}
+
+	resize := func() {
+		ws, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ)
+		if err != nil {
+			return
+		}
+		unix.IoctlSetWinsize(int(h.master.Fd()), unix.TIOCSWINSZ, ws)
+	}
+	resize()
+	sigWinch := make(chan os.Signal, 1)
+	signal.Notify(sigWinch, syscall.SIGWINCH)
+	go func() {
+		for range sigWinch {
+			resize()
+		}
+	}()
+
+	h.restore = func() {
+		signal.Stop(sigWinch)
+		unix.IoctlSetTermios(fd, unix.TCSETS, termios)
+	}
+}
+
+func openPty() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fd := int(master.Fd())
+	err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	ptyNumber, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", ptyNumber), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package sidecars
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+)
+
+func newTtyCmdHandler(cmd *exec.Cmd, stdin io.Reader, stdout io.Writer) (CmdHandler, error) {
+	return nil, fmt.Errorf("Tty mode is not supported on %s", runtime.GOOS)
+}
@@ -1,19 +1,110 @@
 package sidecars
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 type Unzip struct {
 	Src  string
 	Dest string
+	// StripComponents remove given number of leading path elements from file names
+	StripComponents int
+	// Subpath only extract files under this path (after stripping components)
+	Subpath string
+	// AllowUnsafe let entries and symlinks escape dest directory
+	AllowUnsafe bool
 }
 
 func NewUnzip(src string, dest string) Unzip {
-	return Unzip{src, dest}
+	return Unzip{Src: src, Dest: dest}
+}
+
+// targetName give file name inside dest directory or empty string if file must not be extracted
+func (uz Unzip) targetName(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if uz.StripComponents > 0 {
+		parts := strings.Split(name, "/")
+		if len(parts) <= uz.StripComponents {
+			return ""
+		}
+		name = strings.Join(parts[uz.StripComponents:], "/")
+	}
+	subpath := strings.Trim(path.Clean("/"+uz.Subpath), "/")
+	if subpath == "" {
+		return name
+	}
+	if !strings.HasPrefix(name, subpath+"/") {
+		return ""
+	}
+	return strings.TrimPrefix(name, subpath+"/")
+}
+
+// checkEntryName reject entry names escaping dest directory
+func checkEntryName(name string) error {
+	cleaned := path.Clean(strings.Replace(name, "\\", "/", -1))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("Unsafe entry '%s' in archive: it escapes target directory", name)
+	}
+	return nil
+}
+
+// checkSymlinkTarget reject symlinks pointing outside of dest directory
+func checkSymlinkTarget(name, target string) error {
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return fmt.Errorf("Unsafe symlink '%s' in archive: absolute target '%s'", name, target)
+	}
+	resolved := path.Clean(path.Join(path.Dir(name), filepath.ToSlash(target)))
+	if resolved == ".." || strings.HasPrefix(resolved, "../") {
+		return fmt.Errorf("Unsafe symlink '%s' in archive: target '%s' escapes target directory", name, target)
+	}
+	return nil
+}
+
+// checkRealPath reject entries whose parent directory, once symlinks already extracted are followed, is outside of dest directory,
+// symlink target is also checked from real parent directory, it give an error when chained symlinks escape dest directory
+func (uz Unzip) checkRealPath(name, target, linkname string) error {
+	realDest, err := filepath.EvalSymlinks(uz.Dest)
+	if err != nil {
+		return err
+	}
+	// parent directory may not be created yet, only its longest existing part can be resolved
+	parent := filepath.Dir(target)
+	rest := ""
+	for parent != filepath.Dir(parent) {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		rest = filepath.Join(filepath.Base(parent), rest)
+		parent = filepath.Dir(parent)
+	}
+	realParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return fmt.Errorf("Unsafe entry '%s' in archive: %s", name, err.Error())
+	}
+	realParent = filepath.Join(realParent, rest)
+	if !isSubPath(realDest, realParent) {
+		return fmt.Errorf("Unsafe entry '%s' in archive: it is extracted through a symlink escaping target directory", name)
+	}
+	if linkname != "" && !isSubPath(realDest, filepath.Join(realParent, filepath.FromSlash(linkname))) {
+		return fmt.Errorf("Unsafe symlink '%s' in archive: target '%s' escapes target directory", name, linkname)
+	}
+	return nil
+}
+
+func isSubPath(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
 }
 
 func (uz Unzip) Extract() error {
@@ -41,8 +132,40 @@ func (uz Unzip) Extract() error {
 			}
 		}()
 
-		path := filepath.Join(uz.Dest, f.Name)
+		if !uz.AllowUnsafe {
+			if err := checkEntryName(f.Name); err != nil {
+				return err
+			}
+		}
+		name := uz.targetName(f.Name)
+		if name == "" {
+			return nil
+		}
+		path := filepath.Join(uz.Dest, filepath.FromSlash(name))
 
+		if f.Mode()&os.ModeSymlink != 0 {
+			target, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+			if err != nil {
+				return err
+			}
+			if !uz.AllowUnsafe {
+				if err := checkSymlinkTarget(name, string(target)); err != nil {
+					return err
+				}
+				if err := uz.checkRealPath(name, path, string(target)); err != nil {
+					return err
+				}
+			}
+			os.MkdirAll(filepath.Dir(path), 0755)
+			os.Remove(path)
+			return os.Symlink(string(target), path)
+		}
+
+		if !uz.AllowUnsafe {
+			if err := uz.checkRealPath(name, path, ""); err != nil {
+				return err
+			}
+		}
 		if f.FileInfo().IsDir() {
 			os.MkdirAll(path, f.Mode())
 		} else {
@@ -75,3 +198,83 @@ func (uz Unzip) Extract() error {
 
 	return nil
 }
+
+// ExtractTar extract tar archive read from r in dest directory while reading it, as zipper does a leading directory
+// is removed if archive starts with one, Src is not used
+func (uz Unzip) ExtractTar(r io.Reader) error {
+	err := os.MkdirAll(uz.Dest, 0755)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	rootFolder := ""
+	first := true
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fileInfo := header.FileInfo()
+		if first && fileInfo.IsDir() {
+			rootFolder = strings.TrimSuffix(path.Clean(header.Name), "/") + "/"
+			first = false
+			continue
+		}
+		first = false
+		entryName := strings.TrimPrefix(path.Clean(header.Name), rootFolder)
+		if !uz.AllowUnsafe {
+			if err := checkEntryName(entryName); err != nil {
+				return err
+			}
+		}
+		name := uz.targetName(entryName)
+		if name == "" {
+			continue
+		}
+		target := filepath.Join(uz.Dest, filepath.FromSlash(name))
+		if !uz.AllowUnsafe {
+			linkname := ""
+			if header.Typeflag == tar.TypeSymlink {
+				linkname = header.Linkname
+			}
+			if err := uz.checkRealPath(name, target, linkname); err != nil {
+				return err
+			}
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, fileInfo.Mode().Perm()|0700)
+		case tar.TypeSymlink:
+			if !uz.AllowUnsafe {
+				if err := checkSymlinkTarget(name, header.Linkname); err != nil {
+					return err
+				}
+			}
+			os.MkdirAll(filepath.Dir(target), 0755)
+			os.Remove(target)
+			err = os.Symlink(header.Linkname, target)
+		case tar.TypeReg, tar.TypeRegA:
+			os.MkdirAll(filepath.Dir(target), 0755)
+			err = writeFile(target, tr, fileInfo.Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,143 @@
+package sidecars
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type archiveEntry struct {
+	name    string
+	content string
+	symlink bool
+}
+
+func writeZip(t *testing.T, path string, entries []archiveEntry) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		header.SetMode(0644)
+		if entry.symlink {
+			header.SetMode(os.ModeSymlink | 0777)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(entry.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tarReader(t *testing.T, entries []archiveEntry) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(entry.content))}
+		if entry.symlink {
+			header = &tar.Header{Name: entry.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: entry.content}
+		}
+		err := tw.WriteHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !entry.symlink {
+			_, err = tw.Write([]byte(entry.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+var unsafeArchives = map[string][]archiveEntry{
+	"parent dir entry": {
+		{name: "bin/sidecar", content: "sidecar"},
+		{name: "../evil", content: "evil"},
+	},
+	"absolute entry": {
+		{name: "/tmp/evil", content: "evil"},
+	},
+	"symlink escaping dest": {
+		{name: "link", content: "../outside", symlink: true},
+	},
+	"absolute symlink": {
+		{name: "link", content: "/etc", symlink: true},
+	},
+	"file written through symlink escaping dest": {
+		{name: "dir/self", content: "..", symlink: true},
+		{name: "dir/self/up", content: "..", symlink: true},
+	},
+}
+
+func TestExtractRejectsEntriesEscapingDest(t *testing.T) {
+	for desc, entries := range unsafeArchives {
+		dir, err := ioutil.TempDir("", "sidecars-unzip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dest := filepath.Join(dir, "dest")
+		zipPath := filepath.Join(dir, "artifact.zip")
+		writeZip(t, zipPath, entries)
+
+		err = NewUnzip(zipPath, dest).Extract()
+		if err == nil {
+			t.Errorf("Expected zip with %s to be rejected", desc)
+		}
+		err = Unzip{Dest: filepath.Join(dir, "dest-tar")}.ExtractTar(tarReader(t, entries))
+		if err == nil {
+			t.Errorf("Expected tar with %s to be rejected", desc)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "evil")); err == nil {
+			t.Errorf("Entry of archive with %s has been written outside of dest", desc)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestExtractAllowsSymlinksInsideDest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidecars-unzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "dest")
+	zipPath := filepath.Join(dir, "artifact.zip")
+	writeZip(t, zipPath, []archiveEntry{
+		{name: "bin/sidecar", content: "sidecar"},
+		{name: "current", content: "bin", symlink: true},
+		{name: "lib/sidecar", content: "../bin/sidecar", symlink: true},
+	})
+
+	err = NewUnzip(zipPath, dest).Extract()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dest, "lib", "sidecar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "sidecar" {
+		t.Fatalf("Expected symlink to give sidecar content, got %q", string(b))
+	}
+}
@@ -1,40 +1,54 @@
 package utils
 
 import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/env"
+	"io"
+	"net"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"syscall"
 )
 
+// MergeEnv give old env overridden by new one, see env.Merge
 func MergeEnv(old, new map[string]string) map[string]string {
-	for k, v := range new {
-		old[k] = v
-	}
-	return old
+	return env.Merge(old, new)
 }
 
+// EnvToMap see env.FromList
 func EnvToMap(envv []string) map[string]string {
-	env := make(map[string]string)
-	for _, e := range envv {
-		kv := strings.SplitN(e, "=", 2)
-		env[kv[0]] = kv[1]
-	}
-	return env
+	return env.FromList(envv)
 }
 
+// OsEnvToMap see env.FromOs
 func OsEnvToMap() map[string]string {
-	return EnvToMap(os.Environ())
+	return env.FromOs()
+}
+
+// EnvMapToOsEnv see env.ToList
+func EnvMapToOsEnv(envVars map[string]string) []string {
+	return env.ToList(envVars)
+}
+
+// FreePort give a port on which nothing listen
+func FreePort() (int, error) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
 }
 
-func EnvMapToOsEnv(env map[string]string) []string {
-	envv := make([]string, len(env))
-	i := 0
-	for k, v := range env {
-		envv[i] = k + "=" + v
-		i++
+func InStrings(s string, slice []string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
 	}
-	return envv
+	return false
 }
 
 func MapCast(m map[string]string) map[string]interface{} {
@@ -69,3 +83,98 @@ func HasPgidSysProcAttr(attr *syscall.SysProcAttr) bool {
 	valSetpgid := val.FieldByName("Setpgid")
 	return valSetpgid != (reflect.Value{}) && valSetpgid.Kind() == reflect.Bool && valSetpgid.Bool()
 }
+
+// SplitArgs split a command line in arguments as a posix shell would do for quotes and escapes
+// but without any expansion
+func SplitArgs(s string) ([]string, error) {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("Unterminated quote or escape in '%s'", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// IsTerminal check if writer is a terminal, output redirected to a file or a pipe (e.g.: platform logs) is not
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// NoColor check if colors are disabled by NO_COLOR (see https://no-color.org) or CLICOLOR=0
+func NoColor() bool {
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("CLICOLOR") == "0"
+}
+
+// IsNewerVersion compare dotted versions (e.g.: 1.10.0 is newer than 1.9.2), prerelease suffixes are ignored
+func IsNewerVersion(latest, current string) bool {
+	l := versionParts(latest)
+	c := versionParts(current)
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var lp, cp int
+		if i < len(l) {
+			lp = l[i]
+		}
+		if i < len(c) {
+			cp = c[i]
+		}
+		if lp != cp {
+			return lp > cp
+		}
+	}
+	return false
+}
+
+func versionParts(version string) []int {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := make([]int, 0)
+	for _, p := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,183 @@
+package sidecars
+
+import (
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	"github.com/orange-cloudfoundry/cloud-sidecars/utils"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	VerifyStatusOk      = "ok"
+	VerifyStatusDrift   = "drift"
+	VerifyStatusMissing = "missing"
+	VerifyStatusUnknown = "unknown"
+
+	VerifyAtLaunchFail      = "fail"
+	VerifyAtLaunchReinstall = "reinstall"
+)
+
+type VerifyResult struct {
+	Name     string
+	Target   string
+	Expected string
+	Current  string
+	Status   string
+}
+
+// VerifySidecars re-hash stored archives or extracted sidecars directories
+// and compare them with checksums recorded in index or lock file
+func (l Launcher) VerifySidecars(names ...string) ([]VerifyResult, error) {
+	results := make([]VerifyResult, 0)
+	for _, sidecar := range l.sConfig.Sidecars {
+		if sidecar.ArtifactURI == "" {
+			continue
+		}
+		if len(names) > 0 && !utils.InStrings(sidecar.Name, names) {
+			continue
+		}
+		result, err := l.verifySidecar(sidecar)
+		if err != nil {
+			return results, NewSidecarError(sidecar, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (l Launcher) verifySidecar(sidecar *config.Sidecar) (VerifyResult, error) {
+	result := VerifyResult{
+		Name: sidecar.Name,
+	}
+	var target, expected string
+	var checksumFunc func(string) (string, error)
+	if index, ok := l.indexer.Index(sidecar); ok {
+		target = filepath.Join(l.sConfig.Dir, index.ZipFile)
+		expected = index.Checksum
+		checksumFunc = fileChecksum
+		result.Target = "archive"
+	} else {
+		target = SidecarInstallDir(l.sConfig.Dir, sidecar)
+		lock, _ := l.locker.Lock(sidecar)
+		expected = lock.Checksum
+		checksumFunc = DirChecksum
+		result.Target = "directory"
+	}
+	result.Expected = expected
+
+	if _, err := os.Stat(target); os.IsNotExist(err) {
+		result.Status = VerifyStatusMissing
+		return result, nil
+	}
+	current, err := checksumFunc(target)
+	if err != nil {
+		return result, err
+	}
+	result.Current = current
+	switch {
+	case expected == "":
+		result.Status = VerifyStatusUnknown
+	case expected != current:
+		result.Status = VerifyStatusDrift
+	default:
+		result.Status = VerifyStatusOk
+	}
+	return result, nil
+}
+
+// ShowVerifySidecars print verification result as a table
+// and return an error if any sidecar has drifted or is missing
+func (l Launcher) ShowVerifySidecars(names ...string) error {
+	results, err := l.VerifySidecars(names...)
+	if err != nil {
+		return err
+	}
+	table := newTable(l.stdout)
+	table.SetHeader([]string{"Sidecar Name", "Target", "Expected", "Current", "Status"})
+	inError := make([]string, 0)
+	for _, result := range results {
+		table.Append([]string{
+			result.Name,
+			result.Target,
+			shortChecksum(result.Expected),
+			shortChecksum(result.Current),
+			result.Status,
+		})
+		if result.Status == VerifyStatusDrift || result.Status == VerifyStatusMissing {
+			inError = append(inError, result.Name)
+		}
+	}
+	table.Render()
+	if len(inError) > 0 {
+		return fmt.Errorf("Verification failed for sidecars: %s", strings.Join(inError, ", "))
+	}
+	return nil
+}
+
+func shortChecksum(checksum string) string {
+	if checksum == "" {
+		return "-"
+	}
+	if len(checksum) > 12 {
+		return checksum[:12]
+	}
+	return checksum
+}
+
+// verifyAtLaunch verify sidecars to launch before starting them to detect a tampered droplet,
+// sidecars which have drifted or are missing make launch fail or are downloaded and installed again
+func (l Launcher) verifyAtLaunch() error {
+	entry := log.WithField("component", "Launcher")
+	policy := l.sConfig.VerifyAtLaunch
+	if policy != VerifyAtLaunchFail && policy != VerifyAtLaunchReinstall {
+		return fmt.Errorf("Invalid verify_at_launch '%s', it must be %s or %s", policy, VerifyAtLaunchFail, VerifyAtLaunchReinstall)
+	}
+	entry.Info("Verifying installed sidecars ...")
+	results, err := l.VerifySidecars()
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		switch result.Status {
+		case VerifyStatusOk:
+			continue
+		case VerifyStatusUnknown:
+			entry.WithField("sidecar", result.Name).Warn("No checksum has been recorded at setup, sidecar can't be verified")
+			continue
+		}
+		sidecar := l.sConfig.SidecarByName(result.Name)
+		if policy == VerifyAtLaunchFail {
+			return NewSidecarError(sidecar, fmt.Errorf("%s, refusing to launch", verifyProblem(result)))
+		}
+		entry.WithField("sidecar", result.Name).Warnf("%s, sidecar is downloaded and installed again", verifyProblem(result))
+		err := l.reinstallSidecar(sidecar)
+		if err != nil {
+			return err
+		}
+	}
+	entry.Info("Finished verifying installed sidecars.")
+	return nil
+}
+
+// reinstallSidecar remove installed files of sidecar to download and install it again
+func (l Launcher) reinstallSidecar(sidecar *config.Sidecar) error {
+	err := l.resetSidecarSetup(sidecar)
+	if err != nil {
+		return NewSidecarError(sidecar, err)
+	}
+	err = l.downloadArtifact(sidecar)
+	if err != nil {
+		return err
+	}
+	return l.setupSidecarArtifact(sidecar)
+}
+
+func verifyProblem(result VerifyResult) string {
+	if result.Status == VerifyStatusMissing {
+		return fmt.Sprintf("Installed %s is missing", result.Target)
+	}
+	return fmt.Sprintf("Installed %s has been modified since setup", result.Target)
+}
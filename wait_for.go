@@ -0,0 +1,114 @@
+package sidecars
+
+import (
+	"errors"
+	"fmt"
+	"github.com/orange-cloudfoundry/cloud-sidecars/config"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWaitForTimeout = 60
+	waitForCheckTimeout   = 2 * time.Second
+	waitForInterval       = time.Second
+)
+
+var errWaitStopped = errors.New("Stopped while waiting for dependencies")
+
+// waitForDependencies wait for each dependency to be reachable,
+// errWaitStopped is returned if stop is closed while waiting
+func waitForDependencies(deps []*config.WaitFor, stop <-chan struct{}, entry *log.Entry) error {
+	for _, dep := range deps {
+		err := dep.Check()
+		if err != nil {
+			return err
+		}
+		timeout := dep.Timeout
+		if timeout == 0 {
+			timeout = defaultWaitForTimeout
+		}
+		deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+		entry.Infof("Waiting for %s to be reachable ...", dep)
+		for {
+			err = checkDependency(dep)
+			if err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%s is still not reachable after %d seconds: %s", dep, timeout, err.Error())
+			}
+			select {
+			case <-stop:
+				return errWaitStopped
+			case <-time.After(waitForInterval):
+			}
+		}
+		entry.Infof("Finished waiting for %s to be reachable.", dep)
+	}
+	return nil
+}
+
+// waitForProcesses wait for processes to be started, a process listening on an address (i.e.: app) is waited
+// until it accepts connections and an error is returned if it exits before,
+// errWaitStopped is returned if stop is closed while waiting
+func waitForProcesses(processes []*process, stop <-chan struct{}, entry *log.Entry) error {
+	names := make([]string, len(processes))
+	for i, p := range processes {
+		names[i] = p.name
+	}
+	entry.Infof("Waiting for %s to be started ...", strings.Join(names, ", "))
+	for _, p := range processes {
+		var listening *config.WaitFor
+		if p.listenAddr != "" {
+			listening = &config.WaitFor{Tcp: p.listenAddr}
+		}
+		for {
+			state := p.Status().State
+			if listening == nil && state != ProcessStateCreated && state != ProcessStateWaiting {
+				break
+			}
+			if listening != nil && (state == ProcessStateExited || state == ProcessStateFailed) {
+				return fmt.Errorf("%s %s has exited before accepting connections on %s", p.typeP, p.name, p.listenAddr)
+			}
+			if listening != nil && state == ProcessStateRunning && checkDependency(listening) == nil {
+				break
+			}
+			select {
+			case <-stop:
+				return errWaitStopped
+			case <-time.After(startAfterInterval):
+			}
+		}
+	}
+	entry.Infof("Finished waiting for %s to be started.", strings.Join(names, ", "))
+	return nil
+}
+
+func checkDependency(dep *config.WaitFor) error {
+	if dep.Tcp != "" {
+		conn, err := net.DialTimeout("tcp", dep.Tcp, waitForCheckTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	client := &http.Client{
+		Timeout: waitForCheckTimeout,
+	}
+	resp, err := client.Get(dep.Http)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if dep.Status > 0 && resp.StatusCode != dep.Status {
+		return fmt.Errorf("status %d received instead of %d", resp.StatusCode, dep.Status)
+	}
+	if dep.Status == 0 && resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d received", resp.StatusCode)
+	}
+	return nil
+}
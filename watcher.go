@@ -0,0 +1,152 @@
+package sidecars
+
+import (
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+	"path/filepath"
+	"time"
+)
+
+// watchDebounce is time to wait after last change on watched files before applying changes,
+// files are often written in several steps by config management agents
+const watchDebounce = time.Second
+
+// WatchConfig make launcher reload config when config file changes and restart sidecars
+// when one of their watch_files changes, a config loader must be set to reload config
+func (l *Launcher) WatchConfig(configPath string) {
+	l.watchedConfig = configPath
+}
+
+type filesWatcher struct {
+	launcher   *Launcher
+	watcher    *fsnotify.Watcher
+	configPath string
+	// files give sidecars to restart for each watched file
+	files map[string][]string
+	dirs  map[string]bool
+}
+
+// watchFiles watch config file and sidecars watch_files until launcher is stopped
+func (l *Launcher) watchFiles(done chan struct{}) (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	configPath, err := filepath.Abs(l.watchedConfig)
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	w := &filesWatcher{
+		launcher:   l,
+		watcher:    watcher,
+		configPath: configPath,
+		dirs:       make(map[string]bool),
+	}
+	w.update()
+	go w.run(done)
+	return func() {
+		watcher.Close()
+	}, nil
+}
+
+// update watch directories of config file and of sidecars watch_files,
+// directories are watched instead of files to detect files replaced by a new one
+func (w *filesWatcher) update() {
+	entry := log.WithField("component", "Launcher")
+	l := w.launcher
+	l.reloadMu.Lock()
+	files := map[string][]string{
+		w.configPath: {},
+	}
+	for _, sidecar := range l.loadedSidecars {
+		for _, file := range sidecar.WatchFiles {
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(l.sConfig.Dir, file)
+			}
+			absFile, err := filepath.Abs(file)
+			if err != nil {
+				entry.Warnf("Watch file %s of sidecar %s is ignored: %s", file, sidecar.Name, err.Error())
+				continue
+			}
+			files[absFile] = append(files[absFile], sidecar.Name)
+		}
+	}
+	l.reloadMu.Unlock()
+	w.files = files
+	for file := range files {
+		dir := filepath.Dir(file)
+		if w.dirs[dir] {
+			continue
+		}
+		err := w.watcher.Add(dir)
+		if err != nil {
+			entry.Warnf("Could not watch %s: %s", file, err.Error())
+			continue
+		}
+		w.dirs[dir] = true
+	}
+}
+
+func (w *filesWatcher) run(done chan struct{}) {
+	entry := log.WithField("component", "Launcher")
+	changed := make(map[string]bool)
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if _, watched := w.files[event.Name]; !watched || event.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[event.Name] = true
+			debounce = time.After(watchDebounce)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			entry.Warnf("Error when watching files: %s", err.Error())
+		case <-debounce:
+			debounce = nil
+			w.apply(changed)
+			changed = make(map[string]bool)
+		}
+	}
+}
+
+// apply reload config if it has changed and restart sidecars which have a changed watch file
+func (w *filesWatcher) apply(changed map[string]bool) {
+	entry := log.WithField("component", "Launcher")
+	if changed[w.configPath] {
+		entry.Infof("Config file %s has changed.", w.configPath)
+		err := w.launcher.Reload()
+		if err != nil {
+			entry.Errorf("Config has not been reloaded: %s", err.Error())
+		}
+		w.update()
+	}
+	restarted := make(map[string]bool)
+	for file := range changed {
+		for _, name := range w.files[file] {
+			if restarted[name] {
+				continue
+			}
+			restarted[name] = true
+			sidecar := sidecarByName(w.launcher.sConfig.Sidecars, name)
+			if sidecar == nil {
+				continue
+			}
+			for _, p := range w.launcher.sidecarProcesses(sidecar) {
+				entry.Infof("Watch file %s of sidecar %s has changed.", file, p.name)
+				err := p.Restart()
+				if err != nil {
+					entry.Warnf("Sidecar %s has not been restarted: %s", p.name, err.Error())
+				}
+			}
+		}
+	}
+}
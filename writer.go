@@ -5,13 +5,32 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
+// PrefixOptions set what is added before each line of a prefixed output
+type PrefixOptions struct {
+	// Timestamp add RFC3339 time when line was written
+	Timestamp bool
+	// StreamTag add [out] or [err] tag to know from which stream line comes from
+	StreamTag bool
+	// MaxLinesPerSec drop lines above this rate, 0 means no limit
+	MaxLinesPerSec int
+	// MaxLineBytes truncate lines longer than this size, 0 means no limit
+	MaxLineBytes int
+	// RawStdout and RawStderr receive lines kept by limits without prefix (e.g.: to send them to loki), optional
+	RawStdout io.Writer
+	RawStderr io.Writer
+}
+
 type CmdWriter struct {
 	cmd *exec.Cmd
 }
 
-func PrefixCmdOutput(stdout, stderr io.Writer, cmd *exec.Cmd, prefix string) error {
+func PrefixCmdOutput(stdout, stderr io.Writer, cmd *exec.Cmd, prefix string, opts PrefixOptions) error {
 	stdoutCmd, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -22,27 +41,182 @@ func PrefixCmdOutput(stdout, stderr io.Writer, cmd *exec.Cmd, prefix string) err
 		return err
 	}
 
-	// Created scanners for in, out, and err pipes
-	outScanner := bufio.NewScanner(stdoutCmd)
-	errScanner := bufio.NewScanner(stderrCmd)
+	// rate is shared by both streams
+	limiter := newLineLimiter(opts.MaxLinesPerSec)
+	errStream := limiter.stream(stderr, func() string { return opts.linePrefix(prefix, "err") })
+	outStream := limiter.stream(stdout, func() string { return opts.linePrefix(prefix, "out") })
 
-	// Scan for text
+	// Read lines from out and err pipes
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
 	go func() {
-		for errScanner.Scan() {
-			scannerOutput(stderr, prefix, errScanner.Text())
-		}
+		defer wg.Done()
+		readLines(stderrCmd, opts.MaxLineBytes, func(text string) {
+			if !limiter.allow(errStream) {
+				return
+			}
+			scannerOutput(stderr, errStream.prefix(), text)
+			if opts.RawStderr != nil {
+				scannerOutput(opts.RawStderr, "", text)
+			}
+		})
 	}()
-
 	go func() {
-		for outScanner.Scan() {
-			scannerOutput(stdout, prefix, outScanner.Text())
-		}
+		defer wg.Done()
+		readLines(stdoutCmd, opts.MaxLineBytes, func(text string) {
+			if !limiter.allow(outStream) {
+				return
+			}
+			scannerOutput(stdout, outStream.prefix(), text)
+			if opts.RawStdout != nil {
+				scannerOutput(opts.RawStdout, "", text)
+			}
+		})
+	}()
+	// lines dropped just before output is closed are still reported
+	go func() {
+		wg.Wait()
+		limiter.flush()
 	}()
 
 	return nil
 }
 
+// readLines call fn for each line read, lines longer than maxBytes are truncated,
+// rest of the line is discarded without being kept in memory
+func readLines(r io.Reader, maxBytes int, fn func(text string)) {
+	reader := bufio.NewReader(r)
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		if !truncated {
+			line = append(line, chunk...)
+			if maxBytes > 0 && len(line) > maxBytes {
+				line = truncateBytes(line, maxBytes)
+				truncated = true
+			}
+		}
+		if isPrefix {
+			continue
+		}
+		text := string(line)
+		if truncated {
+			text += " [truncated]"
+		}
+		fn(text)
+		line = line[:0]
+		truncated = false
+	}
+}
+
+// truncateBytes cut b to at most max bytes without splitting an utf-8 character
+func truncateBytes(b []byte, max int) []byte {
+	cut := max
+	for cut > 0 && !utf8.RuneStart(b[cut]) {
+		cut--
+	}
+	return b[:cut]
+}
+
 func scannerOutput(writer io.Writer, prefix string, text string) {
-	out := fmt.Sprintf("%s %s\n", prefix, text)
+	out := text + "\n"
+	if prefix != "" {
+		out = fmt.Sprintf("%s %s\n", prefix, text)
+	}
 	fmt.Fprint(writer, out)
 }
+
+// lineLimiter allow a maximum number of lines per second shared by streams,
+// a notice of dropped lines is written on their stream when rate window ends or when output is closed
+type lineLimiter struct {
+	max         int
+	mu          sync.Mutex
+	windowStart time.Time
+	count       int
+	streams     []*limitedStream
+	flushTimer  *time.Timer
+}
+
+// limitedStream is a stream whose lines are counted by a lineLimiter
+type limitedStream struct {
+	writer  io.Writer
+	prefix  func() string
+	dropped int
+}
+
+func newLineLimiter(max int) *lineLimiter {
+	return &lineLimiter{
+		max: max,
+	}
+}
+
+// stream register a stream written on writer with lines prefixed by prefix
+func (l *lineLimiter) stream(writer io.Writer, prefix func() string) *limitedStream {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := &limitedStream{writer: writer, prefix: prefix}
+	l.streams = append(l.streams, s)
+	return s
+}
+
+func (l *lineLimiter) allow(s *limitedStream) bool {
+	if l.max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.windowStart) >= time.Second {
+		l.flushLocked()
+		l.windowStart = now
+		l.count = 0
+	}
+	if l.count >= l.max {
+		s.dropped++
+		if l.flushTimer == nil {
+			l.flushTimer = time.AfterFunc(l.windowStart.Add(time.Second).Sub(now), l.flush)
+		}
+		return false
+	}
+	l.count++
+	return true
+}
+
+// flush write notice of dropped lines on each stream which has dropped lines
+func (l *lineLimiter) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushLocked()
+}
+
+func (l *lineLimiter) flushLocked() {
+	if l.flushTimer != nil {
+		l.flushTimer.Stop()
+		l.flushTimer = nil
+	}
+	for _, s := range l.streams {
+		if s.dropped == 0 {
+			continue
+		}
+		scannerOutput(s.writer, s.prefix(), fmt.Sprintf("[%d lines dropped, output exceeded %d lines per second]", s.dropped, l.max))
+		s.dropped = 0
+	}
+}
+
+func (o PrefixOptions) linePrefix(prefix, stream string) string {
+	parts := make([]string, 0, 3)
+	if o.Timestamp {
+		parts = append(parts, time.Now().Format(time.RFC3339))
+	}
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	if o.StreamTag {
+		parts = append(parts, fmt.Sprintf("[%s]", stream))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,88 @@
+package sidecars
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer which can be written by limiter timer while being read
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestLineLimiterReportsDropsOnTheirStream(t *testing.T) {
+	stdout, stderr := &syncBuffer{}, &syncBuffer{}
+	limiter := newLineLimiter(1)
+	outStream := limiter.stream(stdout, func() string { return "[app]" })
+	limiter.stream(stderr, func() string { return "[app]" })
+
+	if !limiter.allow(outStream) {
+		t.Fatal("Expected first line to be allowed")
+	}
+	if limiter.allow(outStream) || limiter.allow(outStream) {
+		t.Fatal("Expected lines above rate to be dropped")
+	}
+	limiter.flush()
+
+	if !strings.Contains(stdout.String(), "[app] [2 lines dropped") {
+		t.Fatalf("Expected drop notice on stdout, got %q", stdout.String())
+	}
+	if stderr.String() != "" {
+		t.Fatalf("Expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestLineLimiterReportsDropsWhenWindowEnds(t *testing.T) {
+	stdout := &syncBuffer{}
+	limiter := newLineLimiter(1)
+	outStream := limiter.stream(stdout, func() string { return "" })
+
+	limiter.allow(outStream)
+	limiter.allow(outStream)
+	time.Sleep(1200 * time.Millisecond)
+
+	if !strings.Contains(stdout.String(), "[1 lines dropped") {
+		t.Fatalf("Expected drop notice when output stops, got %q", stdout.String())
+	}
+}
+
+func TestPrefixCmdOutputGivesRawLines(t *testing.T) {
+	stdout, raw := &syncBuffer{}, &syncBuffer{}
+	cmd := exec.Command("sh", "-c", "echo hello")
+	err := PrefixCmdOutput(stdout, &syncBuffer{}, cmd, "[sidecar:test]", PrefixOptions{Timestamp: true, RawStdout: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50 && raw.String() == ""; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	cmd.Wait()
+
+	if raw.String() != "hello\n" {
+		t.Fatalf("Expected raw line without prefix, got %q", raw.String())
+	}
+	if !strings.Contains(stdout.String(), "[sidecar:test] hello") {
+		t.Fatalf("Expected prefixed line on stdout, got %q", stdout.String())
+	}
+}